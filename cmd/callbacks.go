@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/infrastructure/di"
+	"github.com/spf13/cobra"
+	"strconv"
+)
+
+var callbacksCmd = &cobra.Command{
+	Use:   "callbacks",
+	Short: "Operate callbacks, which were not delivered",
+}
+
+var callbacksReplayCmd = &cobra.Command{
+	Use:   "replay [id...]",
+	Short: "Send failed callbacks again",
+	Long:  "Send failed callbacks with given ids or all failed callbacks again. Delivered callbacks are removed from list",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		admin, err := di.InitUploadsAdmin()
+		if err != nil {
+			return err
+		}
+		ids := args
+		if len(ids) == 0 {
+			callbacks, err := admin.FailedCallbacks()
+			if err != nil {
+				return err
+			}
+			for _, callback := range callbacks {
+				ids = append(ids, callback.Id)
+			}
+		}
+		failed := 0
+		for _, id := range ids {
+			if err = admin.ReplayFailedCallback(context.Background(), id); err != nil {
+				failed++
+				fmt.Println(id + ": " + err.Error())
+				continue
+			}
+			fmt.Println(id + ": delivered")
+		}
+		if failed > 0 {
+			return errors.New(strconv.Itoa(failed) + " of " + strconv.Itoa(len(ids)) + " callbacks are not delivered")
+		}
+		return nil
+	},
+}
+
+func init() {
+	callbacksCmd.AddCommand(callbacksReplayCmd)
+}
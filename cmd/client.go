@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/pkg/client"
+	"github.com/spf13/cobra"
+	"strings"
+	"time"
+)
+
+var clientOptions struct {
+	server        string
+	headers       []string
+	infoFieldName string
+	concurrency   int
+	retries       int
+	retryDelay    time.Duration
+}
+
+// skipConfig - command does not require configuration of server
+func skipConfig(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+func addClientFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&clientOptions.server, "server", "http://localhost:8080", "url of filup server")
+	cmd.Flags().StringArrayVar(&clientOptions.headers, "header", nil, "header of requests as 'Name: value', can be repeated")
+	cmd.Flags().StringVar(&clientOptions.infoFieldName, "info-field", client.DefaultInfoFieldName, "uploader.infoFieldName of server")
+	cmd.Flags().IntVar(&clientOptions.concurrency, "concurrency", client.DefaultConcurrency, "count of chunks, which are sent in parallel")
+	cmd.Flags().IntVar(&clientOptions.retries, "retries", client.DefaultRetries, "retries of chunk")
+	cmd.Flags().DurationVar(&clientOptions.retryDelay, "retry-delay", client.DefaultRetryDelay, "delay before first retry")
+}
+
+func newClient() (*client.Client, error) {
+	options := []client.Option{
+		client.WithInfoFieldName(clientOptions.infoFieldName),
+		client.WithConcurrency(clientOptions.concurrency),
+		client.WithRetries(clientOptions.retries, clientOptions.retryDelay),
+	}
+	for _, header := range clientOptions.headers {
+		pos := strings.Index(header, ":")
+		if pos < 1 {
+			return nil, errors.New("header " + header + " must be 'Name: value'")
+		}
+		options = append(options, client.WithHeader(strings.TrimSpace(header[:pos]), strings.TrimSpace(header[pos+1:])))
+	}
+	return client.New(clientOptions.server, options...), nil
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/spf13/cobra"
+)
+
+var showSecrets bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Check configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate configuration",
+	Long:  "Validate configuration, command fails if config can not be loaded or has invalid values",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// configuration is loaded and validated before any command
+		fmt.Println("configuration is valid")
+		return nil
+	},
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print effective configuration as json",
+	Long:  "Print configuration with defaults and environment variables applied. Secrets are hidden without --show-secrets",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := config.ProvideConfig()
+		if !showSecrets {
+			cfg = cfg.Masked()
+		}
+		content, err := jsoniter.MarshalIndent(cfg, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(content))
+		return nil
+	},
+}
+
+func init() {
+	configPrintCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "print secrets as is")
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configPrintCmd)
+}
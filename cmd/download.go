@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+var downloadOutput string
+
+var downloadCmd = &cobra.Command{
+	Use:               "download <uuid>",
+	Short:             "Download file from filup server",
+	Args:              cobra.ExactArgs(1),
+	PersistentPreRunE: skipConfig,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		output := downloadOutput
+		if output == "" {
+			output = args[0]
+		}
+		var w io.Writer = os.Stdout
+		if output != "-" {
+			file, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = file.Close()
+			}()
+			w = file
+		}
+		written, err := c.Download(context.Background(), args[0], w)
+		if err != nil {
+			if output != "-" {
+				_ = os.Remove(output)
+			}
+			return err
+		}
+		if output != "-" {
+			fmt.Fprintf(os.Stderr, "%d bytes are written to %s\n", written, output)
+		}
+		return nil
+	},
+}
+
+func init() {
+	addClientFlags(downloadCmd)
+	downloadCmd.Flags().StringVarP(&downloadOutput, "output", "o", "", "output file, uuid by default, '-' for stdout")
+}
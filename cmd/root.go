@@ -22,6 +22,9 @@ var (
 		Use:   config.ProjectName,
 		Short: config.ProjectName + " service",
 		Long:  "File Upload service - upload files directly to S3-compatibility storage. Supports multipart/form-data and websockets. Cloud ready.",
+		// errors are printed by main without usage
+		SilenceUsage:  true,
+		SilenceErrors: true,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			return loadConfig(cfgName)
 		},
@@ -35,6 +38,14 @@ func Execute() error {
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgName, "config", "", "config file")
 	rootCmd.AddCommand(serverCmd)
+	rootCmd.AddCommand(signCmd)
+	rootCmd.AddCommand(uploadsCmd)
+	rootCmd.AddCommand(callbacksCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(storageCmd)
+	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(uploadCmd)
+	rootCmd.AddCommand(downloadCmd)
 }
 
 func loadConfig(configName string) error {
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/infrastructure/di"
+	"github.com/spf13/cobra"
+)
+
+var signRequest dto.SignUrlRequest
+
+var signCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "Make signed download url",
+	Long:  "Make time-limited signed download url, which does not require callbackDownload",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		signed, err := di.InitUrlSigner().SignUrl(signRequest)
+		if err != nil {
+			return err
+		}
+		fmt.Println(signed.Url)
+		return nil
+	},
+}
+
+func init() {
+	signCmd.Flags().StringVar(&signRequest.Uuid, "uuid", "", "uuid of file")
+	signCmd.Flags().Int64Var(&signRequest.Ttl, "ttl", 0, "lifetime of url in seconds, signedUrls.defaultTtl if not set")
+	signCmd.Flags().StringVar(&signRequest.Ip, "ip", "", "client ip, which url is bound to")
+	signCmd.Flags().StringVar(&signRequest.Disposition, "disposition", "", "Content-Disposition header of response")
+	_ = signCmd.MarkFlagRequired("uuid")
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/infrastructure/di"
+	"github.com/spf13/cobra"
+)
+
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Check storage",
+}
+
+var storageCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check credentials, buckets and permissions",
+	Long:  "Check, that credentials are valid, all buckets exist and objects can be written, read and removed. Buckets are not created",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		check, err := di.InitStorageCheck()
+		if err != nil {
+			return err
+		}
+		failed := false
+		for _, bucket := range check.Check(context.Background()) {
+			if bucket.Err != nil {
+				failed = true
+				fmt.Println(bucket.Bucket + ": " + bucket.Err.Error())
+				continue
+			}
+			fmt.Println(bucket.Bucket + ": ok")
+		}
+		if failed {
+			return errors.New("storage check is failed")
+		}
+		return nil
+	},
+}
+
+func init() {
+	storageCmd.AddCommand(storageCheckCmd)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/pkg/client"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	uploadRequest client.UploadRequest
+	uploadTags    []string
+	uploadQuiet   bool
+)
+
+var uploadCmd = &cobra.Command{
+	Use:               "upload <file>",
+	Short:             "Upload file to filup server",
+	Long:              "Upload file to filup server by chunks. Upload with --uuid is resumed: chunks, which are already uploaded, are skipped",
+	Args:              cobra.ExactArgs(1),
+	PersistentPreRunE: skipConfig,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		stat, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		request := uploadRequest
+		request.Size = stat.Size()
+		if request.FileName == "" {
+			request.FileName = filepath.Base(args[0])
+		}
+		request.UserTags = make(map[string]string, len(uploadTags))
+		for _, tag := range uploadTags {
+			pos := strings.Index(tag, "=")
+			if pos < 1 {
+				return errors.New("tag " + tag + " must be 'name=value'")
+			}
+			request.UserTags[tag[:pos]] = tag[pos+1:]
+		}
+		var progress client.Progress
+		if !uploadQuiet {
+			progress = func(sent int64, total int64) {
+				percent := int64(100)
+				if total > 0 {
+					percent = sent * 100 / total
+				}
+				fmt.Fprintf(os.Stderr, "\r%d / %d bytes (%d%%)", sent, total, percent)
+			}
+		}
+		upload, err := c.Upload(context.Background(), request, file, progress)
+		if progress != nil {
+			fmt.Fprintln(os.Stderr)
+		}
+		if err != nil {
+			if upload.Uuid != "" {
+				fmt.Fprintln(os.Stderr, "upload can be resumed with --uuid "+upload.Uuid)
+			}
+			return err
+		}
+		fmt.Println(upload.Uuid)
+		return nil
+	},
+}
+
+func init() {
+	addClientFlags(uploadCmd)
+	uploadCmd.Flags().StringVar(&uploadRequest.Uuid, "uuid", "", "uuid of file, upload with known uuid is resumed")
+	uploadCmd.Flags().StringVar(&uploadRequest.FileName, "file-name", "", "name of file, base name of path by default")
+	uploadCmd.Flags().StringVar(&uploadRequest.ContentType, "content-type", "", "content type of file")
+	uploadCmd.Flags().StringVar(&uploadRequest.Tenant, "tenant", "", "tenant of file")
+	uploadCmd.Flags().StringVar(&uploadRequest.Route, "route", "", "route of file")
+	uploadCmd.Flags().StringArrayVar(&uploadTags, "tag", nil, "user tag as 'name=value', can be repeated")
+	uploadCmd.Flags().BoolVar(&uploadRequest.Overwrite, "overwrite", false, "replace existing file with the same uuid")
+	uploadCmd.Flags().BoolVar(&uploadQuiet, "quiet", false, "do not show progress")
+}
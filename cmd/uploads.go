@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/satmaelstorm/filup/internal/infrastructure/di"
+	"github.com/spf13/cobra"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// uploadsPageLimit - uploads are listed by pages from meta storage
+const uploadsPageLimit = 1000
+
+var uploadsCmd = &cobra.Command{
+	Use:   "uploads",
+	Short: "Operate uploads, which are in progress",
+	Long:  "Operate uploads, which are in progress: list, show, abort or compose them without admin api",
+}
+
+var uploadsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List uploads, received chunks are shown by show command",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		admin, err := di.InitUploadsAdmin()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "UUID\tSIZE\tCHUNKS\tCREATED")
+		for marker := ""; ; {
+			page, err := admin.Uploads(marker, uploadsPageLimit)
+			if err != nil {
+				return err
+			}
+			for _, u := range page.Items {
+				created := ""
+				if u.CreatedAt > 0 {
+					created = time.Unix(u.CreatedAt, 0).UTC().Format(time.RFC3339)
+				}
+				fmt.Fprintln(w, u.Uuid+"\t"+strconv.FormatInt(u.Size, 10)+"\t"+strconv.Itoa(u.ChunksCount)+"\t"+created)
+			}
+			if marker = page.NextMarker; marker == "" {
+				break
+			}
+		}
+		return w.Flush()
+	},
+}
+
+var uploadsShowCmd = &cobra.Command{
+	Use:   "show <uuid>",
+	Short: "Show upload as json",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		admin, err := di.InitUploadsAdmin()
+		if err != nil {
+			return err
+		}
+		upload, err := admin.Upload(args[0])
+		if err != nil {
+			return err
+		}
+		content, err := jsoniter.MarshalIndent(upload, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(content))
+		return nil
+	},
+}
+
+var uploadsAbortCmd = &cobra.Command{
+	Use:   "abort <uuid>",
+	Short: "Remove upload with its chunks",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		admin, err := di.InitUploadsAdmin()
+		if err != nil {
+			return err
+		}
+		if err = admin.AbortUpload(context.Background(), args[0]); err != nil {
+			return err
+		}
+		fmt.Println("upload " + args[0] + " is aborted")
+		return nil
+	},
+}
+
+var uploadsComposeCmd = &cobra.Command{
+	Use:   "compose <uuid>",
+	Short: "Compose upload, which has all chunks, and wait for result",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		admin, err := di.InitUploadsAdmin()
+		if err != nil {
+			return err
+		}
+		if err = admin.ComposeUpload(context.Background(), args[0]); err != nil {
+			return err
+		}
+		fmt.Println("upload " + args[0] + " is composed")
+		return nil
+	},
+}
+
+func init() {
+	uploadsCmd.AddCommand(uploadsListCmd)
+	uploadsCmd.AddCommand(uploadsShowCmd)
+	uploadsCmd.AddCommand(uploadsAbortCmd)
+	uploadsCmd.AddCommand(uploadsComposeCmd)
+}
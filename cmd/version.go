@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print version",
+	Args:  cobra.NoArgs,
+	// version does not require configuration
+	PersistentPreRunE: skipConfig,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("build time: " + BuildTime)
+		fmt.Println("git branch: " + GitBranch)
+		fmt.Println("git commit: " + GitCommit)
+	},
+}
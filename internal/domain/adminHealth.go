@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"net/http"
+)
+
+// AdminHealth handles admin api with detailed state of instance, public health probes answer only status
+type AdminHealth struct {
+	reporter port.HealthReporter
+	adminCfg port.AdminConfig
+}
+
+func ProvideAdminHealth(reporter port.HealthReporter, adminCfg port.AdminConfig) *AdminHealth {
+	return &AdminHealth{reporter: reporter, adminCfg: adminCfg}
+}
+
+func (ah *AdminHealth) Details(ctx context.Context, headers [][2]string) ([]byte, error) {
+	if err := CheckAdminToken(ah.adminCfg, headers); err != nil {
+		return nil, err
+	}
+	_, content, err := ah.reporter.Details(ctx)
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	return content, nil
+}
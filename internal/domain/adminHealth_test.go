@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"testing"
+)
+
+type fakeHealthReporter struct {
+	calls int
+}
+
+func (f *fakeHealthReporter) Details(context.Context) (bool, []byte, error) {
+	f.calls++
+	return false, []byte(`{"ready":false,"checks":{"storage":"unreachable"}}`), nil
+}
+
+type suiteAdminHealth struct {
+	suite.Suite
+	reporter *fakeHealthReporter
+	health   *AdminHealth
+}
+
+func TestAdminHealth(t *testing.T) {
+	suite.Run(t, new(suiteAdminHealth))
+}
+
+func (s *suiteAdminHealth) SetupTest() {
+	s.reporter = &fakeHealthReporter{}
+	s.health = ProvideAdminHealth(s.reporter, config.AdminConfig{Token: "admin"})
+}
+
+func (s *suiteAdminHealth) TestDetails() {
+	content, err := s.health.Details(context.Background(), [][2]string{{"X-Admin-Token", "admin"}})
+	s.Require().Nil(err)
+	s.JSONEq(`{"ready":false,"checks":{"storage":"unreachable"}}`, string(content))
+}
+
+func (s *suiteAdminHealth) TestUnauthorized() {
+	_, err := s.health.Details(context.Background(), [][2]string{{"X-Admin-Token", "wrong"}})
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusUnauthorized, apiErr.GetCode())
+	s.Equal(0, s.reporter.calls)
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"net/http"
+	"strings"
+)
+
+const bearerPrefix = "bearer "
+
+// scopes of token, token is accepted only by routes of its scopes
+const (
+	scopeUpload   = "upload"
+	scopeDownload = "download"
+)
+
+type Authenticator struct {
+	verifier port.TokenVerifier
+	cfg      port.AuthConfig
+}
+
+func ProvideAuthenticator(verifier port.TokenVerifier, cfg port.AuthConfig) *Authenticator {
+	return &Authenticator{verifier: verifier, cfg: cfg}
+}
+
+// Authenticate returns claims of token from request headers, token must have scope of route.
+// Nil claims are returned if token verification is disabled or token is absent and not required
+func (a *Authenticator) Authenticate(headers [][2]string, scope string) (*dto.TokenClaims, error) {
+	if !a.verifier.IsEnabled() {
+		return nil, nil
+	}
+	token := strings.TrimSpace(findHeader(headers, a.cfg.GetTokenHeader()))
+	if len(token) > len(bearerPrefix) && strings.EqualFold(token[:len(bearerPrefix)], bearerPrefix) {
+		token = strings.TrimSpace(token[len(bearerPrefix):])
+	}
+	if token == "" {
+		if a.cfg.IsTokenRequired() {
+			return nil, exceptions.NewApiError(http.StatusUnauthorized, errors.New("token is required"))
+		}
+		return nil, nil
+	}
+	claims, err := a.verifier.Verify(token)
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusUnauthorized, err)
+	}
+	if !claims.HasScope(scope) {
+		return nil, exceptions.NewApiError(http.StatusForbidden, errors.New("token has no scope "+scope))
+	}
+	return &claims, nil
+}
+
+// SkipCallback - callbacks can be replaced with token verification only for file, which is bound to token
+// by uuid claim. Empty uuid is new file with generated uuid, it can be uploaded with any token
+func (a *Authenticator) SkipCallback(claims *dto.TokenClaims, uuid string) bool {
+	return claims != nil && a.cfg.IsSkipCallbacks() && (uuid == "" || claims.GetUuid() == uuid)
+}
+
+// CheckUuid - token with uuid claim grants access only to that file
+func (a *Authenticator) CheckUuid(claims *dto.TokenClaims, uuid string) error {
+	if claims == nil || claims.GetUuid() == "" || claims.GetUuid() == uuid {
+		return nil
+	}
+	return exceptions.NewApiError(http.StatusForbidden, errors.New("token does not grant access to "+uuid))
+}
@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"testing"
+)
+
+type fakeTokenVerifier struct {
+	enabled bool
+	claims  dto.TokenClaims
+}
+
+func (f fakeTokenVerifier) IsEnabled() bool {
+	return f.enabled
+}
+
+func (f fakeTokenVerifier) Verify(token string) (dto.TokenClaims, error) {
+	if token != "valid" {
+		return dto.TokenClaims{}, errors.New("invalid token")
+	}
+	return f.claims, nil
+}
+
+type suiteAuthenticator struct {
+	suite.Suite
+}
+
+func TestAuthenticator(t *testing.T) {
+	suite.Run(t, new(suiteAuthenticator))
+}
+
+func (s *suiteAuthenticator) makeAuthenticator(enabled bool, jwtCfg config.JwtConfig, claims dto.TokenClaims) *Authenticator {
+	return ProvideAuthenticator(
+		fakeTokenVerifier{enabled: enabled, claims: claims},
+		config.AuthConfig{Jwt: jwtCfg}.AfterLoad(),
+	)
+}
+
+func (s *suiteAuthenticator) assertCode(err error, code int) {
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(code, apiErr.GetCode())
+}
+
+func (s *suiteAuthenticator) TestAuthenticate() {
+	a := s.makeAuthenticator(false, config.JwtConfig{Required: true}, dto.TokenClaims{})
+	claims, err := a.Authenticate(nil, scopeUpload)
+	s.Nil(err)
+	s.Nil(claims)
+
+	a = s.makeAuthenticator(true, config.JwtConfig{}, dto.TokenClaims{Subject: "user1", Scopes: []string{scopeUpload}})
+	claims, err = a.Authenticate(nil, scopeUpload)
+	s.Nil(err)
+	s.Nil(claims)
+
+	claims, err = a.Authenticate([][2]string{{"authorization", "Bearer valid"}}, scopeUpload)
+	s.Require().Nil(err)
+	s.Require().NotNil(claims)
+	s.Equal("user1", claims.GetSubject())
+	s.False(a.SkipCallback(claims, ""))
+
+	_, err = a.Authenticate([][2]string{{"authorization", "Bearer valid"}}, scopeDownload)
+	s.assertCode(err, http.StatusForbidden)
+
+	_, err = a.Authenticate([][2]string{{"Authorization", "Bearer invalid"}}, scopeUpload)
+	s.assertCode(err, http.StatusUnauthorized)
+
+	a = s.makeAuthenticator(true, config.JwtConfig{Required: true, SkipCallbacks: true, Header: "X-Token"},
+		dto.TokenClaims{Scopes: []string{scopeUpload, scopeDownload}})
+	_, err = a.Authenticate([][2]string{{"Authorization", "Bearer valid"}}, scopeDownload)
+	s.assertCode(err, http.StatusUnauthorized)
+	claims, err = a.Authenticate([][2]string{{"X-Token", "valid"}}, scopeDownload)
+	s.Require().Nil(err)
+	s.True(a.SkipCallback(claims, ""))
+	s.False(a.SkipCallback(nil, ""))
+}
+
+func (s *suiteAuthenticator) TestSkipCallback() {
+	a := s.makeAuthenticator(true, config.JwtConfig{SkipCallbacks: true}, dto.TokenClaims{})
+	uid := ProvideUuidProvider().NewUuid()
+	s.True(a.SkipCallback(&dto.TokenClaims{}, ""))
+	s.False(a.SkipCallback(&dto.TokenClaims{}, uid))
+	s.True(a.SkipCallback(&dto.TokenClaims{Uuid: uid}, uid))
+	s.False(a.SkipCallback(&dto.TokenClaims{Uuid: uid}, ProvideUuidProvider().NewUuid()))
+
+	a = s.makeAuthenticator(true, config.JwtConfig{}, dto.TokenClaims{})
+	s.False(a.SkipCallback(&dto.TokenClaims{Uuid: uid}, uid))
+}
+
+func (s *suiteAuthenticator) TestApplyClaims() {
+	uploader := MetaUploader{
+		auth:         s.makeAuthenticator(true, config.JwtConfig{}, dto.TokenClaims{}),
+		UuidProvider: ProvideUuidProvider(),
+	}
+	uid := uploader.UuidProvider.NewUuid()
+	im := innerMeta{
+		size:          100,
+		uuid:          uploader.UuidProvider.NewUuid(),
+		uuidGenerated: true,
+		userTags:      map[string]string{"project": "alpha"},
+	}
+
+	result, err := uploader.applyClaims(im, nil)
+	s.Require().Nil(err)
+	s.Equal(im, result)
+
+	result, err = uploader.applyClaims(im, &dto.TokenClaims{
+		Subject: "user1",
+		MaxSize: 100,
+		Uuid:    uid,
+		Tags:    map[string]string{"owner": "user1"},
+	})
+	s.Require().Nil(err)
+	s.Equal(uid, result.uuid)
+	s.False(result.uuidGenerated)
+	s.Equal("user1", result.owner)
+	s.Equal("user1", result.userTags["owner"])
+	s.Equal("alpha", result.userTags["project"])
+
+	_, err = uploader.applyClaims(im, &dto.TokenClaims{MaxSize: 99})
+	s.assertCode(err, http.StatusRequestEntityTooLarge)
+
+	im.uuidGenerated = false
+	_, err = uploader.applyClaims(im, &dto.TokenClaims{Uuid: uid})
+	s.assertCode(err, http.StatusForbidden)
+
+	_, err = uploader.applyClaims(im, &dto.TokenClaims{Tags: map[string]string{"project": "beta"}})
+	s.assertCode(err, http.StatusForbidden)
+}
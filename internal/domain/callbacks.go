@@ -0,0 +1,169 @@
+package domain
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/pkg/client"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// types of callbacks in metrics
+const (
+	callbackTypeBefore   = "before"
+	callbackTypeAfter    = "after"
+	callbackTypeDownload = "download"
+	callbackTypeDelete   = "delete"
+	callbackTypeDeleted  = "deleted"
+)
+
+const (
+	callbackOutcomeSuccess  = "success"
+	callbackOutcomeRejected = "rejected"
+	callbackOutcomeError    = "error"
+)
+
+// headers of signed callbacks, backends verify them by pkg/client
+const (
+	CallbackTimestampHeader = client.CallbackTimestampHeader
+	CallbackSignatureHeader = client.CallbackSignatureHeader
+)
+
+// SignCallback returns signature of callback, it is made by pkg/client, so backends verify it by the same code
+func SignCallback(secret []byte, timestamp string, body []byte) string {
+	return client.SignCallback(secret, timestamp, body)
+}
+
+// postCallback sends body to callback and observes latency and outcome of call.
+// Signature headers are added last, so headers of client request can not replace them
+func postCallback(
+	ctx context.Context,
+	poster port.Poster,
+	metrics port.UploadMetrics,
+	cfg port.UploaderConfig,
+	callbackType string,
+	callback url.URL,
+	body []byte,
+	headers ...[2]string,
+) ([]byte, int, error) {
+	headers = signCallbackHeaders(cfg, body, headers)
+	start := time.Now()
+	result, code, err := poster.Post(ctx, callback, cfg.GetHttpTimeout(), body, headers...)
+	metrics.CallbackDone(callbackType, callbackOutcome(code, err), time.Since(start))
+	return result, code, err
+}
+
+// getCallback is postCallback without body, signature is made of empty body
+func getCallback(
+	ctx context.Context,
+	getter port.Getter,
+	metrics port.UploadMetrics,
+	cfg port.UploaderConfig,
+	callbackType string,
+	callback url.URL,
+	headers ...[2]string,
+) ([]byte, int, error) {
+	headers = signCallbackHeaders(cfg, nil, headers)
+	start := time.Now()
+	result, code, err := getter.Get(ctx, callback, cfg.GetHttpTimeout(), headers...)
+	metrics.CallbackDone(callbackType, callbackOutcome(code, err), time.Since(start))
+	return result, code, err
+}
+
+func signCallbackHeaders(cfg port.UploaderConfig, body []byte, headers [][2]string) [][2]string {
+	secret := cfg.GetCallbackSecret()
+	if len(secret) == 0 {
+		return headers
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signed := make([][2]string, 0, len(headers)+2)
+	signed = append(signed, headers...)
+	return append(signed,
+		[2]string{CallbackTimestampHeader, timestamp},
+		[2]string{CallbackSignatureHeader, SignCallback(secret, timestamp, body)},
+	)
+}
+
+// isCallbackRejected - backend answered with not 2xx code. Poster returns error together with such code
+func isCallbackRejected(code int) bool {
+	return code != 0 && (code < 200 || code > 299)
+}
+
+func callbackOutcome(code int, err error) string {
+	if isCallbackRejected(code) {
+		return callbackOutcomeRejected
+	}
+	if err != nil {
+		return callbackOutcomeError
+	}
+	return callbackOutcomeSuccess
+}
+
+// callbackError converts result of callback to error of request: rejection is translated to client with code
+// and body of backend response, other errors are 502
+func callbackError(httpResult []byte, httpCode int, err error) error {
+	if isCallbackRejected(httpCode) {
+		return exceptions.NewApiError(httpCode, errors.New(string(httpResult)))
+	}
+	if err != nil {
+		return exceptions.NewApiError(http.StatusBadGateway, errors.Wrap(err, "Post error"))
+	}
+	return nil
+}
+
+// postWithRetries sends body to callback until 2xx response or config.GetHttpRetries() attempts.
+// Undelivered callback is saved to failures
+func postWithRetries(
+	ctx context.Context,
+	poster port.Poster,
+	metrics port.UploadMetrics,
+	cfg port.UploaderConfig,
+	logger port.Logger,
+	failures *FailedCallbacks,
+	callbackType string,
+	name string,
+	callback *url.URL,
+	body []byte,
+) bool {
+	retires := 0
+	totalRetires := cfg.GetHttpRetries()
+	var allErrors []string
+	for retires < totalRetires {
+		_, code, err := postCallback(ctx, poster, metrics, cfg, callbackType, *callback, body)
+		if err == nil && (code >= 200 && code <= 299) {
+			return true
+		}
+		retires++
+		var e error
+		if err != nil {
+			e = errors.Wrap(err, name+".poster.error")
+		} else {
+			e = errors.New(name + ".poster.code_" + strconv.Itoa(code))
+		}
+		logger.Error().Println(e)
+		allErrors = append(allErrors, e.Error())
+	}
+	logger.Critical().Println(name + " " + callback.String() +
+		" Error after " + strconv.Itoa(totalRetires) + " with body " + string(body) +
+		" with errors [" + strings.Join(allErrors, ",") + "]")
+	if failures != nil {
+		err := failures.Save(dto.FailedCallback{
+			Type:     callbackType,
+			Url:      callback.String(),
+			Body:     body,
+			Errors:   allErrors,
+			Attempts: totalRetires,
+			FailedAt: time.Now().Unix(),
+		})
+		if err != nil {
+			logger.Error().Println(err)
+		}
+	}
+	return false
+}
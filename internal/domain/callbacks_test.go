@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/pkg/client"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// recordingPoster keeps url and headers of last request
+type recordingPoster struct {
+	url     url.URL
+	headers [][2]string
+}
+
+func (f *recordingPoster) Post(ctx context.Context, serviceUrl url.URL, timeOut time.Duration, body []byte, headers ...[2]string) ([]byte, int, error) {
+	f.headers = headers
+	return nil, http.StatusOK, nil
+}
+
+func (f *recordingPoster) Get(ctx context.Context, serviceUrl url.URL, timeOut time.Duration, headers ...[2]string) ([]byte, int, error) {
+	f.url, f.headers = serviceUrl, headers
+	return nil, http.StatusOK, nil
+}
+
+func (f *recordingPoster) header(name string) string {
+	value := ""
+	for _, h := range f.headers {
+		if h[0] == name {
+			value = h[1]
+		}
+	}
+	return value
+}
+
+type suiteCallbacks struct {
+	suite.Suite
+}
+
+func TestCallbacks(t *testing.T) {
+	suite.Run(t, new(suiteCallbacks))
+}
+
+func (s *suiteCallbacks) TestSignature() {
+	body := []byte(`{"uuid":"870915da-76bb-11ec-8686-e4e7494803df"}`)
+	callback, _ := url.Parse("http://localhost/after")
+	poster := new(recordingPoster)
+	cfg := config.Uploader{CallbackSecret: "secret"}.AfterLoad()
+	// signature of client request is replaced
+	_, _, err := postCallback(context.Background(), poster, new(fakeUploadMetrics), cfg, callbackTypeAfter, *callback, body,
+		[2]string{CallbackSignatureHeader, "forged"})
+	s.Require().Nil(err)
+	timestamp, signature := poster.header(CallbackTimestampHeader), poster.header(CallbackSignatureHeader)
+	s.Equal(SignCallback([]byte("secret"), timestamp, body), signature)
+	s.Nil(client.VerifyCallback([]byte("secret"), timestamp, signature, body, client.DefaultCallbackTolerance))
+	s.Equal(client.ErrInvalidSignature, client.VerifyCallback([]byte("other"), timestamp, signature, body, 0))
+	s.Equal(client.ErrInvalidSignature, client.VerifyCallback([]byte("secret"), timestamp, signature, []byte("{}"), 0))
+
+	poster = new(recordingPoster)
+	_, _, err = postCallback(context.Background(), poster, new(fakeUploadMetrics), config.Uploader{}.AfterLoad(),
+		callbackTypeAfter, *callback, body)
+	s.Require().Nil(err)
+	s.Empty(poster.headers)
+}
+
+func (s *suiteCallbacks) TestGetSignature() {
+	callback, _ := url.Parse("http://localhost/download")
+	getter := new(recordingPoster)
+	cfg := config.Uploader{CallbackSecret: "secret"}.AfterLoad()
+	_, _, err := getCallback(context.Background(), getter, new(fakeUploadMetrics), cfg, callbackTypeDownload, *callback)
+	s.Require().Nil(err)
+	timestamp := getter.header(CallbackTimestampHeader)
+	s.Equal(SignCallback([]byte("secret"), timestamp, nil), getter.header(CallbackSignatureHeader))
+	s.Equal(http.MethodGet, cfg.GetCallbackDownloadMethod())
+	s.Equal(http.MethodPost, config.Uploader{CallbackDownloadMethod: "post"}.AfterLoad().GetCallbackDownloadMethod())
+}
+
+func (s *suiteCallbacks) TestCallbackError() {
+	s.Nil(callbackError([]byte("ok"), http.StatusOK, nil))
+
+	// poster returns error together with code of rejecting response
+	err := callbackError([]byte("denied"), http.StatusForbidden, errors.New("http error"))
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusForbidden, apiErr.GetCode())
+	s.Equal(callbackOutcomeRejected, callbackOutcome(http.StatusForbidden, errors.New("http error")))
+
+	err = callbackError(nil, 0, errors.New("connection refused"))
+	s.Require().NotNil(err)
+	apiErr, ok = err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusBadGateway, apiErr.GetCode())
+	s.Equal(callbackOutcomeError, callbackOutcome(0, errors.New("connection refused")))
+}
+
+func (s *suiteCallbacks) TestClientNames() {
+	uid := "870915da-76bb-11ec-8686-e4e7494803df"
+	s.Equal(ChunkFileName(uid, 3), client.ChunkFileName(uid, 3))
+	s.Equal(MetaFileName(uid), client.MetaFileName(uid))
+}
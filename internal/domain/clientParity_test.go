@@ -0,0 +1,117 @@
+package domain
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/pkg/client"
+	"github.com/stretchr/testify/suite"
+	"github.com/tidwall/gjson"
+	"testing"
+)
+
+// serverOnlyFields - fields of meta, which are not sent to clients or are not decoded by pkg/client
+var serverOnlyFields = map[string]bool{
+	"request_hash":   true,
+	"secret_hash":    true,
+	"content_policy": true,
+	"sse_c":          true,
+	"encrypted_key":  true,
+	"data_key":       true,
+}
+
+// suiteClientParity checks, that pkg/client decodes responses and callbacks of server without losing fields
+type suiteClientParity struct {
+	suite.Suite
+	uid string
+}
+
+func TestClientParity(t *testing.T) {
+	suite.Run(t, new(suiteClientParity))
+}
+
+func (s *suiteClientParity) SetupTest() {
+	s.uid = ProvideUuidProvider().NewUuid()
+}
+
+func (s *suiteClientParity) upload() dto.UploaderStartResult {
+	return dto.UploaderStartResult{
+		Uuid:     s.uid,
+		Size:     20,
+		UserTags: map[string]string{"project": "filup"},
+		Chunks: map[string]dto.UploaderChunk{
+			ChunkFileName(s.uid, 0): dto.NewUploaderChunk(ChunkFileName(s.uid, 0), 10, 0),
+			ChunkFileName(s.uid, 1): dto.NewUploaderChunk(ChunkFileName(s.uid, 1), 10, 10),
+		},
+		Bucket:              "files",
+		Key:                 "2024/" + s.uid,
+		FileName:            "report.pdf",
+		ContentType:         "application/pdf",
+		Tenant:              "acme",
+		Route:               "reports",
+		Owner:               "user",
+		CreatedAt:           1700000000,
+		RequestHash:         "hash",
+		SecretHash:          "secret",
+		DetectedContentType: "application/pdf",
+		ContentPolicy:       &dto.ContentPolicy{Allow: []string{"application/"}},
+		Processors:          []string{"checksum"},
+		SseC:                true,
+		EncryptedKey:        "sealed",
+		DataKey:             "wrapped",
+	}
+}
+
+// assertParity compares json of server with json, which is decoded and encoded again by client
+func (s *suiteClientParity) assertParity(server []byte, decoded interface{}) {
+	s.Require().Nil(jsoniter.Unmarshal(server, decoded))
+	encoded, err := jsoniter.Marshal(decoded)
+	s.Require().Nil(err)
+	gjson.ParseBytes(server).ForEach(func(key, value gjson.Result) bool {
+		if serverOnlyFields[key.String()] {
+			s.False(gjson.GetBytes(encoded, key.String()).Exists(), key.String())
+			return true
+		}
+		s.JSONEq(value.Raw, gjson.GetBytes(encoded, key.String()).Raw, key.String())
+		return true
+	})
+	gjson.ParseBytes(encoded).ForEach(func(key, value gjson.Result) bool {
+		s.True(gjson.GetBytes(server, key.String()).Exists(), key.String())
+		return true
+	})
+}
+
+func (s *suiteClientParity) TestUploadStatus() {
+	server, err := jsoniter.Marshal(dto.UploadStatus{
+		UploaderStartResult: s.upload(),
+		UploadedChunks:      []string{ChunkFileName(s.uid, 0)},
+	})
+	s.Require().Nil(err)
+	var upload client.Upload
+	s.assertParity(server, &upload)
+	s.Equal([]string{ChunkFileName(s.uid, 0)}, upload.UploadedChunks)
+	s.Equal(int64(10), upload.SortedChunks()[1].Offset)
+}
+
+func (s *suiteClientParity) TestUploadCompleted() {
+	server, err := jsoniter.Marshal(dto.UploadCompleted{
+		UploaderStartResult: s.upload(),
+		Scan:                &dto.ScanVerdict{Status: dto.ScanStatusClean},
+		Processing: []dto.ProcessorResult{{
+			Name:    "thumbnail",
+			Status:  "ok",
+			Data:    map[string]interface{}{"width": 100},
+			Objects: []dto.FileLocation{{Bucket: "files", Key: "2024/" + s.uid + "_thumb"}},
+		}},
+	})
+	s.Require().Nil(err)
+	var completed client.CompletedUpload
+	s.assertParity(server, &completed)
+	s.Equal("2024/"+s.uid+"_thumb", completed.Processing[0].Objects[0].Key)
+}
+
+func (s *suiteClientParity) TestNames() {
+	uid, err := ExtractUuidFromPartName(client.ChunkFileName(s.uid, 3))
+	s.Require().Nil(err)
+	s.Equal(s.uid, uid)
+	s.Equal(client.MetaFileName(s.uid), MetaFileName(s.uid))
+}
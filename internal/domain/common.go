@@ -1,17 +1,28 @@
 package domain
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
 	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/pkg/client"
 	"net/http"
 	"regexp"
-	"strconv"
 	"strings"
 )
 
 const (
-	partFilenamePiece = "_part_"
-	metaFilenamePiece = "_meta"
+	partFilenamePiece  = client.PartFilenamePiece
+	metaFilenamePiece  = client.MetaFilenamePiece
+	fileFilenamePiece  = "_file"
+	afterFilenamePiece = "_after"
+
+	failedCallbacksPrefix = "failed_callbacks/"
+
+	uploadSecretHeader = client.UploadSecretHeader
 )
 
 func init() {
@@ -24,12 +35,67 @@ func IsCorrectUuid(str string) bool {
 	return uuidRegexp.MatchString(str)
 }
 
+// ChunkFileName - names of chunks and meta are shared with pkg/client, which resumes uploads by them
 func ChunkFileName(uid string, num int) string {
-	return uid + partFilenamePiece + strconv.Itoa(num)
+	return client.ChunkFileName(uid, num)
 }
 
 func MetaFileName(uid string) string {
-	return uid + metaFilenamePiece
+	return client.MetaFileName(uid)
+}
+
+func FileRecordName(uid string) string {
+	return uid + fileFilenamePiece
+}
+
+// CallbackAfterName - body of callbackAfter is kept to resend it
+func CallbackAfterName(uid string) string {
+	return uid + afterFilenamePiece
+}
+
+func FailedCallbackName(id string) string {
+	return failedCallbacksPrefix + id
+}
+
+// NewUploadSecret returns random secret of upload session and its hash, which is stored in meta
+func NewUploadSecret() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret := hex.EncodeToString(b)
+	return secret, UploadSecretHash(secret), nil
+}
+
+func UploadSecretHash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckUploadSecret - uploads, which were started without secret, do not require it
+func CheckUploadSecret(secret string, secretHash string) error {
+	if secretHash == "" {
+		return nil
+	}
+	if secret == "" {
+		return exceptions.NewApiError(http.StatusUnauthorized, errors.New("upload secret is required"))
+	}
+	if subtle.ConstantTimeCompare([]byte(UploadSecretHash(secret)), []byte(secretHash)) != 1 {
+		return exceptions.NewApiError(http.StatusForbidden, errors.New("invalid upload secret"))
+	}
+	return nil
+}
+
+// CheckAdminToken authorizes request of admin api
+func CheckAdminToken(cfg port.AdminConfig, headers [][2]string) error {
+	adminToken := cfg.GetAdminToken()
+	if adminToken == "" {
+		return exceptions.NewApiError(http.StatusForbidden, errors.New("admin api is disabled"))
+	}
+	if subtle.ConstantTimeCompare([]byte(findHeader(headers, adminTokenHeader)), []byte(adminToken)) != 1 {
+		return exceptions.NewApiError(http.StatusUnauthorized, errors.New("invalid admin token"))
+	}
+	return nil
 }
 
 func ExtractUuidFromPartName(fn string) (string, error) {
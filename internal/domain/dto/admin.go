@@ -0,0 +1,39 @@
+package dto
+
+import "encoding/json"
+
+// AdminUpload - upload, which is not composed yet
+type AdminUpload struct {
+	Uuid        string `json:"uuid"`
+	Size        int64  `json:"size"`
+	FileName    string `json:"file_name,omitempty"`
+	Tenant      string `json:"tenant,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+	CreatedAt   int64  `json:"created_at,omitempty"`
+	ChunksCount int    `json:"chunks_count"`
+}
+
+// AdminUploadState - upload with counts of chunks, which are in storage
+type AdminUploadState struct {
+	AdminUpload
+	ReceivedChunks int `json:"received_chunks"`
+	MissingChunks  int `json:"missing_chunks"`
+}
+
+// AdminUploadsPage - uploads ordered by uuid, NextMarker is empty on the last page
+type AdminUploadsPage struct {
+	Items      []AdminUpload `json:"items"`
+	Limit      int           `json:"limit"`
+	NextMarker string        `json:"next_marker,omitempty"`
+}
+
+// FailedCallback - callback, which was not delivered after all retries
+type FailedCallback struct {
+	Id       string          `json:"id"`
+	Type     string          `json:"type"`
+	Url      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+	Errors   []string        `json:"errors"`
+	Attempts int             `json:"attempts"`
+	FailedAt int64           `json:"failed_at"`
+}
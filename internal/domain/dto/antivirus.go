@@ -0,0 +1,32 @@
+package dto
+
+const (
+	ScanStatusClean    = "clean"
+	ScanStatusInfected = "infected"
+	ScanStatusError    = "error"
+
+	ScanActionQuarantine = "quarantine"
+	ScanActionTag        = "tag"
+	ScanActionDelete     = "delete"
+)
+
+type ScanVerdict struct {
+	Status    string `json:"status"`
+	Signature string `json:"signature,omitempty"`
+	Action    string `json:"action,omitempty"`
+}
+
+func (v ScanVerdict) IsInfected() bool {
+	return v.Status == ScanStatusInfected
+}
+
+func (v ScanVerdict) GetSignature() string {
+	return v.Signature
+}
+
+// UploadCompleted - body of callbackAfter request
+type UploadCompleted struct {
+	UploaderStartResult
+	Scan       *ScanVerdict      `json:"scan,omitempty"`
+	Processing []ProcessorResult `json:"processing,omitempty"`
+}
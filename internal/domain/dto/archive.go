@@ -0,0 +1,12 @@
+package dto
+
+type ArchiveFile struct {
+	Uuid string `json:"uuid"`
+	Name string `json:"name,omitempty"`
+}
+
+// ArchiveRequest - list of files for archive download, Name is name of archive
+type ArchiveRequest struct {
+	Name  string        `json:"name,omitempty"`
+	Files []ArchiveFile `json:"files"`
+}
@@ -0,0 +1,34 @@
+package dto
+
+type TokenClaims struct {
+	Subject string
+	MaxSize int64
+	Uuid    string
+	Tags    map[string]string
+	Scopes  []string
+}
+
+func (c TokenClaims) GetSubject() string {
+	return c.Subject
+}
+
+func (c TokenClaims) GetMaxSize() int64 {
+	return c.MaxSize
+}
+
+func (c TokenClaims) GetUuid() string {
+	return c.Uuid
+}
+
+func (c TokenClaims) GetTags() map[string]string {
+	return c.Tags
+}
+
+func (c TokenClaims) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
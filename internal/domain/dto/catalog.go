@@ -0,0 +1,85 @@
+package dto
+
+import "strings"
+
+type CatalogEntry struct {
+	Uuid        string            `json:"uuid"`
+	Bucket      string            `json:"bucket,omitempty"`
+	Key         string            `json:"key"`
+	Size        int64             `json:"size"`
+	ContentType string            `json:"content_type"`
+	UserTags    map[string]string `json:"user_tags"`
+	FileName    string            `json:"file_name"`
+	Tenant      string            `json:"tenant,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	CreatedAt   int64             `json:"created_at"`
+	CompletedAt int64             `json:"completed_at"`
+}
+
+func (e CatalogEntry) GetUUID() string {
+	return e.Uuid
+}
+
+func (e CatalogEntry) GetCompletedAt() int64 {
+	return e.CompletedAt
+}
+
+type CatalogFilter struct {
+	Owner       string
+	Tenant      string
+	ContentType string
+	FileName    string
+	UserTags    map[string]string
+	CreatedFrom int64
+	CreatedTo   int64
+	// Cursor - next_cursor of previous page, list starts from newest entry without it
+	Cursor string
+	Limit  int
+	// MaxScan limits entries, which are read by one request, when filter matches few of them
+	MaxScan       int
+	lowerFileName string
+}
+
+func NewCatalogFilter() CatalogFilter {
+	return CatalogFilter{UserTags: make(map[string]string)}
+}
+
+// Matches checks all filter conditions except pagination
+func (f *CatalogFilter) Matches(e CatalogEntry) bool {
+	if f.Owner != "" && f.Owner != e.Owner {
+		return false
+	}
+	if f.Tenant != "" && f.Tenant != e.Tenant {
+		return false
+	}
+	if f.ContentType != "" && !strings.HasPrefix(e.ContentType, f.ContentType) {
+		return false
+	}
+	if f.FileName != "" {
+		if f.lowerFileName == "" {
+			f.lowerFileName = strings.ToLower(f.FileName)
+		}
+		if !strings.Contains(strings.ToLower(e.FileName), f.lowerFileName) {
+			return false
+		}
+	}
+	if f.CreatedFrom > 0 && e.CreatedAt < f.CreatedFrom {
+		return false
+	}
+	if f.CreatedTo > 0 && e.CreatedAt > f.CreatedTo {
+		return false
+	}
+	for k, v := range f.UserTags {
+		if tv, ok := e.UserTags[k]; !ok || tv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// CatalogPage - NextCursor is empty on the last page
+type CatalogPage struct {
+	Items      []CatalogEntry `json:"items"`
+	Limit      int            `json:"limit"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
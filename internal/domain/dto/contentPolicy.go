@@ -0,0 +1,30 @@
+package dto
+
+import "strings"
+
+// ContentPolicy - lists of allowed and denied MIME types. Type matches entry, which is equal to it or is its prefix ("image/")
+type ContentPolicy struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+func (p ContentPolicy) IsEmpty() bool {
+	return len(p.Allow) == 0 && len(p.Deny) == 0
+}
+
+func (p ContentPolicy) IsAllowed(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if matchContentType(p.Deny, mediaType) {
+		return false
+	}
+	return len(p.Allow) == 0 || matchContentType(p.Allow, mediaType)
+}
+
+func matchContentType(list []string, mediaType string) bool {
+	for _, entry := range list {
+		if strings.HasPrefix(mediaType, strings.ToLower(entry)) {
+			return true
+		}
+	}
+	return false
+}
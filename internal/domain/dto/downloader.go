@@ -0,0 +1,39 @@
+package dto
+
+type ByteRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+func (r ByteRange) GetLength() int64 {
+	return r.End - r.Start + 1
+}
+
+type DownloadCallbackRequest struct {
+	Uuid         string            `json:"uuid"`
+	Bucket       string            `json:"bucket,omitempty"`
+	Key          string            `json:"key"`
+	Size         int64             `json:"size"`
+	ContentType  string            `json:"content_type"`
+	UserMetadata map[string]string `json:"metadata"`
+	UserTags     map[string]string `json:"user_tags"`
+	Range        *ByteRange        `json:"range"`
+}
+
+type DownloadResult struct {
+	StatusCode  int
+	ContentType string
+	Headers     [][2]string
+}
+
+func (r DownloadResult) GetStatusCode() int {
+	return r.StatusCode
+}
+
+func (r DownloadResult) GetContentType() string {
+	return r.ContentType
+}
+
+func (r DownloadResult) GetHeaders() [][2]string {
+	return r.Headers
+}
@@ -0,0 +1,4 @@
+package dto
+
+// EnvelopeSegmentSize - size of plaintext segment of envelope encryption. Chunks of upload must be aligned by segments
+const EnvelopeSegmentSize = 64 * 1024
@@ -0,0 +1,114 @@
+package dto
+
+// FileLocation - place of composed file in storage, empty Bucket means default final bucket
+type FileLocation struct {
+	Bucket string `json:"bucket,omitempty"`
+	Key    string `json:"key"`
+	// Encryption - reference to keys of file, it is never serialized
+	Encryption *Encryption `json:"-"`
+}
+
+// Encryption - SSE-C key of upload is sealed by master key, empty EncryptedKey means key derived from master key by Uuid.
+// DataKey - key of envelope encryption by Filup, it is wrapped by master key of envelope
+type Encryption struct {
+	Uuid         string
+	SseC         bool
+	EncryptedKey string
+	DataKey      string
+	// ObjectKey - object is made from file and is encrypted by own key, which is derived from DataKey by ObjectKey
+	ObjectKey string
+}
+
+// NewEncryption returns nil for files, which are not encrypted by SSE-C or envelope
+func NewEncryption(uuid string, sseC bool, encryptedKey string, dataKey string) *Encryption {
+	if !sseC && dataKey == "" {
+		return nil
+	}
+	return &Encryption{Uuid: uuid, SseC: sseC, EncryptedKey: encryptedKey, DataKey: dataKey}
+}
+
+func (e *Encryption) IsSseC() bool {
+	return e != nil && e.SseC
+}
+
+func (e *Encryption) GetDataKey() string {
+	if e == nil {
+		return ""
+	}
+	return e.DataKey
+}
+
+func (e *Encryption) GetObjectKey() string {
+	if e == nil {
+		return ""
+	}
+	return e.ObjectKey
+}
+
+// ForObject returns encryption of object, which is made from file and is stored with key
+func (e *Encryption) ForObject(key string) *Encryption {
+	if e == nil {
+		return nil
+	}
+	object := *e
+	object.ObjectKey = key
+	return &object
+}
+
+func NewFileLocation(bucket, key, uuid string, encryption *Encryption) FileLocation {
+	if key == "" {
+		key = uuid
+	}
+	return FileLocation{Bucket: bucket, Key: key, Encryption: encryption}
+}
+
+func (l FileLocation) GetBucket() string {
+	return l.Bucket
+}
+
+func (l FileLocation) GetKey() string {
+	return l.Key
+}
+
+type FileRecord struct {
+	Uuid     string            `json:"uuid"`
+	Bucket   string            `json:"bucket,omitempty"`
+	Key      string            `json:"key"`
+	Size     int64             `json:"size"`
+	UserTags map[string]string `json:"user_tags"`
+	// SseC - file is encrypted by SSE-C key, EncryptedKey is key of client sealed by master key
+	SseC         bool   `json:"sse_c,omitempty"`
+	EncryptedKey string `json:"encrypted_key,omitempty"`
+	// DataKey - key of envelope encryption wrapped by master key
+	DataKey string `json:"data_key,omitempty"`
+	// Derived - objects made by processors
+	Derived []FileLocation `json:"derived,omitempty"`
+	// ScanStatus - verdict of antivirus, empty if scanning is disabled
+	ScanStatus string `json:"scan_status,omitempty"`
+}
+
+func (r FileRecord) GetUUID() string {
+	return r.Uuid
+}
+
+func (r FileRecord) GetLocation() FileLocation {
+	return NewFileLocation(r.Bucket, r.Key, r.Uuid, NewEncryption(r.Uuid, r.SseC, r.EncryptedKey, r.DataKey))
+}
+
+func (r FileRecord) GetDerived() []FileLocation {
+	return r.Derived
+}
+
+func NewFileRecord(metaInfo UploaderStartResult) FileRecord {
+	location := metaInfo.GetLocation()
+	return FileRecord{
+		Uuid:         metaInfo.GetUUID(),
+		Bucket:       location.GetBucket(),
+		Key:          location.GetKey(),
+		Size:         metaInfo.GetSize(),
+		UserTags:     metaInfo.GetUserTags(),
+		SseC:         metaInfo.SseC,
+		EncryptedKey: metaInfo.EncryptedKey,
+		DataKey:      metaInfo.DataKey,
+	}
+}
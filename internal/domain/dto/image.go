@@ -0,0 +1,29 @@
+package dto
+
+import "strconv"
+
+const (
+	ImageFitContain = "contain"
+	ImageFitCover   = "cover"
+	ImageFitFill    = "fill"
+
+	ImageFormatJpeg = "jpeg"
+	ImageFormatPng  = "png"
+)
+
+// ImageParams - requested variant of image. Zero Width or Height is calculated by proportions of source
+type ImageParams struct {
+	Width  int
+	Height int
+	Fit    string
+	Format string
+}
+
+// VariantName - name of cached variant, it depends only on parameters
+func (p ImageParams) VariantName() string {
+	return strconv.Itoa(p.Width) + "x" + strconv.Itoa(p.Height) + "-" + p.Fit + "." + p.Format
+}
+
+func (p ImageParams) GetContentType() string {
+	return "image/" + p.Format
+}
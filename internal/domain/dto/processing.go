@@ -0,0 +1,15 @@
+package dto
+
+const (
+	ProcessingStatusOk      = "ok"
+	ProcessingStatusSkipped = "skipped"
+	ProcessingStatusError   = "error"
+)
+
+type ProcessorResult struct {
+	Name    string                 `json:"name"`
+	Status  string                 `json:"status"`
+	Error   string                 `json:"error,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	Objects []FileLocation         `json:"objects,omitempty"`
+}
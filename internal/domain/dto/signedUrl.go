@@ -0,0 +1,27 @@
+package dto
+
+type SignUrlRequest struct {
+	Uuid        string `json:"uuid"`
+	Ttl         int64  `json:"ttl"`
+	Ip          string `json:"ip"`
+	Disposition string `json:"disposition"`
+}
+
+type SignedUrl struct {
+	Url     string `json:"url"`
+	Expires int64  `json:"expires"`
+}
+
+// DownloadOptions - options of download, which are granted by signed url
+type DownloadOptions struct {
+	Signed      bool
+	Disposition string
+}
+
+func (o DownloadOptions) IsSigned() bool {
+	return o.Signed
+}
+
+func (o DownloadOptions) GetDisposition() string {
+	return o.Disposition
+}
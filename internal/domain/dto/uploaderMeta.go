@@ -31,6 +31,47 @@ type UploaderStartResult struct {
 	Size     int64                    `json:"size"`
 	UserTags map[string]string        `json:"user_tags"`
 	Chunks   map[string]UploaderChunk `json:"chunks"`
+	Bucket   string                   `json:"bucket,omitempty"`
+	Key      string                   `json:"key,omitempty"`
+
+	FileName    string `json:"file_name,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Tenant      string `json:"tenant,omitempty"`
+	Route       string `json:"route,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+	CreatedAt   int64  `json:"created_at,omitempty"`
+
+	// RequestHash - fingerprint of start request, used to recognize replays of start
+	RequestHash string `json:"request_hash,omitempty"`
+	// SecretHash - hash of upload secret, which is required to upload parts, get status and abort upload
+	SecretHash string `json:"secret_hash,omitempty"`
+
+	// DetectedContentType - MIME type, which is detected by first bytes of file
+	DetectedContentType string         `json:"detected_content_type,omitempty"`
+	ContentPolicy       *ContentPolicy `json:"content_policy,omitempty"`
+	// Processors - names of processors, which are applied to composed file
+	Processors []string `json:"processors,omitempty"`
+	// SseC - file is encrypted by SSE-C key, EncryptedKey is key supplied by client and sealed by master key
+	SseC         bool   `json:"sse_c,omitempty"`
+	EncryptedKey string `json:"encrypted_key,omitempty"`
+	// DataKey - key of envelope encryption wrapped by master key
+	DataKey string `json:"data_key,omitempty"`
+	// TraceParent - W3C traceparent of request with last part, span of composing is linked to it
+	TraceParent string `json:"-"`
+	// RequestId - id of request with last part, records of composing are correlated with it
+	RequestId string `json:"-"`
+}
+
+// GetFileContentType returns detected MIME type of file, declared by client type is used until detection
+func (u *UploaderStartResult) GetFileContentType() string {
+	if u.DetectedContentType != "" {
+		return u.DetectedContentType
+	}
+	return u.ContentType
+}
+
+func (u *UploaderStartResult) GetSecretHash() string {
+	return u.SecretHash
 }
 
 func (u *UploaderStartResult) GetUUID() string {
@@ -49,6 +90,10 @@ func (u *UploaderStartResult) GetUserTags() map[string]string {
 	return u.UserTags
 }
 
+func (u *UploaderStartResult) GetLocation() FileLocation {
+	return NewFileLocation(u.Bucket, u.Key, u.Uuid, NewEncryption(u.Uuid, u.SseC, u.EncryptedKey, u.DataKey))
+}
+
 func NewUploaderStartResult(
 	uuid string,
 	chunks map[string]UploaderChunk,
@@ -62,3 +107,8 @@ func NewUploaderStartResult(
 		UserTags: userTags,
 	}
 }
+
+type UploadStatus struct {
+	UploaderStartResult
+	UploadedChunks []string `json:"uploaded_chunks"`
+}
@@ -0,0 +1,263 @@
+package domain
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"io"
+)
+
+const (
+	envelopeKeySize   = 32
+	envelopeNonceSize = 12
+	envelopeTagSize   = 16
+	// envelopeOverhead - every segment is stored with its nonce and authentication tag
+	envelopeOverhead          = envelopeNonceSize + envelopeTagSize
+	envelopeCipherSegmentSize = dto.EnvelopeSegmentSize + envelopeOverhead
+)
+
+// Envelope encrypts files with AES-GCM by segments, so any range of file can be decrypted without reading of whole file.
+// Every file has own data key wrapped by master key. Nonce of segment is random and is stored before it, so chunk,
+// which is uploaded again with other bytes, never reuses nonce. Number of segment and mark of last segment are
+// in additional data, so reordering and truncation of segments are detected
+type Envelope struct {
+	cfg port.EnvelopeConfig
+}
+
+func ProvideEnvelope(cfg port.EnvelopeConfig) *Envelope {
+	return &Envelope{cfg: cfg}
+}
+
+// NewDataKey returns random data key wrapped by master key or empty string if envelope encryption is disabled
+func (e *Envelope) NewDataKey() (string, error) {
+	if !e.cfg.IsEnabled() {
+		return "", nil
+	}
+	key := make([]byte, envelopeKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", errors.Wrap(err, "Envelope.NewDataKey")
+	}
+	return e.wrapKey(key)
+}
+
+// ObjectDataKey returns data key of object, which is made from file, e.g. thumbnail or resized image.
+// It is derived from data key of file by HMAC with key of object, so segments of different objects
+// are never encrypted by the same key and nonce
+func (e *Envelope) ObjectDataKey(dataKey string, objectKey string) (string, error) {
+	key, err := e.unwrapKey(dataKey)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(objectKey))
+	return e.wrapKey(mac.Sum(nil))
+}
+
+// EncryptPart encrypts part of file, which starts from offset, and returns encrypted stream with its size.
+// Offset must be aligned by segment
+func (e *Envelope) EncryptPart(dataKey string, offset, size, fileSize int64, content io.Reader) (io.Reader, int64, error) {
+	if offset%dto.EnvelopeSegmentSize != 0 {
+		return nil, 0, errors.New("Envelope: offset of part is not aligned by segment")
+	}
+	aead, err := e.fileCipher(dataKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &segmentEncrypter{
+		aead:      aead,
+		src:       io.LimitReader(content, size),
+		index:     offset / dto.EnvelopeSegmentSize,
+		lastIndex: lastSegmentIndex(fileSize),
+		buf:       make([]byte, dto.EnvelopeSegmentSize),
+		sealed:    make([]byte, envelopeCipherSegmentSize),
+	}, EnvelopeCipherSize(size), nil
+}
+
+// Decrypt returns decrypted stream of segments from firstSegment. Skip bytes of first segment are dropped,
+// stream is limited by length
+func (e *Envelope) Decrypt(dataKey string, stream io.ReadCloser, firstSegment, plainSize, skip, length int64) (io.ReadCloser, error) {
+	aead, err := e.fileCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &segmentDecrypter{
+		aead:      aead,
+		src:       stream,
+		index:     firstSegment,
+		lastIndex: lastSegmentIndex(plainSize),
+		skip:      skip,
+		remain:    length,
+		buf:       make([]byte, envelopeCipherSegmentSize),
+	}, nil
+}
+
+func (e *Envelope) fileCipher(dataKey string) (cipher.AEAD, error) {
+	key, err := e.unwrapKey(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return newGcm(key)
+}
+
+func (e *Envelope) wrapKey(key []byte) (string, error) {
+	master, err := newGcm(e.cfg.GetMasterKey())
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, master.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.Wrap(err, "Envelope.WrapKey")
+	}
+	return base64.StdEncoding.EncodeToString(master.Seal(nonce, nonce, key, nil)), nil
+}
+
+func (e *Envelope) unwrapKey(dataKey string) ([]byte, error) {
+	if !e.cfg.IsEnabled() {
+		return nil, errors.New("Envelope: file is encrypted, but master key is not configured")
+	}
+	master, err := newGcm(e.cfg.GetMasterKey())
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(dataKey)
+	if err != nil || len(wrapped) < master.NonceSize() {
+		return nil, errors.New("Envelope: incorrect data key")
+	}
+	key, err := master.Open(nil, wrapped[:master.NonceSize()], wrapped[master.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Envelope.UnwrapKey")
+	}
+	return key, nil
+}
+
+// EnvelopeCipherSize - every segment has nonce and authentication tag
+func EnvelopeCipherSize(plainSize int64) int64 {
+	segments := (plainSize + dto.EnvelopeSegmentSize - 1) / dto.EnvelopeSegmentSize
+	return plainSize + segments*envelopeOverhead
+}
+
+func EnvelopePlainSize(cipherSize int64) int64 {
+	segments := (cipherSize + envelopeCipherSegmentSize - 1) / envelopeCipherSegmentSize
+	return cipherSize - segments*envelopeOverhead
+}
+
+// envelopeCipherRange returns range of encrypted file, which contains segments of plaintext range, and number of first segment
+func envelopeCipherRange(plainRange dto.ByteRange, cipherSize int64) (dto.ByteRange, int64) {
+	first := plainRange.Start / dto.EnvelopeSegmentSize
+	last := plainRange.End / dto.EnvelopeSegmentSize
+	end := (last+1)*envelopeCipherSegmentSize - 1
+	if end > cipherSize-1 {
+		end = cipherSize - 1
+	}
+	return dto.ByteRange{Start: first * envelopeCipherSegmentSize, End: end}, first
+}
+
+func lastSegmentIndex(plainSize int64) int64 {
+	if plainSize < 1 {
+		return -1
+	}
+	return (plainSize - 1) / dto.EnvelopeSegmentSize
+}
+
+func newGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "Envelope.NewCipher")
+	}
+	return cipher.NewGCM(block)
+}
+
+func segmentAdditionalData(index, lastIndex int64) []byte {
+	data := make([]byte, 9)
+	binary.BigEndian.PutUint64(data, uint64(index))
+	if index == lastIndex {
+		data[8] = 1
+	}
+	return data
+}
+
+type segmentEncrypter struct {
+	aead      cipher.AEAD
+	src       io.Reader
+	index     int64
+	lastIndex int64
+	buf       []byte
+	sealed    []byte
+	out       []byte
+}
+
+func (s *segmentEncrypter) Read(p []byte) (int, error) {
+	if len(s.out) == 0 {
+		n, err := io.ReadFull(s.src, s.buf)
+		if n == 0 {
+			if err == io.EOF {
+				return 0, io.EOF
+			}
+			return 0, err
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		nonce := s.sealed[:envelopeNonceSize]
+		if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+			return 0, errors.Wrap(err, "Envelope.Encrypt")
+		}
+		s.out = s.aead.Seal(nonce, nonce, s.buf[:n], segmentAdditionalData(s.index, s.lastIndex))
+		s.index++
+	}
+	n := copy(p, s.out)
+	s.out = s.out[n:]
+	return n, nil
+}
+
+type segmentDecrypter struct {
+	aead      cipher.AEAD
+	src       io.ReadCloser
+	index     int64
+	lastIndex int64
+	skip      int64
+	remain    int64
+	buf       []byte
+	plain     []byte
+	out       []byte
+}
+
+func (s *segmentDecrypter) Read(p []byte) (int, error) {
+	for len(s.out) == 0 {
+		if s.remain < 1 {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(s.src, s.buf)
+		if n <= envelopeNonceSize {
+			return 0, errors.New("Envelope: encrypted file is truncated")
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		s.plain, err = s.aead.Open(s.plain[:0], s.buf[:envelopeNonceSize], s.buf[envelopeNonceSize:n], segmentAdditionalData(s.index, s.lastIndex))
+		if err != nil {
+			return 0, errors.Wrap(err, "Envelope.Decrypt")
+		}
+		s.index++
+		s.out = s.plain[s.skip:]
+		s.skip = 0
+		if int64(len(s.out)) > s.remain {
+			s.out = s.out[:s.remain]
+		}
+		s.remain -= int64(len(s.out))
+	}
+	n := copy(p, s.out)
+	s.out = s.out[n:]
+	return n, nil
+}
+
+func (s *segmentDecrypter) Close() error {
+	return s.src.Close()
+}
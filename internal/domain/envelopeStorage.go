@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"io"
+	"io/ioutil"
+)
+
+// EnvelopeStorage encrypts files with data key on write and decrypts them on read.
+// Files without data key are passed to storage as is
+type EnvelopeStorage struct {
+	port.RawStorageFiles
+	streamer port.RawFileStreamer
+	envelope *Envelope
+}
+
+func ProvideEnvelopeStorage(files port.RawStorageFiles, streamer port.RawFileStreamer, envelope *Envelope) *EnvelopeStorage {
+	return &EnvelopeStorage{
+		RawStorageFiles: files,
+		streamer:        streamer,
+		envelope:        envelope,
+	}
+}
+
+// PutFile stores object, which is made from file. Data key of file is never used for it as is,
+// object is encrypted by key derived for it
+func (es *EnvelopeStorage) PutFile(location dto.FileLocation, contentType string, size int64, content io.Reader) error {
+	if location.Encryption.GetDataKey() == "" {
+		return es.RawStorageFiles.PutFile(location, contentType, size, content)
+	}
+	if location.Encryption.GetObjectKey() == "" {
+		return errors.New("EnvelopeStorage: data key of file can not be used for another object")
+	}
+	dataKey, err := es.dataKey(location)
+	if err != nil {
+		return err
+	}
+	encrypted, cipherSize, err := es.envelope.EncryptPart(dataKey, 0, size, size, content)
+	if err != nil {
+		return err
+	}
+	return es.RawStorageFiles.PutFile(location, contentType, cipherSize, encrypted)
+}
+
+func (es *EnvelopeStorage) GetFileInfo(location dto.FileLocation) (port.FileInfo, error) {
+	info, err := es.streamer.GetFileInfo(location)
+	if err != nil || location.Encryption.GetDataKey() == "" {
+		return info, err
+	}
+	return envelopeFileInfo{FileInfo: info, size: EnvelopePlainSize(info.GetSize())}, nil
+}
+
+func (es *EnvelopeStorage) GetFileTags(location dto.FileLocation) (map[string]string, error) {
+	return es.streamer.GetFileTags(location)
+}
+
+// GetFileStream reads only segments of encrypted file, which contain requested range
+func (es *EnvelopeStorage) GetFileStream(location dto.FileLocation, byteRange *dto.ByteRange) (io.ReadCloser, port.FileInfo, error) {
+	dataKey := location.Encryption.GetDataKey()
+	if dataKey == "" {
+		return es.streamer.GetFileStream(location, byteRange)
+	}
+	dataKey, err := es.dataKey(location)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := es.streamer.GetFileInfo(location)
+	if err != nil {
+		return nil, nil, err
+	}
+	plainSize := EnvelopePlainSize(info.GetSize())
+	plainInfo := envelopeFileInfo{FileInfo: info, size: plainSize}
+	if plainSize < 1 {
+		return ioutil.NopCloser(bytes.NewReader(nil)), plainInfo, nil
+	}
+	plainRange := dto.ByteRange{Start: 0, End: plainSize - 1}
+	if byteRange != nil {
+		plainRange = *byteRange
+	}
+	cipherRange, firstSegment := envelopeCipherRange(plainRange, info.GetSize())
+	stream, _, err := es.streamer.GetFileStream(location, &cipherRange)
+	if err != nil {
+		return nil, nil, err
+	}
+	skip := plainRange.Start - firstSegment*dto.EnvelopeSegmentSize
+	decrypted, err := es.envelope.Decrypt(dataKey, stream, firstSegment, plainSize, skip, plainRange.GetLength())
+	if err != nil {
+		_ = stream.Close()
+		return nil, nil, err
+	}
+	return decrypted, plainInfo, nil
+}
+
+func (es *EnvelopeStorage) dataKey(location dto.FileLocation) (string, error) {
+	if objectKey := location.Encryption.GetObjectKey(); objectKey != "" {
+		return es.envelope.ObjectDataKey(location.Encryption.GetDataKey(), objectKey)
+	}
+	return location.Encryption.GetDataKey(), nil
+}
+
+// envelopeFileInfo reports size of decrypted file
+type envelopeFileInfo struct {
+	port.FileInfo
+	size int64
+}
+
+func (i envelopeFileInfo) GetSize() int64 {
+	return i.size
+}
@@ -0,0 +1,219 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+)
+
+type suiteEnvelope struct {
+	suite.Suite
+	envelope *Envelope
+	dataKey  string
+	plain    []byte
+	cipher   []byte
+}
+
+func TestEnvelope(t *testing.T) {
+	suite.Run(t, new(suiteEnvelope))
+}
+
+func (s *suiteEnvelope) SetupSuite() {
+	cfg := config.EnvelopeConfig{MasterKey: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))}.AfterLoad()
+	s.envelope = ProvideEnvelope(cfg)
+	var err error
+	s.dataKey, err = s.envelope.NewDataKey()
+	s.Require().Nil(err)
+	s.Require().NotEmpty(s.dataKey)
+
+	s.plain = make([]byte, 3*dto.EnvelopeSegmentSize+1000)
+	rand.New(rand.NewSource(1)).Read(s.plain)
+	// file is uploaded by two chunks, which are encrypted independently
+	chunkSize := int64(2 * dto.EnvelopeSegmentSize)
+	fileSize := int64(len(s.plain))
+	for _, offset := range []int64{0, chunkSize} {
+		size := chunkSize
+		if offset+size > fileSize {
+			size = fileSize - offset
+		}
+		reader, cipherSize, err := s.envelope.EncryptPart(s.dataKey, offset, size, fileSize, bytes.NewReader(s.plain[offset:]))
+		s.Require().Nil(err)
+		part, err := io.ReadAll(reader)
+		s.Require().Nil(err)
+		s.Require().Equal(cipherSize, int64(len(part)))
+		s.cipher = append(s.cipher, part...)
+	}
+}
+
+func (s *suiteEnvelope) decrypt(cipher []byte, byteRange dto.ByteRange) ([]byte, error) {
+	cipherRange, first := envelopeCipherRange(byteRange, int64(len(cipher)))
+	stream := ioutil.NopCloser(bytes.NewReader(cipher[cipherRange.Start : cipherRange.End+1]))
+	skip := byteRange.Start - first*dto.EnvelopeSegmentSize
+	reader, err := s.envelope.Decrypt(s.dataKey, stream, first, EnvelopePlainSize(int64(len(cipher))), skip, byteRange.GetLength())
+	s.Require().Nil(err)
+	return io.ReadAll(reader)
+}
+
+func (s *suiteEnvelope) TestSizes() {
+	s.Equal(EnvelopeCipherSize(int64(len(s.plain))), int64(len(s.cipher)))
+	s.Equal(int64(len(s.plain)), EnvelopePlainSize(int64(len(s.cipher))))
+	s.Equal(int64(0), EnvelopeCipherSize(0))
+	s.Equal(int64(dto.EnvelopeSegmentSize), EnvelopePlainSize(EnvelopeCipherSize(dto.EnvelopeSegmentSize)))
+}
+
+func (s *suiteEnvelope) TestDecryptRanges() {
+	last := int64(len(s.plain)) - 1
+	ranges := []dto.ByteRange{
+		{Start: 0, End: last},
+		{Start: 10, End: 20},
+		{Start: dto.EnvelopeSegmentSize - 5, End: 2*dto.EnvelopeSegmentSize + 5},
+		{Start: last - 100, End: last},
+	}
+	for _, r := range ranges {
+		content, err := s.decrypt(s.cipher, r)
+		s.Require().Nil(err)
+		s.Equal(s.plain[r.Start:r.End+1], content)
+	}
+}
+
+func (s *suiteEnvelope) TestTamperedFile() {
+	tampered := append([]byte(nil), s.cipher...)
+	tampered[100] ^= 1
+	_, err := s.decrypt(tampered, dto.ByteRange{Start: 0, End: 10})
+	s.NotNil(err)
+}
+
+func (s *suiteEnvelope) TestTruncatedFile() {
+	truncated := s.cipher[:3*envelopeCipherSegmentSize]
+	_, err := s.decrypt(truncated, dto.ByteRange{Start: 0, End: EnvelopePlainSize(int64(len(truncated))) - 1})
+	s.NotNil(err)
+}
+
+func (s *suiteEnvelope) TestPartUploadedAgain() {
+	chunkSize := int64(2 * dto.EnvelopeSegmentSize)
+	other := append([]byte(nil), s.plain...)
+	other[0] ^= 1
+	reader, _, err := s.envelope.EncryptPart(s.dataKey, 0, chunkSize, int64(len(other)), bytes.NewReader(other))
+	s.Require().Nil(err)
+	part, err := io.ReadAll(reader)
+	s.Require().Nil(err)
+	// segments of the same position get different nonces
+	s.NotEqual(s.cipher[:envelopeNonceSize], part[:envelopeNonceSize])
+
+	replaced := append(part, s.cipher[len(part):]...)
+	content, err := s.decrypt(replaced, dto.ByteRange{Start: 0, End: int64(len(other)) - 1})
+	s.Require().Nil(err)
+	s.Equal(other, content)
+}
+
+func (s *suiteEnvelope) TestDisabled() {
+	envelope := ProvideEnvelope(config.EnvelopeConfig{}.AfterLoad())
+	key, err := envelope.NewDataKey()
+	s.Nil(err)
+	s.Empty(key)
+	_, _, err = envelope.EncryptPart(s.dataKey, 0, 1, 1, bytes.NewReader([]byte{1}))
+	s.NotNil(err)
+}
+
+func (s *suiteEnvelope) TestObjectDataKey() {
+	objectKey, err := s.envelope.ObjectDataKey(s.dataKey, "a/b.thumb.jpg")
+	s.Require().Nil(err)
+	// wrapped keys differ by nonce, but wrap the same derived key
+	sameKey, err := s.envelope.ObjectDataKey(s.dataKey, "a/b.thumb.jpg")
+	s.Require().Nil(err)
+	otherKey, err := s.envelope.ObjectDataKey(s.dataKey, "a/b.noexif.jpg")
+	s.Require().Nil(err)
+	key, err := s.envelope.unwrapKey(objectKey)
+	s.Require().Nil(err)
+	same, err := s.envelope.unwrapKey(sameKey)
+	s.Require().Nil(err)
+	other, err := s.envelope.unwrapKey(otherKey)
+	s.Require().Nil(err)
+	file, err := s.envelope.unwrapKey(s.dataKey)
+	s.Require().Nil(err)
+	s.Equal(key, same)
+	s.NotEqual(key, other)
+	s.NotEqual(key, file)
+}
+
+// memoryStorage keeps objects in memory
+type memoryStorage struct {
+	*fakeStorageFiles
+	objects map[string][]byte
+}
+
+type memoryFileInfo struct {
+	size int64
+}
+
+func (i memoryFileInfo) GetSize() int64 {
+	return i.size
+}
+
+func (i memoryFileInfo) GetContentType() string {
+	return ""
+}
+
+func (i memoryFileInfo) GetUserMetadata() map[string]string {
+	return nil
+}
+
+func (i memoryFileInfo) GetETag() string {
+	return ""
+}
+
+func (m *memoryStorage) PutFile(location dto.FileLocation, contentType string, size int64, content io.Reader) error {
+	data, err := io.ReadAll(content)
+	m.objects[location.GetKey()] = data
+	return err
+}
+
+func (m *memoryStorage) GetFileInfo(location dto.FileLocation) (port.FileInfo, error) {
+	return memoryFileInfo{size: int64(len(m.objects[location.GetKey()]))}, nil
+}
+
+func (m *memoryStorage) GetFileTags(location dto.FileLocation) (map[string]string, error) {
+	return nil, nil
+}
+
+func (m *memoryStorage) GetFileStream(location dto.FileLocation, byteRange *dto.ByteRange) (io.ReadCloser, port.FileInfo, error) {
+	data := m.objects[location.GetKey()]
+	if byteRange != nil {
+		data = data[byteRange.Start : byteRange.End+1]
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), memoryFileInfo{size: int64(len(data))}, nil
+}
+
+func (s *suiteEnvelope) TestStorageObjects() {
+	raw := &memoryStorage{fakeStorageFiles: &fakeStorageFiles{}, objects: make(map[string][]byte)}
+	storage := ProvideEnvelopeStorage(raw, raw, s.envelope)
+	encryption := dto.NewEncryption("a", false, "", s.dataKey)
+	content := s.plain[:1000]
+
+	err := storage.PutFile(dto.FileLocation{Key: "a.thumb.jpg", Encryption: encryption}, "image/jpeg", 1000, bytes.NewReader(content))
+	s.NotNil(err)
+
+	location := dto.FileLocation{Key: "a.thumb.jpg", Encryption: encryption.ForObject("a.thumb.jpg")}
+	s.Require().Nil(storage.PutFile(location, "image/jpeg", 1000, bytes.NewReader(content)))
+	s.NotEqual(content, raw.objects["a.thumb.jpg"])
+
+	stream, info, err := storage.GetFileStream(location, nil)
+	s.Require().Nil(err)
+	s.Equal(int64(1000), info.GetSize())
+	decrypted, err := io.ReadAll(stream)
+	s.Require().Nil(err)
+	s.Equal(content, decrypted)
+
+	// object is not readable by data key of file
+	stream, _, err = storage.GetFileStream(dto.FileLocation{Key: "a.thumb.jpg", Encryption: encryption}, nil)
+	s.Require().Nil(err)
+	_, err = io.ReadAll(stream)
+	s.NotNil(err)
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FailedCallbacks keeps undelivered callbacks in meta storage, so they can be inspected and replayed by admin api.
+// Callbacks, which failed earlier than uploader.failedCallbacksTtl, are removed on saving of new one
+type FailedCallbacks struct {
+	storage      port.StorageMeta
+	lister       port.StorageMetaLister
+	cleaner      port.StorageCleaner
+	uuidProvider UuidProvider
+	cfg          port.UploaderConfig
+}
+
+func ProvideFailedCallbacks(
+	storage port.StorageMeta,
+	lister port.StorageMetaLister,
+	cleaner port.StorageCleaner,
+	uuidProvider UuidProvider,
+	cfg port.UploaderConfig,
+) *FailedCallbacks {
+	return &FailedCallbacks{storage: storage, lister: lister, cleaner: cleaner, uuidProvider: uuidProvider, cfg: cfg}
+}
+
+func (fc *FailedCallbacks) Save(callback dto.FailedCallback) error {
+	if callback.Id == "" {
+		callback.Id = fc.uuidProvider.NewUuid()
+	}
+	content, err := jsoniter.Marshal(callback)
+	if err != nil {
+		return errors.Wrap(err, "FailedCallbacks.Save")
+	}
+	if err = fc.storage.PutMetaFile(FailedCallbackName(callback.Id), content); err != nil {
+		return errors.Wrap(err, "FailedCallbacks.Save")
+	}
+	return errors.Wrap(fc.removeExpired(), "FailedCallbacks.Save")
+}
+
+func (fc *FailedCallbacks) Load(id string) (dto.FailedCallback, error) {
+	if !IsCorrectUuid(id) {
+		return dto.FailedCallback{}, exceptions.NewApiError(http.StatusBadRequest, errors.New("id must be correct uuid"))
+	}
+	content, err := fc.storage.GetMetaFile(FailedCallbackName(id))
+	if err != nil {
+		return dto.FailedCallback{}, exceptions.NewApiError(http.StatusInternalServerError, errors.Wrap(err, "error in meta storage"))
+	}
+	if len(content) < 1 {
+		return dto.FailedCallback{}, exceptions.NewApiError(http.StatusNotFound, errors.New("failed callback "+id+" not found"))
+	}
+	var callback dto.FailedCallback
+	if err = jsoniter.Unmarshal(content, &callback); err != nil {
+		return dto.FailedCallback{}, exceptions.NewApiError(http.StatusInternalServerError, errors.Wrap(err, "error while deserialize failed callback"))
+	}
+	return callback, nil
+}
+
+// List returns failed callbacks from oldest to newest, expired callbacks are skipped
+func (fc *FailedCallbacks) List() ([]dto.FailedCallback, error) {
+	names, err := fc.lister.ListMetaFiles(failedCallbacksPrefix, "", 0)
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	result := make([]dto.FailedCallback, 0, len(names))
+	for _, name := range names {
+		callback, err := fc.Load(strings.TrimPrefix(name, failedCallbacksPrefix))
+		if apiErr, ok := err.(exceptions.ApiError); ok && apiErr.GetCode() == http.StatusNotFound {
+			// callback was replayed or removed after listing
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if fc.isExpired(callback) {
+			continue
+		}
+		result = append(result, callback)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].FailedAt < result[j].FailedAt
+	})
+	return result, nil
+}
+
+// removeExpired removes callbacks, which failed earlier than ttl
+func (fc *FailedCallbacks) removeExpired() error {
+	if fc.cfg.GetFailedCallbacksTtl() <= 0 {
+		return nil
+	}
+	names, err := fc.lister.ListMetaFiles(failedCallbacksPrefix, "", 0)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		callback, err := fc.Load(strings.TrimPrefix(name, failedCallbacksPrefix))
+		if err != nil {
+			continue
+		}
+		if fc.isExpired(callback) {
+			if err = fc.Remove(callback.Id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (fc *FailedCallbacks) isExpired(callback dto.FailedCallback) bool {
+	ttl := fc.cfg.GetFailedCallbacksTtl()
+	return ttl > 0 && time.Since(time.Unix(callback.FailedAt, 0)) > ttl
+}
+
+func (fc *FailedCallbacks) Remove(id string) error {
+	return errors.Wrap(fc.cleaner.RemoveMeta(FailedCallbackName(id)), "FailedCallbacks.Remove")
+}
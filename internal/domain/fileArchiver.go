@@ -0,0 +1,157 @@
+package domain
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultArchiveName = "files.zip"
+
+// archiveEntry - authorized file of archive
+type archiveEntry struct {
+	uuid     string
+	name     string
+	location dto.FileLocation
+}
+
+type FileArchiver struct {
+	downloader *FileDownloader
+	catalog    *FilesCatalog
+	streamer   port.FileStreamer
+	config     port.UploaderConfig
+	logger     port.Logger
+}
+
+func ProvideFileArchiver(
+	downloader *FileDownloader,
+	catalog *FilesCatalog,
+	streamer port.FileStreamer,
+	config port.UploaderConfig,
+	logger port.Logger,
+) *FileArchiver {
+	return &FileArchiver{
+		downloader: downloader,
+		catalog:    catalog,
+		streamer:   streamer,
+		config:     config,
+		logger:     logger,
+	}
+}
+
+// GetStreamer authorizes every file as single download and returns streamer of ZIP archive.
+// Files are stored without compression and are read one by one, so archive is never buffered
+func (fa *FileArchiver) GetStreamer(ctx context.Context, headers [][2]string, body []byte) (func(writer *bufio.Writer), dto.DownloadResult, error) {
+	var request dto.ArchiveRequest
+	if err := jsoniter.Unmarshal(body, &request); err != nil {
+		return nil, dto.DownloadResult{}, exceptions.NewApiError(http.StatusBadRequest, err)
+	}
+	if len(request.Files) == 0 {
+		return nil, dto.DownloadResult{}, exceptions.NewApiError(http.StatusBadRequest, errors.New("files must not be empty"))
+	}
+	if len(request.Files) > fa.config.GetArchiveMaxFiles() {
+		return nil, dto.DownloadResult{}, exceptions.NewApiError(http.StatusBadRequest,
+			errors.New("archive can not contain more than "+strconv.Itoa(fa.config.GetArchiveMaxFiles())+" files"))
+	}
+	entries := make([]archiveEntry, 0, len(request.Files))
+	names := make(map[string]bool, len(request.Files))
+	for _, file := range request.Files {
+		if !IsCorrectUuid(file.Uuid) {
+			return nil, dto.DownloadResult{}, exceptions.NewApiError(http.StatusBadRequest, errors.New("uuid must be correct uuid"))
+		}
+		access, err := fa.downloader.authorize(headers, file.Uuid, dto.DownloadOptions{}, false)
+		if err != nil {
+			return nil, dto.DownloadResult{}, err
+		}
+		name := file.Name
+		if name == "" {
+			name = fa.catalog.FileName(file.Uuid)
+		}
+		if name == "" {
+			name = path.Base(access.record.GetLocation().GetKey())
+		}
+		entries = append(entries, archiveEntry{
+			uuid:     file.Uuid,
+			name:     uniqueEntryName(sanitizeEntryName(name, file.Uuid), names),
+			location: access.record.GetLocation(),
+		})
+	}
+	archiveName := request.Name
+	if archiveName == "" {
+		archiveName = defaultArchiveName
+	}
+	result := dto.DownloadResult{
+		StatusCode:  http.StatusOK,
+		ContentType: "application/zip",
+		Headers:     [][2]string{{contentDispositionName, "attachment; filename=\"" + sanitizeEntryName(path.Base(archiveName), defaultArchiveName) + "\""}},
+	}
+	logger := port.LoggerFromContext(ctx, fa.logger)
+	return func(writer *bufio.Writer) {
+		if err := fa.writeArchive(logger, writer, entries); err != nil {
+			logger.Error().Println(errors.Wrap(err, "FileArchiver.writeArchive()"))
+		}
+	}, result, nil
+}
+
+// writeArchive - ZIP64 records are written by archive/zip for entries larger than 4GB
+func (fa *FileArchiver) writeArchive(logger port.Logger, writer io.Writer, entries []archiveEntry) error {
+	archive := zip.NewWriter(writer)
+	for _, entry := range entries {
+		if err := fa.writeEntry(logger.With([2]string{port.LogFieldUuid, entry.uuid}), archive, entry); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func (fa *FileArchiver) writeEntry(logger port.Logger, archive *zip.Writer, entry archiveEntry) error {
+	stream, _, err := fa.streamer.GetFileStream(entry.location, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := stream.Close(); err != nil {
+			logger.Error().Println(errors.Wrap(err, "FileArchiver.writeEntry()"))
+		}
+	}()
+	header := &zip.FileHeader{Name: entry.name, Method: zip.Store}
+	header.Modified = time.Now()
+	w, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, stream)
+	return err
+}
+
+// sanitizeEntryName removes directories from name, so archive can not be unpacked outside of target directory
+func sanitizeEntryName(name string, fallback string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = strings.Trim(path.Base(path.Clean("/"+name)), "/")
+	if name == "" || name == "." || name == ".." {
+		return fallback
+	}
+	return strings.ReplaceAll(name, "\"", "")
+}
+
+// uniqueEntryName adds number to repeated name: report.pdf, report (1).pdf, report (2).pdf
+func uniqueEntryName(name string, names map[string]bool) string {
+	result := name
+	ext := path.Ext(name)
+	for i := 1; names[result]; i++ {
+		result = strings.TrimSuffix(name, ext) + " (" + strconv.Itoa(i) + ")" + ext
+	}
+	names[result] = true
+	return result
+}
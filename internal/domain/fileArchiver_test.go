@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"testing"
+)
+
+type suiteFileArchiver struct {
+	suite.Suite
+}
+
+func TestFileArchiver(t *testing.T) {
+	suite.Run(t, new(suiteFileArchiver))
+}
+
+func (s *suiteFileArchiver) TestSanitizeEntryName() {
+	s.Equal("report.pdf", sanitizeEntryName("report.pdf", "x"))
+	s.Equal("passwd", sanitizeEntryName("../../etc/passwd", "x"))
+	s.Equal("evil.exe", sanitizeEntryName("..\\..\\evil.exe", "x"))
+	s.Equal("x", sanitizeEntryName("..", "x"))
+	s.Equal("x", sanitizeEntryName("", "x"))
+	s.Equal("a.txt", sanitizeEntryName("a\".txt", "x"))
+}
+
+func (s *suiteFileArchiver) TestUniqueEntryName() {
+	names := map[string]bool{}
+	s.Equal("report.pdf", uniqueEntryName("report.pdf", names))
+	s.Equal("report (1).pdf", uniqueEntryName("report.pdf", names))
+	s.Equal("report (2).pdf", uniqueEntryName("report.pdf", names))
+	s.Equal("data", uniqueEntryName("data", names))
+	s.Equal("data (1)", uniqueEntryName("data", names))
+}
+
+func (s *suiteFileArchiver) TestWriteArchive() {
+	loggers := logsEngine.InitLoggersEmpty("test")
+	archiver := ProvideFileArchiver(nil, nil, fakeFileStreamer{}, config.Uploader{}.AfterLoad(), &loggers)
+	buf := new(bytes.Buffer)
+	err := archiver.writeArchive(&loggers, buf, []archiveEntry{
+		{name: "a.txt", location: dto.FileLocation{Key: "a"}},
+		{name: "b.txt", location: dto.FileLocation{Key: "b"}},
+	})
+	s.Require().Nil(err)
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	s.Require().Nil(err)
+	s.Require().Equal(2, len(reader.File))
+	for i, name := range []string{"a.txt", "b.txt"} {
+		s.Equal(name, reader.File[i].Name)
+		s.Equal(zip.Store, reader.File[i].Method)
+		f, err := reader.File[i].Open()
+		s.Require().Nil(err)
+		content, err := io.ReadAll(f)
+		s.Require().Nil(err)
+		s.Equal("content", string(content))
+	}
+}
+
+func (s *suiteFileArchiver) TestRequestErrors() {
+	loggers := logsEngine.InitLoggersEmpty("test")
+	archiver := ProvideFileArchiver(nil, nil, fakeFileStreamer{}, config.Uploader{ArchiveMaxFiles: 1}.AfterLoad(), &loggers)
+	for _, body := range []string{
+		"not json",
+		`{"files": []}`,
+		`{"files": [{"uuid": "a"}, {"uuid": "b"}]}`,
+		`{"files": [{"uuid": "not-uuid"}]}`,
+	} {
+		_, _, err := archiver.GetStreamer(context.Background(), nil, []byte(body))
+		s.Require().NotNil(err, body)
+	}
+}
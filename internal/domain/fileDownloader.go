@@ -3,18 +3,45 @@ package domain
 import (
 	"bufio"
 	"context"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
 	"github.com/satmaelstorm/filup/internal/domain/exceptions"
 	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/tidwall/gjson"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 )
 
+const (
+	rangeHeader            = "Range"
+	rangeUnitPrefix        = "bytes="
+	contentRangeName       = "Content-Range"
+	contentDispositionName = "Content-Disposition"
+)
+
+// response headers which can not be overridden by callbackDownload
+var protectedDownloadHeaders = map[string]bool{
+	"content-length":    true,
+	"content-range":     true,
+	"transfer-encoding": true,
+	"connection":        true,
+}
+
 type FileDownloader struct {
 	streamer port.FileStreamer
+	records  *FileRecords
 	logger   port.Logger
 	config   port.UploaderConfig
+	poster   port.Poster
 	getter   port.Getter
+	metrics  port.UploadMetrics
+	tracer   port.Tracer
+	auth     *Authenticator
+	scanCfg  port.AntivirusConfig
 	ctx      context.Context
 }
 
@@ -22,46 +49,290 @@ func ProvideFileDownloader(
 	ctxProvider port.ContextProvider,
 	config port.UploaderConfig,
 	streamer port.FileStreamer,
+	records *FileRecords,
+	poster port.Poster,
 	getter port.Getter,
+	metrics port.UploadMetrics,
+	tracer port.Tracer,
+	auth *Authenticator,
+	scanCfg port.AntivirusConfig,
 	logger port.Logger,
 ) *FileDownloader {
 	return &FileDownloader{
 		streamer: streamer,
+		records:  records,
 		logger:   logger,
 		config:   config,
+		poster:   poster,
 		getter:   getter,
+		metrics:  metrics,
+		tracer:   tracer,
+		auth:     auth,
+		scanCfg:  scanCfg,
 		ctx:      ctxProvider.Ctx(),
 	}
 }
 
-func (fd *FileDownloader) GetStreamer(headers [][2]string, fileName string) (func(writer *bufio.Writer), port.FileInfo, error) {
-	if fd.config.GetCallbackDownload() != nil {
-		httpResult, httpCode, err := fd.getter.Get(fd.ctx, *fd.config.GetCallbackDownload(), fd.config.GetHttpTimeout(), headers...)
+// downloadAccess - result of authorization of download
+type downloadAccess struct {
+	record    dto.FileRecord
+	info      port.FileInfo
+	byteRange *dto.ByteRange
+	headers   [][2]string
+}
+
+// GetStreamer - download by signed url is already authorized, so token and callbackDownload are not checked
+func (fd *FileDownloader) GetStreamer(
+	ctx context.Context,
+	headers [][2]string,
+	fileName string,
+	options dto.DownloadOptions,
+) (func(writer *bufio.Writer), dto.DownloadResult, error) {
+	access, err := fd.authorize(headers, fileName, options, true)
+	if err != nil {
+		return nil, dto.DownloadResult{}, err
+	}
+	stream, info, err := fd.streamer.GetFileStream(access.record.GetLocation(), access.byteRange)
+	if err != nil {
+		return nil, dto.DownloadResult{}, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	logger := port.LoggerFromContext(ctx, fd.logger).With([2]string{port.LogFieldUuid, fileName})
+	return fd.getStreamerFunc(logger, stream), fd.makeResult(info, access.byteRange, access.headers), nil
+}
+
+// authorize checks token or signed url and callbackDownload. Range header is considered only if withRange is true
+func (fd *FileDownloader) authorize(
+	headers [][2]string,
+	fileName string,
+	options dto.DownloadOptions,
+	withRange bool,
+) (downloadAccess, error) {
+	var claims *dto.TokenClaims
+	var err error
+	var access downloadAccess
+	if !options.IsSigned() {
+		claims, err = fd.auth.Authenticate(headers, scopeDownload)
 		if err != nil {
-			return nil, nil, exceptions.NewApiError(http.StatusBadGateway, errors.Wrap(err, "Get error"))
+			return access, err
 		}
-		if httpCode < 200 || httpCode > 299 {
-			return nil, nil, exceptions.NewApiError(httpCode, errors.New(string(httpResult)))
+		if err = fd.auth.CheckUuid(claims, fileName); err != nil {
+			return access, err
+		}
+	}
+	access.record, err = fd.records.Load(fileName)
+	if err != nil {
+		return access, err
+	}
+	if err = fd.checkScanStatus(access.record); err != nil {
+		return access, err
+	}
+	access.info, err = fd.streamer.GetFileInfo(access.record.GetLocation())
+	if err != nil {
+		return access, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	if withRange {
+		access.byteRange, err = parseRange(findHeader(headers, rangeHeader), access.info.GetSize())
+		if err != nil {
+			return access, err
+		}
+	}
+	if !options.IsSigned() && !fd.auth.SkipCallback(claims, fileName) {
+		access.headers, err = fd.callCallbackDownload(headers, access.record, access.info, access.byteRange)
+		if err != nil {
+			return access, err
+		}
+	}
+	if options.GetDisposition() != "" {
+		access.headers = append(access.headers, [2]string{contentDispositionName, options.GetDisposition()})
+	}
+	return access, nil
+}
+
+// checkScanStatus - infected file is not served even if it was only tagged, file with failed scan is served
+// only if antivirus fails open. Files, which were not scanned, have no status
+func (fd *FileDownloader) checkScanStatus(record dto.FileRecord) error {
+	switch record.ScanStatus {
+	case "", dto.ScanStatusClean:
+		return nil
+	case dto.ScanStatusError:
+		if fd.scanCfg.IsFailOpen() {
+			return nil
+		}
+	}
+	return exceptions.NewApiError(http.StatusForbidden, errors.New("scan status of file "+record.GetUUID()+" is "+record.ScanStatus))
+}
+
+func (fd *FileDownloader) makeResult(info port.FileInfo, byteRange *dto.ByteRange, extraHeaders [][2]string) dto.DownloadResult {
+	result := dto.DownloadResult{
+		StatusCode:  http.StatusOK,
+		ContentType: info.GetContentType(),
+		Headers:     [][2]string{{"Accept-Ranges", "bytes"}},
+	}
+	if byteRange != nil {
+		result.StatusCode = http.StatusPartialContent
+		result.Headers = append(result.Headers, [2]string{
+			contentRangeName,
+			"bytes " + strconv.FormatInt(byteRange.Start, 10) + "-" + strconv.FormatInt(byteRange.End, 10) +
+				"/" + strconv.FormatInt(info.GetSize(), 10),
+		})
+	}
+	result.Headers = append(result.Headers, extraHeaders...)
+	return result
+}
+
+// callCallbackDownload sends headers of request and context of file to callbackDownload:
+// in query by GET or in JSON body with tags of file by POST
+func (fd *FileDownloader) callCallbackDownload(
+	headers [][2]string,
+	record dto.FileRecord,
+	info port.FileInfo,
+	byteRange *dto.ByteRange,
+) ([][2]string, error) {
+	callbackDownload := fd.config.GetCallbackDownload()
+	if callbackDownload == nil {
+		return nil, nil
+	}
+	ctx := fd.tracer.Extract(fd.ctx, headers)
+	request := dto.DownloadCallbackRequest{
+		Uuid:         record.GetUUID(),
+		Bucket:       record.GetLocation().GetBucket(),
+		Key:          record.GetLocation().GetKey(),
+		Size:         info.GetSize(),
+		ContentType:  info.GetContentType(),
+		UserMetadata: info.GetUserMetadata(),
+		Range:        byteRange,
+	}
+	if fd.config.GetCallbackDownloadMethod() != http.MethodPost {
+		callback := downloadCallbackQuery(*callbackDownload, request)
+		httpResult, httpCode, err := getCallback(ctx, fd.getter, fd.metrics, fd.config, callbackTypeDownload, callback, headers...)
+		if err = callbackError(httpResult, httpCode, err); err != nil {
+			return nil, err
 		}
+		return extractCallbackHeaders(httpResult), nil
+	}
+	tags, err := fd.streamer.GetFileTags(record.GetLocation())
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
 	}
-	stream, info, err := fd.streamer.GetFileStream(fileName)
+	request.UserTags = tags
+	body, err := jsoniter.Marshal(request)
 	if err != nil {
-		return nil, nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	httpResult, httpCode, err := postCallback(ctx, fd.poster, fd.metrics, fd.config, callbackTypeDownload, *callbackDownload, body, headers...)
+	if err = callbackError(httpResult, httpCode, err); err != nil {
+		return nil, err
+	}
+	return extractCallbackHeaders(httpResult), nil
+}
+
+// downloadCallbackQuery adds context of file to query of GET callbackDownload, query of configured url is kept
+func downloadCallbackQuery(callback url.URL, request dto.DownloadCallbackRequest) url.URL {
+	query := callback.Query()
+	query.Set("uuid", request.Uuid)
+	if request.Bucket != "" {
+		query.Set("bucket", request.Bucket)
 	}
-	return fd.getStreamerFunc(stream), info, nil
+	query.Set("key", request.Key)
+	query.Set("size", strconv.FormatInt(request.Size, 10))
+	query.Set("content_type", request.ContentType)
+	for name, value := range request.UserMetadata {
+		query.Set("metadata."+name, value)
+	}
+	if request.Range != nil {
+		query.Set("range", strconv.FormatInt(request.Range.Start, 10)+"-"+strconv.FormatInt(request.Range.End, 10))
+	}
+	callback.RawQuery = query.Encode()
+	return callback
 }
 
-func (fd *FileDownloader) getStreamerFunc(stream io.ReadCloser) func(writer *bufio.Writer) {
+// extractCallbackHeaders reads optional {"headers": {"Name": "value"}} object from callbackDownload response
+func extractCallbackHeaders(callbackResponse []byte) [][2]string {
+	if !gjson.ValidBytes(callbackResponse) {
+		return nil
+	}
+	var result [][2]string
+	gjson.GetBytes(callbackResponse, "headers").ForEach(func(key, value gjson.Result) bool {
+		if !protectedDownloadHeaders[strings.ToLower(key.String())] {
+			result = append(result, [2]string{key.String(), value.String()})
+		}
+		return true
+	})
+	return result
+}
+
+func (fd *FileDownloader) getStreamerFunc(logger port.Logger, stream io.ReadCloser) func(writer *bufio.Writer) {
 	return func(writer *bufio.Writer) {
 		defer func(stream io.Closer) {
 			err := stream.Close()
 			if err != nil {
-				fd.logger.Error().Println(err)
+				logger.Error().Println(err)
 			}
 		}(stream)
 		_, err := writer.ReadFrom(stream)
 		if err != nil {
-			fd.logger.Error().Println(err)
+			logger.Error().Println(err)
+		}
+	}
+}
+
+func findHeader(headers [][2]string, name string) string {
+	for _, h := range headers {
+		if strings.EqualFold(h[0], name) {
+			return h[1]
+		}
+	}
+	return ""
+}
+
+func removeHeader(headers [][2]string, name string) [][2]string {
+	result := make([][2]string, 0, len(headers))
+	for _, h := range headers {
+		if !strings.EqualFold(h[0], name) {
+			result = append(result, h)
+		}
+	}
+	return result
+}
+
+// parseRange supports only single byte range, multiple ranges are ignored and full file will be sent
+func parseRange(header string, size int64) (*dto.ByteRange, error) {
+	if header == "" || !strings.HasPrefix(header, rangeUnitPrefix) {
+		return nil, nil
+	}
+	spec := strings.TrimSpace(header[len(rangeUnitPrefix):])
+	if strings.Contains(spec, ",") {
+		return nil, nil
+	}
+	notSatisfiable := exceptions.NewApiError(http.StatusRequestedRangeNotSatisfiable, errors.New("incorrect range "+header))
+	pos := strings.Index(spec, "-")
+	if pos < 0 || size < 1 {
+		return nil, notSatisfiable
+	}
+	startStr, endStr := strings.TrimSpace(spec[:pos]), strings.TrimSpace(spec[pos+1:])
+	if startStr == "" {
+		suffix, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || suffix < 1 {
+			return nil, notSatisfiable
+		}
+		if suffix > size {
+			suffix = size
+		}
+		return &dto.ByteRange{Start: size - suffix, End: size - 1}, nil
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start >= size {
+		return nil, notSatisfiable
+	}
+	end := size - 1
+	if endStr != "" {
+		end, err = strconv.ParseInt(endStr, 10, 64)
+		if err != nil || end < start {
+			return nil, notSatisfiable
+		}
+		if end > size-1 {
+			end = size - 1
 		}
 	}
+	return &dto.ByteRange{Start: start, End: end}, nil
 }
@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"context"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+type suiteFileDownloader struct {
+	suite.Suite
+}
+
+func TestFileDownloader(t *testing.T) {
+	suite.Run(t, new(suiteFileDownloader))
+}
+
+func (s *suiteFileDownloader) TestCheckScanStatus() {
+	fd := FileDownloader{scanCfg: config.AntivirusConfig{}}
+	s.Nil(fd.checkScanStatus(dto.FileRecord{}))
+	s.Nil(fd.checkScanStatus(dto.FileRecord{ScanStatus: dto.ScanStatusClean}))
+	for _, status := range []string{dto.ScanStatusError, dto.ScanStatusInfected} {
+		err := fd.checkScanStatus(dto.FileRecord{ScanStatus: status})
+		s.Require().NotNil(err)
+		apiErr, ok := err.(exceptions.ApiError)
+		s.Require().True(ok)
+		s.Equal(http.StatusForbidden, apiErr.GetCode())
+	}
+
+	fd.scanCfg = config.AntivirusConfig{FailOpen: true}
+	s.Nil(fd.checkScanStatus(dto.FileRecord{ScanStatus: dto.ScanStatusError}))
+	s.NotNil(fd.checkScanStatus(dto.FileRecord{ScanStatus: dto.ScanStatusInfected}))
+}
+
+func (s *suiteFileDownloader) TestLoadRecordOfUploadInProgress() {
+	storage := &fakeAdminMetaStorage{files: make(map[string][]byte)}
+	records := ProvideFileRecords(storage)
+	uid := ProvideUuidProvider().NewUuid()
+
+	record, err := records.Load(uid)
+	s.Require().Nil(err)
+	s.Equal(uid, record.GetLocation().GetKey())
+
+	storage.files[MetaFileName(uid)] = []byte(`{"uuid":"` + uid + `"}`)
+	_, err = records.Load(uid)
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusNotFound, apiErr.GetCode())
+
+	s.Require().Nil(records.Save(dto.FileRecord{Uuid: uid, Key: "key", ScanStatus: dto.ScanStatusClean}))
+	record, err = records.Load(uid)
+	s.Require().Nil(err)
+	s.Equal("key", record.GetLocation().GetKey())
+	s.Equal(dto.ScanStatusClean, record.ScanStatus)
+}
+
+func (s *suiteFileDownloader) TestParseRange() {
+	r, err := parseRange("", 100)
+	s.Require().Nil(err)
+	s.Nil(r)
+
+	r, err = parseRange("bytes=0-9", 100)
+	s.Require().Nil(err)
+	s.Require().NotNil(r)
+	s.Equal(int64(0), r.Start)
+	s.Equal(int64(9), r.End)
+	s.Equal(int64(10), r.GetLength())
+
+	r, err = parseRange("bytes=90-", 100)
+	s.Require().Nil(err)
+	s.Require().NotNil(r)
+	s.Equal(int64(90), r.Start)
+	s.Equal(int64(99), r.End)
+
+	r, err = parseRange("bytes=-20", 100)
+	s.Require().Nil(err)
+	s.Require().NotNil(r)
+	s.Equal(int64(80), r.Start)
+	s.Equal(int64(99), r.End)
+
+	r, err = parseRange("bytes=50-500", 100)
+	s.Require().Nil(err)
+	s.Require().NotNil(r)
+	s.Equal(int64(99), r.End)
+
+	r, err = parseRange("bytes=0-1,5-6", 100)
+	s.Require().Nil(err)
+	s.Nil(r)
+
+	_, err = parseRange("bytes=100-", 100)
+	s.Require().NotNil(err)
+	e, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusRequestedRangeNotSatisfiable, e.GetCode())
+
+	_, err = parseRange("bytes=10-5", 100)
+	s.Require().NotNil(err)
+}
+
+func (s *suiteFileDownloader) TestExtractCallbackHeaders() {
+	h := extractCallbackHeaders([]byte(`{"headers":{"Content-Disposition":"attachment","Content-Length":"1"}}`))
+	s.Require().Equal(1, len(h))
+	s.Equal("Content-Disposition", h[0][0])
+	s.Equal("attachment", h[0][1])
+
+	s.Nil(extractCallbackHeaders([]byte("OK")))
+	s.Nil(extractCallbackHeaders(nil))
+}
+
+func (s *suiteFileDownloader) TestFindHeader() {
+	headers := [][2]string{{"Content-Type", "text/plain"}, {"range", "bytes=0-1"}}
+	s.Equal("bytes=0-1", findHeader(headers, rangeHeader))
+	s.Equal("", findHeader(headers, "Authorization"))
+}
+
+type fakeDownloadInfo struct{}
+
+func (fakeDownloadInfo) GetSize() int64 {
+	return 100
+}
+
+func (fakeDownloadInfo) GetContentType() string {
+	return "image/png"
+}
+
+func (fakeDownloadInfo) GetUserMetadata() map[string]string {
+	return map[string]string{"Project": "filup"}
+}
+
+func (fakeDownloadInfo) GetETag() string {
+	return ""
+}
+
+func (s *suiteFileDownloader) TestGetCallbackDownload() {
+	getter := new(recordingPoster)
+	fd := FileDownloader{
+		config:  config.Uploader{CallbackDownload: "http://localhost/download?app=1"}.AfterLoad(),
+		getter:  getter,
+		metrics: new(fakeUploadMetrics),
+		tracer:  fakeTracer{},
+		ctx:     context.Background(),
+	}
+	uid := ProvideUuidProvider().NewUuid()
+	record := dto.FileRecord{Uuid: uid, Bucket: "files", Key: "2024/" + uid}
+	_, err := fd.callCallbackDownload([][2]string{{"Authorization", "Bearer token"}}, record, fakeDownloadInfo{},
+		&dto.ByteRange{Start: 0, End: 9})
+	s.Require().Nil(err)
+
+	s.Equal("/download", getter.url.Path)
+	s.Equal(url.Values{
+		"app":              {"1"},
+		"uuid":             {uid},
+		"bucket":           {"files"},
+		"key":              {"2024/" + uid},
+		"size":             {"100"},
+		"content_type":     {"image/png"},
+		"metadata.Project": {"filup"},
+		"range":            {"0-9"},
+	}, getter.url.Query())
+	s.Equal("Bearer token", getter.header("Authorization"))
+}
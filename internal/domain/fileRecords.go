@@ -0,0 +1,77 @@
+package domain
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"net/http"
+)
+
+// FileRecords keeps location of composed files in meta storage, so download can resolve uuid to bucket and key
+type FileRecords struct {
+	storage port.StorageMeta
+}
+
+func ProvideFileRecords(storage port.StorageMeta) *FileRecords {
+	return &FileRecords{storage: storage}
+}
+
+func (fr *FileRecords) Save(record dto.FileRecord) error {
+	content, err := jsoniter.Marshal(record)
+	if err != nil {
+		return errors.Wrap(err, "FileRecords.Save")
+	}
+	return errors.Wrap(fr.storage.PutMetaFile(FileRecordName(record.GetUUID()), content), "FileRecords.Save")
+}
+
+// SaveCallbackAfter keeps body of callbackAfter, so it can be resent by admin api
+func (fr *FileRecords) SaveCallbackAfter(uuid string, body []byte) error {
+	return errors.Wrap(fr.storage.PutMetaFile(CallbackAfterName(uuid), body), "FileRecords.SaveCallbackAfter")
+}
+
+func (fr *FileRecords) LoadCallbackAfter(uuid string) ([]byte, error) {
+	if !IsCorrectUuid(uuid) {
+		return nil, exceptions.NewApiError(http.StatusBadRequest, errors.New("incorrect uuid"))
+	}
+	content, err := fr.storage.GetMetaFile(CallbackAfterName(uuid))
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, errors.Wrap(err, "error in meta storage"))
+	}
+	if len(content) < 1 {
+		return nil, exceptions.NewApiError(http.StatusNotFound, errors.New("callbackAfter of "+uuid+" not found"))
+	}
+	return content, nil
+}
+
+// Load returns record of file. Files uploaded before records were introduced are stored by uuid in default bucket.
+// Record is saved after file is scanned and processed, so file of upload, which is in progress, is not found
+func (fr *FileRecords) Load(uuid string) (dto.FileRecord, error) {
+	if !IsCorrectUuid(uuid) {
+		return dto.FileRecord{}, exceptions.NewApiError(http.StatusBadRequest, errors.New("incorrect uuid"))
+	}
+	content, err := fr.storage.GetMetaFile(FileRecordName(uuid))
+	if err != nil {
+		return dto.FileRecord{}, exceptions.NewApiError(http.StatusInternalServerError, errors.Wrap(err, "error in meta storage"))
+	}
+	if len(content) < 1 {
+		return fr.legacyRecord(uuid)
+	}
+	var record dto.FileRecord
+	if err = jsoniter.Unmarshal(content, &record); err != nil {
+		return dto.FileRecord{}, exceptions.NewApiError(http.StatusInternalServerError, errors.Wrap(err, "error while deserialize file record"))
+	}
+	return record, nil
+}
+
+func (fr *FileRecords) legacyRecord(uuid string) (dto.FileRecord, error) {
+	meta, err := fr.storage.GetMetaFile(MetaFileName(uuid))
+	if err != nil {
+		return dto.FileRecord{}, exceptions.NewApiError(http.StatusInternalServerError, errors.Wrap(err, "error in meta storage"))
+	}
+	if len(meta) > 0 {
+		return dto.FileRecord{}, exceptions.NewApiError(http.StatusNotFound, errors.New("upload of "+uuid+" is not completed"))
+	}
+	return dto.FileRecord{Uuid: uuid, Key: uuid}, nil
+}
@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"context"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"net/http"
+)
+
+type FileRemover struct {
+	config   port.UploaderConfig
+	images   port.ImagesConfig
+	files    port.StorageFiles
+	cleaner  port.StorageCleaner
+	records  *FileRecords
+	catalog  *FilesCatalog
+	poster   port.Poster
+	metrics  port.UploadMetrics
+	tracer   port.Tracer
+	logger   port.Logger
+	failures *FailedCallbacks
+	ctx      context.Context
+}
+
+func ProvideFileRemover(
+	ctxProvider port.ContextProvider,
+	config port.UploaderConfig,
+	images port.ImagesConfig,
+	files port.StorageFiles,
+	cleaner port.StorageCleaner,
+	records *FileRecords,
+	catalog *FilesCatalog,
+	poster port.Poster,
+	metrics port.UploadMetrics,
+	tracer port.Tracer,
+	logger port.Logger,
+	failures *FailedCallbacks,
+) *FileRemover {
+	return &FileRemover{
+		config:   config,
+		images:   images,
+		files:    files,
+		cleaner:  cleaner,
+		records:  records,
+		catalog:  catalog,
+		poster:   poster,
+		metrics:  metrics,
+		tracer:   tracer,
+		logger:   logger,
+		failures: failures,
+		ctx:      ctxProvider.Ctx(),
+	}
+}
+
+// Delete removes composed file. Deletion is allowed only through callbackDelete authorization.
+func (fr *FileRemover) Delete(ctx context.Context, headers [][2]string, uuid string) error {
+	if fr.config.GetCallbackDelete() == nil {
+		return exceptions.NewApiError(http.StatusForbidden, errors.New("deletion is disabled"))
+	}
+	record, err := fr.records.Load(uuid)
+	if err != nil {
+		return err
+	}
+	exists, err := fr.files.IsFileExists(record.GetLocation())
+	if err != nil {
+		return exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	if !exists {
+		return exceptions.NewApiError(http.StatusNotFound, errors.New("file "+uuid+" not found"))
+	}
+	body, err := jsoniter.Marshal(record)
+	if err != nil {
+		return exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	if err = fr.postCallbackDelete(headers, body); err != nil {
+		return err
+	}
+	if err = fr.files.RemoveFile(record.GetLocation()); err != nil {
+		return exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	logger := port.LoggerFromContext(ctx, fr.logger).With([2]string{port.LogFieldUuid, uuid})
+	fr.cleanup(logger, record)
+	logger.Trace().Println("FileRemover.Delete(): file is removed")
+	if callbackDeleted := fr.config.GetCallbackDeleted(); callbackDeleted != nil {
+		go postWithRetries(fr.ctx, fr.poster, fr.metrics, fr.config, logger, fr.failures, callbackTypeDeleted, "CallbackDeleted", callbackDeleted, body)
+	}
+	return nil
+}
+
+func (fr *FileRemover) cleanup(logger port.Logger, record dto.FileRecord) {
+	for _, derived := range record.GetDerived() {
+		if err := fr.files.RemoveFile(derived); err != nil {
+			logger.Error().Println(errors.Wrap(err, "FileRemover.cleanup()"))
+		}
+	}
+	if bucket := fr.images.GetBucket(); bucket != "" {
+		if err := fr.files.RemoveFiles(bucket, ImageVariantsPrefix(record.GetUUID())); err != nil {
+			logger.Error().Println(errors.Wrap(err, "FileRemover.cleanup()"))
+		}
+	}
+	if err := fr.cleaner.RemoveMeta(FileRecordName(record.GetUUID())); err != nil {
+		logger.Error().Println(errors.Wrap(err, "FileRemover.cleanup()"))
+	}
+	if err := fr.cleaner.RemoveMeta(CallbackAfterName(record.GetUUID())); err != nil {
+		logger.Error().Println(errors.Wrap(err, "FileRemover.cleanup()"))
+	}
+	fr.catalog.Unregister(record.GetUUID())
+}
+
+func (fr *FileRemover) postCallbackDelete(headers [][2]string, body []byte) error {
+	callbackDelete := fr.config.GetCallbackDelete()
+	httpResult, httpCode, err := postCallback(fr.tracer.Extract(fr.ctx, headers), fr.poster, fr.metrics, fr.config, callbackTypeDelete, *callbackDelete, body, headers...)
+	return callbackError(httpResult, httpCode, err)
+}
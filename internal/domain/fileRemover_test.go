@@ -0,0 +1,159 @@
+package domain
+
+import (
+	"context"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+type fakeStorageFiles struct {
+	exists   bool
+	removed  []dto.FileLocation
+	tagged   map[string]string
+	moved    []dto.FileLocation
+	put      []dto.FileLocation
+	contents []string
+	prefix   []string
+	failErr  error
+}
+
+func (f *fakeStorageFiles) IsFileExists(location dto.FileLocation) (bool, error) {
+	return f.exists, nil
+}
+
+func (f *fakeStorageFiles) RemoveFile(location dto.FileLocation) error {
+	f.removed = append(f.removed, location)
+	return nil
+}
+
+func (f *fakeStorageFiles) PutFile(location dto.FileLocation, contentType string, size int64, content io.Reader) error {
+	f.put = append(f.put, location)
+	if content != nil {
+		data, _ := ioutil.ReadAll(content)
+		f.contents = append(f.contents, string(data))
+	}
+	return f.failErr
+}
+
+func (f *fakeStorageFiles) RemoveFiles(bucket string, prefix string) error {
+	f.prefix = append(f.prefix, bucket+"/"+prefix)
+	return nil
+}
+
+func (f *fakeStorageFiles) TagFile(location dto.FileLocation, tags map[string]string) error {
+	f.tagged = tags
+	return f.failErr
+}
+
+func (f *fakeStorageFiles) MoveFile(src dto.FileLocation, dest dto.FileLocation) error {
+	if f.failErr != nil {
+		return f.failErr
+	}
+	f.moved = append(f.moved, dest)
+	return nil
+}
+
+type fakeStorageCleaner struct {
+	removedMeta []string
+}
+
+func (f *fakeStorageCleaner) RemoveMeta(fileName string) error {
+	f.removedMeta = append(f.removedMeta, fileName)
+	return nil
+}
+
+func (f *fakeStorageCleaner) RemoveParts(partsNames []string) error {
+	return nil
+}
+
+type fakeContextProvider struct {
+}
+
+func (f fakeContextProvider) Ctx() context.Context {
+	return context.Background()
+}
+
+type suiteFileRemover struct {
+	suite.Suite
+	files   *fakeStorageFiles
+	cleaner *fakeStorageCleaner
+	catalog *fakeFileCatalog
+}
+
+func TestFileRemover(t *testing.T) {
+	suite.Run(t, new(suiteFileRemover))
+}
+
+func (s *suiteFileRemover) SetupTest() {
+	s.files = &fakeStorageFiles{exists: true}
+	s.cleaner = &fakeStorageCleaner{}
+	s.catalog = &fakeFileCatalog{enabled: true, entries: make(map[string]dto.CatalogEntry)}
+}
+
+func (s *suiteFileRemover) makeRemover(cfg config.Uploader, poster fakePoster) *FileRemover {
+	loggers := logsEngine.InitLoggersEmpty("test")
+	return ProvideFileRemover(
+		fakeContextProvider{},
+		cfg.AfterLoad(),
+		config.ImagesConfig{Bucket: "filup-images"}.AfterLoad(),
+		s.files,
+		s.cleaner,
+		ProvideFileRecords(&fakeMetaStorage{}),
+		ProvideFilesCatalog(s.catalog, config.AdminConfig{Token: "admin"}, &loggers),
+		poster,
+		new(fakeUploadMetrics),
+		fakeTracer{},
+		&loggers,
+		nil,
+	)
+}
+
+func (s *suiteFileRemover) TestDeleteDisabled() {
+	fr := s.makeRemover(config.Uploader{}, fakePoster{retCode: http.StatusOK})
+	err := fr.Delete(context.Background(), nil, ProvideUuidProvider().NewUuid())
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusForbidden, apiErr.GetCode())
+	s.Equal(0, len(s.files.removed))
+}
+
+func (s *suiteFileRemover) TestDeleteDenied() {
+	fr := s.makeRemover(config.Uploader{CallbackDelete: "http://localhost"}, fakePoster{retCode: http.StatusForbidden})
+	err := fr.Delete(context.Background(), nil, ProvideUuidProvider().NewUuid())
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusForbidden, apiErr.GetCode())
+	s.Equal(0, len(s.files.removed))
+}
+
+func (s *suiteFileRemover) TestDeleteNotFound() {
+	s.files.exists = false
+	fr := s.makeRemover(config.Uploader{CallbackDelete: "http://localhost"}, fakePoster{retCode: http.StatusOK})
+	err := fr.Delete(context.Background(), nil, ProvideUuidProvider().NewUuid())
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusNotFound, apiErr.GetCode())
+}
+
+func (s *suiteFileRemover) TestDelete() {
+	uid := ProvideUuidProvider().NewUuid()
+	s.catalog.entries[uid] = dto.CatalogEntry{Uuid: uid}
+	fr := s.makeRemover(config.Uploader{CallbackDelete: "http://localhost"}, fakePoster{retCode: http.StatusOK})
+	err := fr.Delete(context.Background(), nil, uid)
+	s.Require().Nil(err)
+	s.Require().Equal(1, len(s.files.removed))
+	s.Equal(uid, s.files.removed[0].GetKey())
+	s.Equal([]string{FileRecordName(uid), CallbackAfterName(uid)}, s.cleaner.removedMeta)
+	s.Equal([]string{"filup-images/" + ImageVariantsPrefix(uid)}, s.files.prefix)
+	s.Equal(0, len(s.catalog.entries))
+}
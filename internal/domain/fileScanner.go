@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+)
+
+const scanTagName = "filup-scan"
+
+type FileScanner struct {
+	scanner  port.VirusScanner
+	cfg      port.AntivirusConfig
+	streamer port.FileStreamer
+	files    port.StorageFiles
+	logger   port.Logger
+}
+
+func ProvideFileScanner(
+	scanner port.VirusScanner,
+	cfg port.AntivirusConfig,
+	streamer port.FileStreamer,
+	files port.StorageFiles,
+	logger port.Logger,
+) *FileScanner {
+	return &FileScanner{
+		scanner:  scanner,
+		cfg:      cfg,
+		streamer: streamer,
+		files:    files,
+		logger:   logger,
+	}
+}
+
+// Scan checks composed file and applies configured action to infected file and, unless antivirus fails open,
+// to file, which was not scanned because of error. Nil verdict is returned if scanning is disabled
+func (fs *FileScanner) Scan(location dto.FileLocation) *dto.ScanVerdict {
+	if !fs.scanner.IsEnabled() {
+		return nil
+	}
+	verdict, err := fs.scan(location)
+	if err != nil {
+		fs.logger.Critical().Println(errors.Wrap(err, "FileScanner.Scan()"))
+		verdict = dto.ScanVerdict{Status: dto.ScanStatusError}
+		if !fs.cfg.IsFailOpen() {
+			verdict.Action = fs.applyAction(location, verdict)
+		}
+		return &verdict
+	}
+	if verdict.IsInfected() {
+		verdict.Action = fs.applyAction(location, verdict)
+	}
+	return &verdict
+}
+
+func (fs *FileScanner) scan(location dto.FileLocation) (dto.ScanVerdict, error) {
+	stream, _, err := fs.streamer.GetFileStream(location, nil)
+	if err != nil {
+		return dto.ScanVerdict{}, err
+	}
+	defer func() {
+		if err := stream.Close(); err != nil {
+			fs.logger.Error().Println(errors.Wrap(err, "FileScanner.scan()"))
+		}
+	}()
+	return fs.scanner.Scan(stream)
+}
+
+// applyAction returns applied action. Infected file is deleted if configured action fails
+func (fs *FileScanner) applyAction(location dto.FileLocation, verdict dto.ScanVerdict) string {
+	var err error
+	action := fs.cfg.GetAction()
+	switch action {
+	case dto.ScanActionTag:
+		err = fs.files.TagFile(location, map[string]string{scanTagName: verdict.Status})
+	case dto.ScanActionQuarantine:
+		err = fs.files.MoveFile(location, dto.FileLocation{
+			Bucket:     fs.cfg.GetQuarantineBucket(),
+			Key:        location.GetKey(),
+			Encryption: location.Encryption,
+		})
+	default:
+		action = dto.ScanActionDelete
+		err = fs.files.RemoveFile(location)
+	}
+	if err == nil {
+		return action
+	}
+	fs.logger.Critical().Println(errors.Wrap(err, "FileScanner.applyAction()"))
+	if action == dto.ScanActionDelete {
+		return ""
+	}
+	if err = fs.files.RemoveFile(location); err != nil {
+		fs.logger.Critical().Println(errors.Wrap(err, "FileScanner.applyAction()"))
+		return ""
+	}
+	return dto.ScanActionDelete
+}
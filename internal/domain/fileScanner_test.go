@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+type fakeVirusScanner struct {
+	enabled bool
+	verdict dto.ScanVerdict
+	err     error
+}
+
+func (f fakeVirusScanner) IsEnabled() bool {
+	return f.enabled
+}
+
+func (f fakeVirusScanner) Scan(stream io.Reader) (dto.ScanVerdict, error) {
+	return f.verdict, f.err
+}
+
+type fakeFileStreamer struct {
+}
+
+func (f fakeFileStreamer) GetFileInfo(location dto.FileLocation) (port.FileInfo, error) {
+	return nil, nil
+}
+
+func (f fakeFileStreamer) GetFileTags(location dto.FileLocation) (map[string]string, error) {
+	return nil, nil
+}
+
+func (f fakeFileStreamer) GetFileStream(location dto.FileLocation, byteRange *dto.ByteRange) (io.ReadCloser, port.FileInfo, error) {
+	return ioutil.NopCloser(strings.NewReader("content")), nil, nil
+}
+
+type suiteFileScanner struct {
+	suite.Suite
+	files    *fakeStorageFiles
+	location dto.FileLocation
+}
+
+func TestFileScanner(t *testing.T) {
+	suite.Run(t, new(suiteFileScanner))
+}
+
+func (s *suiteFileScanner) SetupTest() {
+	s.files = &fakeStorageFiles{}
+	s.location = dto.FileLocation{Key: ProvideUuidProvider().NewUuid()}
+}
+
+func (s *suiteFileScanner) makeScanner(scanner fakeVirusScanner, action string) *FileScanner {
+	return s.makeScannerWithConfig(scanner, config.AntivirusConfig{Address: "tcp://localhost:3310", Action: action})
+}
+
+func (s *suiteFileScanner) makeScannerWithConfig(scanner fakeVirusScanner, cfg config.AntivirusConfig) *FileScanner {
+	loggers := logsEngine.InitLoggersEmpty("test")
+	return ProvideFileScanner(
+		scanner,
+		cfg.AfterLoad(),
+		fakeFileStreamer{},
+		s.files,
+		&loggers,
+	)
+}
+
+func (s *suiteFileScanner) TestDisabled() {
+	s.Nil(s.makeScanner(fakeVirusScanner{}, "").Scan(s.location))
+}
+
+func (s *suiteFileScanner) TestClean() {
+	verdict := s.makeScanner(fakeVirusScanner{enabled: true, verdict: dto.ScanVerdict{Status: dto.ScanStatusClean}}, "").Scan(s.location)
+	s.Require().NotNil(verdict)
+	s.Equal(dto.ScanStatusClean, verdict.Status)
+	s.False(isFileRemoved(verdict))
+	s.Equal(0, len(s.files.moved))
+}
+
+func (s *suiteFileScanner) TestError() {
+	failed := fakeVirusScanner{enabled: true, err: errors.New("connection refused")}
+	verdict := s.makeScanner(failed, "").Scan(s.location)
+	s.Require().NotNil(verdict)
+	s.Equal(dto.ScanStatusError, verdict.Status)
+	s.Equal(dto.ScanActionQuarantine, verdict.Action)
+	s.True(isFileRemoved(verdict))
+	s.Equal(1, len(s.files.moved))
+
+	verdict = s.makeScanner(failed, dto.ScanActionTag).Scan(s.location)
+	s.Equal(dto.ScanStatusError, s.files.tagged[scanTagName])
+	s.False(isFileRemoved(verdict))
+
+	verdict = s.makeScannerWithConfig(failed, config.AntivirusConfig{Address: "tcp://localhost:3310", FailOpen: true}).Scan(s.location)
+	s.Require().NotNil(verdict)
+	s.Equal(dto.ScanStatusError, verdict.Status)
+	s.Empty(verdict.Action)
+	s.False(isFileRemoved(verdict))
+	s.Equal(1, len(s.files.moved))
+}
+
+func (s *suiteFileScanner) TestInfectedActions() {
+	infected := fakeVirusScanner{enabled: true, verdict: dto.ScanVerdict{Status: dto.ScanStatusInfected, Signature: "Eicar"}}
+
+	verdict := s.makeScanner(infected, dto.ScanActionQuarantine).Scan(s.location)
+	s.Require().NotNil(verdict)
+	s.Equal(dto.ScanActionQuarantine, verdict.Action)
+	s.Equal("Eicar", verdict.GetSignature())
+	s.True(isFileRemoved(verdict))
+	s.Require().Equal(1, len(s.files.moved))
+	s.Equal("filup-quarantine", s.files.moved[0].GetBucket())
+
+	verdict = s.makeScanner(infected, dto.ScanActionTag).Scan(s.location)
+	s.Equal(dto.ScanActionTag, verdict.Action)
+	s.Equal(dto.ScanStatusInfected, s.files.tagged[scanTagName])
+	s.False(isFileRemoved(verdict))
+
+	verdict = s.makeScanner(infected, dto.ScanActionDelete).Scan(s.location)
+	s.Equal(dto.ScanActionDelete, verdict.Action)
+	s.Equal(1, len(s.files.removed))
+
+	s.files.failErr = errors.New("access denied")
+	verdict = s.makeScanner(infected, dto.ScanActionQuarantine).Scan(s.location)
+	s.Equal(dto.ScanActionDelete, verdict.Action)
+	s.Equal(2, len(s.files.removed))
+}
@@ -0,0 +1,181 @@
+package domain
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	catalogDefaultLimit = 50
+	catalogMaxLimit     = 1000
+	catalogMaxScan      = 10 * catalogMaxLimit
+	catalogTagArgPrefix = "tag."
+)
+
+type FilesCatalog struct {
+	catalog  port.FileCatalog
+	adminCfg port.AdminConfig
+	logger   port.Logger
+}
+
+func ProvideFilesCatalog(catalog port.FileCatalog, adminCfg port.AdminConfig, logger port.Logger) *FilesCatalog {
+	return &FilesCatalog{catalog: catalog, adminCfg: adminCfg, logger: logger}
+}
+
+func (fc *FilesCatalog) Register(metaInfo dto.UploaderStartResult, completedAt time.Time) {
+	if !fc.catalog.IsEnabled() {
+		return
+	}
+	location := metaInfo.GetLocation()
+	err := fc.catalog.Put(dto.CatalogEntry{
+		Uuid:        metaInfo.GetUUID(),
+		Bucket:      location.GetBucket(),
+		Key:         location.GetKey(),
+		Size:        metaInfo.GetSize(),
+		ContentType: metaInfo.GetFileContentType(),
+		UserTags:    metaInfo.GetUserTags(),
+		FileName:    metaInfo.FileName,
+		Tenant:      metaInfo.Tenant,
+		Owner:       metaInfo.Owner,
+		CreatedAt:   metaInfo.CreatedAt,
+		CompletedAt: completedAt.Unix(),
+	})
+	if err != nil {
+		fc.logger.Error().Println(errors.Wrap(err, "FilesCatalog.Register()"))
+	}
+}
+
+func (fc *FilesCatalog) Unregister(uuid string) {
+	if !fc.catalog.IsEnabled() {
+		return
+	}
+	if err := fc.catalog.Delete(uuid); err != nil {
+		fc.logger.Error().Println(errors.Wrap(err, "FilesCatalog.Unregister()"))
+	}
+}
+
+// List - catalog exposes owners, names and keys of all files, so it is authorized by admin token
+func (fc *FilesCatalog) List(headers [][2]string, args [][2]string) ([]byte, error) {
+	if err := CheckAdminToken(fc.adminCfg, headers); err != nil {
+		return nil, err
+	}
+	if err := fc.checkEnabled(); err != nil {
+		return nil, err
+	}
+	filter, err := parseCatalogFilter(args)
+	if err != nil {
+		return nil, err
+	}
+	page, err := fc.catalog.List(filter)
+	if errors.Is(err, port.ErrInvalidCatalogCursor) {
+		return nil, exceptions.NewApiError(http.StatusBadRequest, err)
+	}
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, errors.Wrap(err, "error in catalog"))
+	}
+	if page.Items == nil {
+		page.Items = []dto.CatalogEntry{}
+	}
+	return fc.render(page)
+}
+
+func (fc *FilesCatalog) Get(headers [][2]string, uuid string) ([]byte, error) {
+	if err := CheckAdminToken(fc.adminCfg, headers); err != nil {
+		return nil, err
+	}
+	if err := fc.checkEnabled(); err != nil {
+		return nil, err
+	}
+	entry, found, err := fc.catalog.Get(uuid)
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, errors.Wrap(err, "error in catalog"))
+	}
+	if !found {
+		return nil, exceptions.NewApiError(http.StatusNotFound, errors.New("file "+uuid+" not found"))
+	}
+	return fc.render(entry)
+}
+
+// FileName returns original name of file or empty string if it is unknown
+func (fc *FilesCatalog) FileName(uuid string) string {
+	if !fc.catalog.IsEnabled() {
+		return ""
+	}
+	entry, found, err := fc.catalog.Get(uuid)
+	if err != nil {
+		fc.logger.Error().Println(errors.Wrap(err, "FilesCatalog.FileName()"))
+		return ""
+	}
+	if !found {
+		return ""
+	}
+	return entry.FileName
+}
+
+func (fc *FilesCatalog) checkEnabled() error {
+	if !fc.catalog.IsEnabled() {
+		return exceptions.NewApiError(http.StatusNotFound, errors.New("files catalog is disabled"))
+	}
+	return nil
+}
+
+func (fc *FilesCatalog) render(v interface{}) ([]byte, error) {
+	content, err := jsoniter.Marshal(v)
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	return content, nil
+}
+
+func parseCatalogFilter(args [][2]string) (dto.CatalogFilter, error) {
+	filter := dto.NewCatalogFilter()
+	filter.Limit = catalogDefaultLimit
+	filter.MaxScan = catalogMaxScan
+	var err error
+	for _, arg := range args {
+		switch name, value := arg[0], arg[1]; {
+		case name == "owner":
+			filter.Owner = value
+		case name == "tenant":
+			filter.Tenant = value
+		case name == "content_type":
+			filter.ContentType = value
+		case name == "file_name":
+			filter.FileName = value
+		case name == "created_from":
+			filter.CreatedFrom, err = parseCatalogInt(name, value)
+		case name == "created_to":
+			filter.CreatedTo, err = parseCatalogInt(name, value)
+		case name == "cursor":
+			filter.Cursor = value
+		case name == "limit":
+			var limit int64
+			limit, err = parseCatalogInt(name, value)
+			filter.Limit = int(limit)
+		case strings.HasPrefix(name, catalogTagArgPrefix) && len(name) > len(catalogTagArgPrefix):
+			filter.UserTags[name[len(catalogTagArgPrefix):]] = value
+		}
+		if err != nil {
+			return filter, err
+		}
+	}
+	if filter.Limit < 1 || filter.Limit > catalogMaxLimit {
+		return filter, exceptions.NewApiError(http.StatusBadRequest, errors.New("limit must be from 1 to "+strconv.Itoa(catalogMaxLimit)))
+	}
+	return filter, nil
+}
+
+func parseCatalogInt(name, value string) (int64, error) {
+	result, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || result < 0 {
+		return 0, exceptions.NewApiError(http.StatusBadRequest, errors.New(name+" must be non-negative integer"))
+	}
+	return result, nil
+}
@@ -0,0 +1,177 @@
+package domain
+
+import (
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/stretchr/testify/suite"
+	"github.com/tidwall/gjson"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type fakeFileCatalog struct {
+	enabled bool
+	entries map[string]dto.CatalogEntry
+}
+
+func (f *fakeFileCatalog) IsEnabled() bool {
+	return f.enabled
+}
+
+func (f *fakeFileCatalog) Put(entry dto.CatalogEntry) error {
+	f.entries[entry.GetUUID()] = entry
+	return nil
+}
+
+func (f *fakeFileCatalog) Get(uuid string) (dto.CatalogEntry, bool, error) {
+	e, ok := f.entries[uuid]
+	return e, ok, nil
+}
+
+func (f *fakeFileCatalog) Delete(uuid string) error {
+	delete(f.entries, uuid)
+	return nil
+}
+
+func (f *fakeFileCatalog) List(filter dto.CatalogFilter) (dto.CatalogPage, error) {
+	if filter.Cursor == "bad" {
+		return dto.CatalogPage{}, port.ErrInvalidCatalogCursor
+	}
+	page := dto.CatalogPage{Limit: filter.Limit}
+	for _, e := range f.entries {
+		if filter.Matches(e) {
+			page.Items = append(page.Items, e)
+		}
+	}
+	return page, nil
+}
+
+type suiteFilesCatalog struct {
+	suite.Suite
+	catalog *fakeFileCatalog
+	fc      *FilesCatalog
+	admin   [][2]string
+}
+
+func TestFilesCatalog(t *testing.T) {
+	suite.Run(t, new(suiteFilesCatalog))
+}
+
+func (s *suiteFilesCatalog) SetupTest() {
+	s.catalog = &fakeFileCatalog{enabled: true, entries: make(map[string]dto.CatalogEntry)}
+	loggers := logsEngine.InitLoggersEmpty("test")
+	s.fc = ProvideFilesCatalog(s.catalog, config.AdminConfig{Token: "admin"}, &loggers)
+	s.admin = [][2]string{{"X-Admin-Token", "admin"}}
+}
+
+func (s *suiteFilesCatalog) TestParseCatalogFilter() {
+	f, err := parseCatalogFilter([][2]string{
+		{"owner", "user1"},
+		{"tag.project", "alpha"},
+		{"created_from", "100"},
+		{"limit", "10"},
+		{"cursor", "00ff"},
+		{"unknown", "value"},
+	})
+	s.Require().Nil(err)
+	s.Equal("user1", f.Owner)
+	s.Equal("alpha", f.UserTags["project"])
+	s.Equal(int64(100), f.CreatedFrom)
+	s.Equal(10, f.Limit)
+	s.Equal("00ff", f.Cursor)
+	s.Equal(catalogMaxScan, f.MaxScan)
+
+	f, err = parseCatalogFilter(nil)
+	s.Require().Nil(err)
+	s.Equal(catalogDefaultLimit, f.Limit)
+
+	_, err = parseCatalogFilter([][2]string{{"limit", "0"}})
+	s.Require().NotNil(err)
+	_, err = parseCatalogFilter([][2]string{{"limit", "-1"}})
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusBadRequest, apiErr.GetCode())
+}
+
+func (s *suiteFilesCatalog) TestFilterMatches() {
+	e := dto.CatalogEntry{
+		Owner:       "user1",
+		ContentType: "image/png",
+		FileName:    "Holiday.PNG",
+		UserTags:    map[string]string{"project": "alpha"},
+		CreatedAt:   150,
+	}
+	f := dto.NewCatalogFilter()
+	s.True(f.Matches(e))
+	f.FileName = "holiday"
+	f.ContentType = "image/"
+	f.UserTags["project"] = "alpha"
+	f.CreatedFrom = 100
+	s.True(f.Matches(e))
+	f.CreatedTo = 120
+	s.False(f.Matches(e))
+	f.CreatedTo = 0
+	f.UserTags["project"] = "beta"
+	s.False(f.Matches(e))
+}
+
+func (s *suiteFilesCatalog) TestRegisterAndGet() {
+	uid := ProvideUuidProvider().NewUuid()
+	s.fc.Register(dto.UploaderStartResult{
+		Uuid:     uid,
+		Size:     10,
+		FileName: "report.pdf",
+		Owner:    "user1",
+	}, time.Unix(200, 0))
+
+	content, err := s.fc.Get(s.admin, uid)
+	s.Require().Nil(err)
+	s.Equal("report.pdf", gjson.GetBytes(content, "file_name").String())
+	s.Equal(uid, gjson.GetBytes(content, "key").String())
+	s.Equal(int64(200), gjson.GetBytes(content, "completed_at").Int())
+
+	content, err = s.fc.List(s.admin, [][2]string{{"owner", "user1"}})
+	s.Require().Nil(err)
+	s.Equal(int64(1), gjson.GetBytes(content, "items.#").Int())
+	s.False(gjson.GetBytes(content, "next_cursor").Exists())
+
+	content, err = s.fc.List(s.admin, [][2]string{{"owner", "user2"}})
+	s.Require().Nil(err)
+	s.True(gjson.GetBytes(content, "items").IsArray())
+	s.Equal(int64(0), gjson.GetBytes(content, "items.#").Int())
+
+	_, err = s.fc.List(s.admin, [][2]string{{"cursor", "bad"}})
+	s.assertCode(err, http.StatusBadRequest)
+
+	_, err = s.fc.Get(s.admin, ProvideUuidProvider().NewUuid())
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusNotFound, apiErr.GetCode())
+}
+
+func (s *suiteFilesCatalog) TestDisabled() {
+	s.catalog.enabled = false
+	_, err := s.fc.List(s.admin, nil)
+	s.Require().NotNil(err)
+	s.fc.Register(dto.UploaderStartResult{Uuid: ProvideUuidProvider().NewUuid()}, time.Now())
+	s.Equal(0, len(s.catalog.entries))
+}
+
+func (s *suiteFilesCatalog) TestAdminToken() {
+	_, err := s.fc.List(nil, nil)
+	s.assertCode(err, http.StatusUnauthorized)
+	_, err = s.fc.Get([][2]string{{"X-Admin-Token", "wrong"}}, ProvideUuidProvider().NewUuid())
+	s.assertCode(err, http.StatusUnauthorized)
+}
+
+func (s *suiteFilesCatalog) assertCode(err error, code int) {
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(code, apiErr.GetCode())
+}
@@ -0,0 +1,198 @@
+package domain
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"golang.org/x/sync/singleflight"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	imageWidthArg  = "w"
+	imageHeightArg = "h"
+	imageFitArg    = "fit"
+	imageFormatArg = "format"
+)
+
+var resizableImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+type ImageResizer struct {
+	downloader  *FileDownloader
+	streamer    port.FileStreamer
+	files       port.StorageFiles
+	transformer port.ImageTransformer
+	cfg         port.ImagesConfig
+	logger      port.Logger
+	// flights - concurrent requests of the same variant share one resize
+	flights singleflight.Group
+}
+
+func ProvideImageResizer(
+	downloader *FileDownloader,
+	streamer port.FileStreamer,
+	files port.StorageFiles,
+	transformer port.ImageTransformer,
+	cfg port.ImagesConfig,
+	logger port.Logger,
+) *ImageResizer {
+	return &ImageResizer{
+		downloader:  downloader,
+		streamer:    streamer,
+		files:       files,
+		transformer: transformer,
+		cfg:         cfg,
+		logger:      logger,
+	}
+}
+
+// GetStreamer returns resized variant of image. Access is checked as for download of original file.
+// Variants are cached by parameters and version of original
+func (ir *ImageResizer) GetStreamer(
+	ctx context.Context,
+	headers [][2]string,
+	fileName string,
+	args [][2]string,
+	options dto.DownloadOptions,
+) (func(writer *bufio.Writer), dto.DownloadResult, error) {
+	logger := port.LoggerFromContext(ctx, ir.logger).With([2]string{port.LogFieldUuid, fileName})
+	params, err := parseImageParams(args, ir.cfg)
+	if err != nil {
+		return nil, dto.DownloadResult{}, err
+	}
+	access, err := ir.downloader.authorize(headers, fileName, options, false)
+	if err != nil {
+		return nil, dto.DownloadResult{}, err
+	}
+	sourceType := access.info.GetContentType()
+	if !resizableImageTypes[sourceType] {
+		return nil, dto.DownloadResult{}, exceptions.NewApiError(http.StatusUnsupportedMediaType,
+			errors.New("file of type "+sourceType+" can not be resized"))
+	}
+	if params.Format == "" {
+		params.Format = defaultImageFormat(sourceType)
+	}
+	result := dto.DownloadResult{StatusCode: http.StatusOK, ContentType: params.GetContentType(), Headers: access.headers}
+	variantKey := ImageVariantsPrefix(access.record.GetUUID()) + access.info.GetETag() + "/" + params.VariantName()
+	variant := dto.FileLocation{
+		Bucket:     ir.cfg.GetBucket(),
+		Key:        variantKey,
+		Encryption: access.record.GetLocation().Encryption.ForObject(variantKey),
+	}
+	if variant.Bucket != "" {
+		if exists, err := ir.files.IsFileExists(variant); err != nil {
+			logger.Error().Println(errors.Wrap(err, "ImageResizer.GetStreamer()"))
+		} else if exists {
+			stream, _, err := ir.streamer.GetFileStream(variant, nil)
+			if err != nil {
+				return nil, dto.DownloadResult{}, exceptions.NewApiError(http.StatusInternalServerError, err)
+			}
+			return ir.downloader.getStreamerFunc(logger, stream), result, nil
+		}
+	}
+	shared, err, _ := ir.flights.Do(variant.Bucket+"/"+variant.Key, func() (interface{}, error) {
+		content, err := ir.resize(logger, access.record.GetLocation(), params)
+		if err != nil {
+			return nil, err
+		}
+		if variant.Bucket != "" {
+			if err = ir.files.PutFile(variant, params.GetContentType(), int64(len(content)), bytes.NewReader(content)); err != nil {
+				logger.Error().Println(errors.Wrap(err, "ImageResizer.GetStreamer()"))
+			}
+		}
+		return content, nil
+	})
+	if err != nil {
+		return nil, dto.DownloadResult{}, err
+	}
+	content := shared.([]byte)
+	return func(writer *bufio.Writer) {
+		if _, err := writer.Write(content); err != nil {
+			logger.Error().Println(err)
+		}
+	}, result, nil
+}
+
+func (ir *ImageResizer) resize(logger port.Logger, location dto.FileLocation, params dto.ImageParams) ([]byte, error) {
+	stream, _, err := ir.streamer.GetFileStream(location, nil)
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	defer func() {
+		if err := stream.Close(); err != nil {
+			logger.Error().Println(errors.Wrap(err, "ImageResizer.resize()"))
+		}
+	}()
+	content, err := ir.transformer.Transform(stream, params)
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusUnprocessableEntity, err)
+	}
+	return content, nil
+}
+
+// ImageVariantsPrefix - all cached variants of image have keys with this prefix
+func ImageVariantsPrefix(uuid string) string {
+	return uuid + "/"
+}
+
+func parseImageParams(args [][2]string, cfg port.ImagesConfig) (dto.ImageParams, error) {
+	badRequest := func(msg string) (dto.ImageParams, error) {
+		return dto.ImageParams{}, exceptions.NewApiError(http.StatusBadRequest, errors.New(msg))
+	}
+	var params dto.ImageParams
+	var err error
+	if params.Width, err = parseImageSide(findArg(args, imageWidthArg), cfg.GetMaxWidth()); err != nil {
+		return badRequest("w must be from 0 to " + strconv.Itoa(cfg.GetMaxWidth()))
+	}
+	if params.Height, err = parseImageSide(findArg(args, imageHeightArg), cfg.GetMaxHeight()); err != nil {
+		return badRequest("h must be from 0 to " + strconv.Itoa(cfg.GetMaxHeight()))
+	}
+	switch fit := strings.ToLower(findArg(args, imageFitArg)); fit {
+	case "":
+		params.Fit = dto.ImageFitContain
+	case dto.ImageFitContain, dto.ImageFitCover, dto.ImageFitFill:
+		params.Fit = fit
+	default:
+		return badRequest("fit must be one of contain, cover, fill")
+	}
+	switch format := strings.ToLower(findArg(args, imageFormatArg)); format {
+	case "":
+	case "jpg", dto.ImageFormatJpeg:
+		params.Format = dto.ImageFormatJpeg
+	case dto.ImageFormatPng:
+		params.Format = dto.ImageFormatPng
+	default:
+		return badRequest("format must be jpeg or png")
+	}
+	return params, nil
+}
+
+func parseImageSide(value string, max int) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	side, err := strconv.Atoi(value)
+	if err != nil || side < 0 || side > max {
+		return 0, errors.New("incorrect size")
+	}
+	return side, nil
+}
+
+// defaultImageFormat - png keeps transparency of png and gif
+func defaultImageFormat(sourceType string) string {
+	if sourceType == "image/png" || sourceType == "image/gif" {
+		return dto.ImageFormatPng
+	}
+	return dto.ImageFormatJpeg
+}
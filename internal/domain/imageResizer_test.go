@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"testing"
+)
+
+type suiteImageResizer struct {
+	suite.Suite
+	cfg config.ImagesConfig
+}
+
+func TestImageResizer(t *testing.T) {
+	suite.Run(t, new(suiteImageResizer))
+}
+
+func (s *suiteImageResizer) SetupTest() {
+	s.cfg = config.ImagesConfig{MaxWidth: 1000, MaxHeight: 500}.AfterLoad()
+}
+
+func (s *suiteImageResizer) TestParseDefaults() {
+	params, err := parseImageParams(nil, s.cfg)
+	s.Require().Nil(err)
+	s.Equal(dto.ImageParams{Fit: dto.ImageFitContain}, params)
+}
+
+func (s *suiteImageResizer) TestParse() {
+	params, err := parseImageParams([][2]string{{"w", "300"}, {"h", "200"}, {"fit", "Cover"}, {"format", "jpg"}}, s.cfg)
+	s.Require().Nil(err)
+	s.Equal(dto.ImageParams{Width: 300, Height: 200, Fit: dto.ImageFitCover, Format: dto.ImageFormatJpeg}, params)
+	s.Equal("300x200-cover.jpeg", params.VariantName())
+	s.Equal("image/jpeg", params.GetContentType())
+}
+
+func (s *suiteImageResizer) TestParseErrors() {
+	for _, args := range [][][2]string{
+		{{"w", "1001"}},
+		{{"h", "501"}},
+		{{"w", "-1"}},
+		{{"w", "abc"}},
+		{{"fit", "crop"}},
+		{{"format", "webp"}},
+	} {
+		_, err := parseImageParams(args, s.cfg)
+		s.Require().NotNil(err, args)
+		apiErr, ok := err.(exceptions.ApiError)
+		s.Require().True(ok)
+		s.Equal(http.StatusBadRequest, apiErr.GetCode())
+	}
+}
+
+func (s *suiteImageResizer) TestDefaultFormat() {
+	s.Equal(dto.ImageFormatPng, defaultImageFormat("image/png"))
+	s.Equal(dto.ImageFormatPng, defaultImageFormat("image/gif"))
+	s.Equal(dto.ImageFormatJpeg, defaultImageFormat("image/webp"))
+	s.Equal(dto.ImageFormatJpeg, defaultImageFormat("image/jpeg"))
+}
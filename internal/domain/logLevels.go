@@ -0,0 +1,51 @@
+package domain
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"net/http"
+)
+
+// LogLevels handles admin api, which changes minimal level of logs without restart
+type LogLevels struct {
+	controller port.LogLevelController
+	adminCfg   port.AdminConfig
+	logger     port.Logger
+}
+
+func ProvideLogLevels(controller port.LogLevelController, adminCfg port.AdminConfig, logger port.Logger) *LogLevels {
+	return &LogLevels{controller: controller, adminCfg: adminCfg, logger: logger}
+}
+
+func (ll *LogLevels) Get(headers [][2]string) ([]byte, error) {
+	if err := CheckAdminToken(ll.adminCfg, headers); err != nil {
+		return nil, err
+	}
+	return ll.render()
+}
+
+func (ll *LogLevels) Set(headers [][2]string, body []byte) ([]byte, error) {
+	if err := CheckAdminToken(ll.adminCfg, headers); err != nil {
+		return nil, err
+	}
+	var req dto.LogLevel
+	if err := jsoniter.Unmarshal(body, &req); err != nil {
+		return nil, exceptions.NewApiError(http.StatusBadRequest, err)
+	}
+	previous := ll.controller.Level()
+	if err := ll.controller.SetLevel(req.Level); err != nil {
+		return nil, exceptions.NewApiError(http.StatusBadRequest, err)
+	}
+	ll.logger.Critical().Println("LogLevels.Set(): level is changed from " + previous + " to " + req.Level)
+	return ll.render()
+}
+
+func (ll *LogLevels) render() ([]byte, error) {
+	content, err := jsoniter.Marshal(dto.LogLevel{Level: ll.controller.Level()})
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	return content, nil
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"testing"
+)
+
+type suiteLogLevels struct {
+	suite.Suite
+	loggers logsEngine.Loggers
+	levels  *LogLevels
+}
+
+func TestLogLevels(t *testing.T) {
+	suite.Run(t, new(suiteLogLevels))
+}
+
+func (s *suiteLogLevels) SetupTest() {
+	s.loggers = logsEngine.InitLoggersEmpty("test")
+	s.levels = ProvideLogLevels(&s.loggers, config.AdminConfig{Token: "admin"}, &s.loggers)
+}
+
+func (s *suiteLogLevels) assertCode(err error, code int) {
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(code, apiErr.GetCode())
+}
+
+func (s *suiteLogLevels) TestGetAndSet() {
+	admin := [][2]string{{"X-Admin-Token", "admin"}}
+	content, err := s.levels.Get(admin)
+	s.Require().Nil(err)
+	s.JSONEq(`{"level":"trace"}`, string(content))
+
+	content, err = s.levels.Set(admin, []byte(`{"level":"error"}`))
+	s.Require().Nil(err)
+	s.JSONEq(`{"level":"error"}`, string(content))
+	s.Equal(logsEngine.LogError, s.loggers.Level())
+
+	// loggers with fields share level with parent
+	s.Equal(logsEngine.LogError, s.loggers.With([2]string{"uuid", "1"}).(*logsEngine.Loggers).Level())
+
+	_, err = s.levels.Set(admin, []byte(`{"level":"verbose"}`))
+	s.assertCode(err, http.StatusBadRequest)
+	s.Equal(logsEngine.LogError, s.loggers.Level())
+}
+
+func (s *suiteLogLevels) TestAdminToken() {
+	_, err := s.levels.Get(nil)
+	s.assertCode(err, http.StatusUnauthorized)
+	_, err = s.levels.Set([][2]string{{"X-Admin-Token", "wrong"}}, []byte(`{"level":"error"}`))
+	s.assertCode(err, http.StatusUnauthorized)
+	s.Equal(logsEngine.LogTrace, s.loggers.Level())
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"hash/fnv"
+	"net/http"
+	"sync"
+)
+
+// metaGuardStripes - uploads share locks by hash of uuid
+const metaGuardStripes = 64
+
+// MetaGuard serializes changes of meta of the same upload. Every change is applied to fresh meta, so requests,
+// which change different fields concurrently (secret of replayed start, type of file from first chunk), keep each other
+type MetaGuard struct {
+	storage port.StorageMeta
+	locks   [metaGuardStripes]sync.Mutex
+}
+
+func ProvideMetaGuard(storage port.StorageMeta) *MetaGuard {
+	return &MetaGuard{storage: storage}
+}
+
+// Update loads meta of upload, changes it and saves it under lock of upload. Removed upload is not restored
+func (g *MetaGuard) Update(uuid string, change func(metaInfo *dto.UploaderStartResult)) (dto.UploaderStartResult, error) {
+	var metaInfo dto.UploaderStartResult
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(uuid))
+	lock := &g.locks[hash.Sum32()%metaGuardStripes]
+	lock.Lock()
+	defer lock.Unlock()
+
+	content, err := g.storage.GetMetaFile(MetaFileName(uuid))
+	if err != nil {
+		return metaInfo, exceptions.NewApiError(http.StatusInternalServerError, errors.Wrap(err, "error in meta storage"))
+	}
+	if len(content) == 0 {
+		return metaInfo, exceptions.NewApiError(http.StatusNotFound, errors.New("upload "+uuid+" not found"))
+	}
+	if err = jsoniter.Unmarshal(content, &metaInfo); err != nil {
+		return metaInfo, exceptions.NewApiError(http.StatusInternalServerError, errors.Wrap(err, "error while deserialize meta"))
+	}
+	change(&metaInfo)
+	if content, err = jsoniter.Marshal(metaInfo); err != nil {
+		return metaInfo, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	if err = g.storage.PutMetaFile(MetaFileName(uuid), content); err != nil {
+		return metaInfo, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	return metaInfo, nil
+}
@@ -9,26 +9,53 @@ import (
 	"net/url"
 	"sort"
 	"strconv"
-	"strings"
+	"sync"
+	"time"
+)
+
+// reasons of failed uploads in metrics
+const (
+	uploadFailedCompose     = "compose"
+	uploadFailedInfected    = "infected"
+	uploadFailedScan        = "scan"
+	uploadFailedRecord      = "record"
+	uploadFailedContentType = "content_type"
+	uploadFailedAborted     = "aborted"
 )
 
 type PartsComposer struct {
-	storage port.PartsComposer
-	cleaner port.StorageCleaner
-	cfg     port.UploaderConfig
-	in      chan dto.UploaderStartResult
-	logger  port.Logger
-	poster  port.Poster
-	ctx     context.Context
+	storage  port.PartsComposer
+	records  *FileRecords
+	catalog  *FilesCatalog
+	scanner  *FileScanner
+	pipeline *ProcessingPipeline
+	cleaner  port.StorageCleaner
+	cfg      port.UploaderConfig
+	in       chan dto.UploaderStartResult
+	logger   port.Logger
+	poster   port.Poster
+	metrics  port.UploadMetrics
+	tracer   port.Tracer
+	failures *FailedCallbacks
+	ctx      context.Context
+	// composing - uuids of uploads, which are in queue or are being composed
+	composing sync.Map
 }
 
 func ProvidePartsComposer(
 	ctx port.ContextProvider,
 	storage port.PartsComposer,
 	cleaner port.StorageCleaner,
+	records *FileRecords,
+	catalog *FilesCatalog,
+	scanner *FileScanner,
+	pipeline *ProcessingPipeline,
 	cfg port.UploaderConfig,
 	logger port.Logger,
 	poster port.Poster,
+	metrics port.UploadMetrics,
+	tracer port.Tracer,
+	failures *FailedCallbacks,
 ) *PartsComposer {
 	pc := new(PartsComposer)
 	pc.storage = storage
@@ -36,16 +63,50 @@ func ProvidePartsComposer(
 	pc.in = make(chan dto.UploaderStartResult, cfg.GetComposerWorkers()*2)
 	pc.logger = logger
 	pc.poster = poster
+	pc.metrics = metrics
+	pc.tracer = tracer
+	pc.failures = failures
 	pc.ctx = ctx.Ctx()
 	pc.cleaner = cleaner
+	pc.records = records
+	pc.catalog = catalog
+	pc.scanner = scanner
+	pc.pipeline = pipeline
 
 	pc.runWorkers(pc.ctx)
 
 	return pc
 }
 
-func (pc *PartsComposer) Run(metaInfo dto.UploaderStartResult) {
+func (pc *PartsComposer) Run(metaInfo dto.UploaderStartResult) error {
+	if !pc.acquire(metaInfo.GetUUID()) {
+		return port.ErrAlreadyComposing
+	}
 	pc.in <- metaInfo
+	pc.metrics.ComposeQueueDepth(len(pc.in))
+	return nil
+}
+
+// Compose composes upload in goroutine of caller and returns error, if file is not saved
+func (pc *PartsComposer) Compose(metaInfo dto.UploaderStartResult) error {
+	if !pc.acquire(metaInfo.GetUUID()) {
+		return port.ErrAlreadyComposing
+	}
+	return pc.process(metaInfo)
+}
+
+// acquire marks upload as composing, it is released by process
+func (pc *PartsComposer) acquire(uuid string) bool {
+	_, loaded := pc.composing.LoadOrStore(uuid, true)
+	return !loaded
+}
+
+// CheckBacklog is readiness check: requests with last part are blocked, when queue of composer is full
+func (pc *PartsComposer) CheckBacklog() error {
+	if len(pc.in) >= cap(pc.in) {
+		return errors.New("PartsComposer.CheckBacklog: " + strconv.Itoa(len(pc.in)) + " uploads are waiting for composer")
+	}
+	return nil
 }
 
 func (pc *PartsComposer) runWorkers(ctx context.Context) {
@@ -60,7 +121,8 @@ func (pc *PartsComposer) worker(ctx context.Context, in <-chan dto.UploaderStart
 		case <-ctx.Done():
 			return
 		case metaInfo := <-in:
-			pc.process(metaInfo)
+			pc.metrics.ComposeQueueDepth(len(in))
+			_ = pc.process(metaInfo)
 		}
 	}
 }
@@ -82,58 +144,107 @@ func (pc *PartsComposer) getChunksSlice(metaInfo dto.UploaderStartResult) []stri
 	return partsNames
 }
 
-func (pc *PartsComposer) process(metaInfo dto.UploaderStartResult) {
+// process composes file in own trace, which is linked to trace of request with last part
+func (pc *PartsComposer) process(metaInfo dto.UploaderStartResult) error {
+	defer pc.composing.Delete(metaInfo.GetUUID())
+	ctx, span := pc.tracer.Start(pc.ctx, "upload.compose", metaInfo.TraceParent)
+	span.SetAttribute(uploadUuidAttribute, metaInfo.GetUUID())
+	logger := pc.logger.With(
+		[2]string{port.LogFieldUuid, metaInfo.GetUUID()},
+		[2]string{port.LogFieldRequestId, metaInfo.RequestId},
+	)
+	ctx = port.ContextWithLogger(ctx, logger, metaInfo.RequestId)
+	var err, result error
+	defer func() {
+		span.End(err)
+	}()
 	partsNames := pc.getChunksSlice(metaInfo)
-	_, err := pc.storage.ComposeFileParts(
-		metaInfo.GetUUID(),
+	start := time.Now()
+	_, composeSpan := pc.tracer.Start(ctx, "storage.compose")
+	_, err = pc.storage.ComposeFileParts(
+		metaInfo.GetLocation(),
 		partsNames,
 		metaInfo.GetUserTags(),
+		metaInfo.GetFileContentType(),
 	)
+	composeSpan.End(err)
+	pc.metrics.ComposeDone(time.Since(start))
+	completed := dto.UploadCompleted{UploaderStartResult: metaInfo}
+	// sealed keys are not sent to callbackAfter
+	completed.EncryptedKey, completed.DataKey = "", ""
 	if err != nil {
-		pc.logger.Critical().Println(errors.Wrap(err, "PartsComposer.process()"))
+		result = errors.Wrap(err, "PartsComposer.process()")
+		logger.Critical().Println(result)
+		pc.metrics.UploadFailed(uploadFailedCompose)
+	} else if completed.Scan = pc.scan(ctx, metaInfo.GetLocation()); isFileRemoved(completed.Scan) {
+		result = errors.New("PartsComposer.process(): scan status of file is " + completed.Scan.Status)
+		logger.Error().Println(result)
+		if completed.Scan.IsInfected() {
+			pc.metrics.UploadFailed(uploadFailedInfected)
+		} else {
+			pc.metrics.UploadFailed(uploadFailedScan)
+		}
+	} else if err = pc.saveRecord(ctx, metaInfo, &completed); err != nil {
+		result = errors.Wrap(err, "PartsComposer.process()")
+		logger.Critical().Println(result)
+		pc.metrics.UploadFailed(uploadFailedRecord)
+	} else {
+		pc.catalog.Register(metaInfo, time.Now())
+		pc.metrics.UploadCompleted()
+		logger.Trace().Println("PartsComposer.process(): upload is completed")
 	}
 	if callbackAfter := pc.cfg.GetCallbackAfter(); callbackAfter != nil {
-		pc.processCallbackAfter(callbackAfter, metaInfo) //TODO make async?
+		pc.processCallbackAfter(ctx, callbackAfter, completed) //TODO make async?
 	}
-	err = pc.cleaner.RemoveMeta(MetaFileName(metaInfo.GetUUID()))
-	if err != nil {
-		pc.logger.Error().Println(errors.Wrap(err, "PartsComposer.process()"))
+	if cleanErr := pc.cleaner.RemoveMeta(MetaFileName(metaInfo.GetUUID())); cleanErr != nil {
+		logger.Error().Println(errors.Wrap(cleanErr, "PartsComposer.process()"))
 	}
-	err = pc.cleaner.RemoveParts(partsNames)
-	if err != nil {
-		pc.logger.Error().Println(errors.Wrap(err, "PartsComposer.process()"))
+	if cleanErr := pc.cleaner.RemoveParts(partsNames); cleanErr != nil {
+		logger.Error().Println(errors.Wrap(cleanErr, "PartsComposer.process()"))
+	}
+	return result
+}
+
+func (pc *PartsComposer) scan(ctx context.Context, location dto.FileLocation) *dto.ScanVerdict {
+	_, span := pc.tracer.Start(ctx, "antivirus.scan")
+	verdict := pc.scanner.Scan(location)
+	if verdict != nil {
+		span.SetAttribute("antivirus.status", verdict.Status)
+	}
+	span.End(nil)
+	return verdict
+}
+
+// saveRecord runs processors and saves record of composed file with derived objects. Infected file, which is only
+// tagged, is not processed: it is never served, so derived objects must not be made from it
+func (pc *PartsComposer) saveRecord(ctx context.Context, metaInfo dto.UploaderStartResult, completed *dto.UploadCompleted) error {
+	if completed.Scan == nil || !completed.Scan.IsInfected() {
+		_, span := pc.tracer.Start(ctx, "processing")
+		completed.Processing = pc.pipeline.Run(metaInfo)
+		span.End(nil)
 	}
+	record := dto.NewFileRecord(metaInfo)
+	record.Derived = DerivedObjects(completed.Processing)
+	if completed.Scan != nil {
+		record.ScanStatus = completed.Scan.Status
+	}
+	return pc.records.Save(record)
+}
+
+// isFileRemoved - infected or not scanned file, to which action was applied, is not in storage, if it was not only tagged
+func isFileRemoved(verdict *dto.ScanVerdict) bool {
+	return verdict != nil && verdict.Action != "" && verdict.Action != dto.ScanActionTag
 }
 
-func (pc *PartsComposer) processCallbackAfter(callbackAfter *url.URL, metaInfo dto.UploaderStartResult) {
-	body, err := jsoniter.Marshal(metaInfo)
+func (pc *PartsComposer) processCallbackAfter(ctx context.Context, callbackAfter *url.URL, completed dto.UploadCompleted) {
+	logger := port.LoggerFromContext(ctx, pc.logger)
+	body, err := jsoniter.Marshal(completed)
 	if err != nil {
-		pc.logger.Critical().Println(errors.Wrap(err, "PartsComposer.processCallbackAfter()"))
+		logger.Critical().Println(errors.Wrap(err, "PartsComposer.processCallbackAfter()"))
 		return
 	}
-	retires := 0
-	totalRetires := pc.cfg.GetHttpRetries()
-	var allErrors []string
-	for retires < totalRetires {
-		_, code, err := pc.poster.Post(pc.ctx, *callbackAfter, pc.cfg.GetHttpTimeout(), body)
-		if err == nil && (code >= 200 && code <= 299) {
-			return
-		}
-		retires++
-		if err != nil {
-			e := errors.Wrap(err, "PartsComposer.processCallbackAfter().poster.error")
-			pc.logger.Error().Println(e)
-			allErrors = append(allErrors, e.Error())
-			continue
-		}
-		if code < 200 || code > 299 {
-			e := errors.Wrap(err, "PartsComposer.processCallbackAfter().poster.code_"+strconv.Itoa(code))
-			pc.logger.Error().Println(e)
-			allErrors = append(allErrors, e.Error())
-			continue
-		}
+	if err = pc.records.SaveCallbackAfter(completed.GetUUID(), body); err != nil {
+		logger.Error().Println(errors.Wrap(err, "PartsComposer.processCallbackAfter()"))
 	}
-	pc.logger.Critical().Println("CallbackAfter " + callbackAfter.String() +
-		" Error after " + strconv.Itoa(totalRetires) + " with body " + string(body) +
-		" with errors [" + strings.Join(allErrors, ",") + "]")
+	postWithRetries(ctx, pc.poster, pc.metrics, pc.cfg, logger, pc.failures, callbackTypeAfter, "CallbackAfter", callbackAfter, body)
 }
@@ -1,7 +1,10 @@
 package domain
 
 import (
+	"context"
 	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
 	"github.com/stretchr/testify/suite"
 	"testing"
 )
@@ -37,3 +40,54 @@ func (s *suitePartsComposer) TestGetChunksSlice() {
 	s.Equal("part2", names[2])
 	s.Equal("part3", names[3])
 }
+
+func (s *suitePartsComposer) TestComposeOnce() {
+	pc := &PartsComposer{in: make(chan dto.UploaderStartResult, 3), metrics: new(fakeUploadMetrics)}
+	metaInfo := dto.UploaderStartResult{Uuid: "upload"}
+	s.Require().Nil(pc.Run(metaInfo))
+	s.ErrorIs(pc.Run(metaInfo), port.ErrAlreadyComposing)
+	s.ErrorIs(pc.Compose(metaInfo), port.ErrAlreadyComposing)
+	s.Len(pc.in, 1)
+
+	s.Require().Nil(pc.Run(dto.UploaderStartResult{Uuid: "another"}))
+	pc.composing.Delete(metaInfo.GetUUID())
+	s.Require().Nil(pc.Run(metaInfo))
+}
+
+func (s *suitePartsComposer) TestCheckBacklog() {
+	pc := &PartsComposer{in: make(chan dto.UploaderStartResult, 2)}
+	s.Nil(pc.CheckBacklog())
+	pc.in <- dto.UploaderStartResult{}
+	s.Nil(pc.CheckBacklog())
+	pc.in <- dto.UploaderStartResult{}
+	s.NotNil(pc.CheckBacklog())
+}
+
+func (s *suitePartsComposer) TestInfectedFileIsNotProcessed() {
+	loggers := logsEngine.InitLoggersEmpty("test")
+	streamer := &countingStreamer{}
+	registry := fakeProcessorsRegistry{"checksum": fakeProcessor{name: "checksum", data: map[string]interface{}{"md5": "hash"}}}
+	storage := &fakeAdminMetaStorage{files: make(map[string][]byte)}
+	pc := &PartsComposer{
+		pipeline: ProvideProcessingPipeline(registry, streamer, &fakeStorageFiles{}, &loggers),
+		records:  ProvideFileRecords(storage),
+		tracer:   fakeTracer{},
+	}
+	uid := ProvideUuidProvider().NewUuid()
+	metaInfo := dto.UploaderStartResult{Uuid: uid, Key: uid, Processors: []string{"checksum"}}
+
+	completed := dto.UploadCompleted{UploaderStartResult: metaInfo}
+	completed.Scan = &dto.ScanVerdict{Status: dto.ScanStatusInfected, Action: dto.ScanActionTag}
+	s.Require().Nil(pc.saveRecord(context.Background(), metaInfo, &completed))
+	s.Nil(completed.Processing)
+	s.Equal(0, streamer.calls)
+	record, err := pc.records.Load(uid)
+	s.Require().Nil(err)
+	s.Equal(dto.ScanStatusInfected, record.ScanStatus)
+
+	completed.Scan = &dto.ScanVerdict{Status: dto.ScanStatusClean}
+	s.Require().Nil(pc.saveRecord(context.Background(), metaInfo, &completed))
+	s.Require().Len(completed.Processing, 1)
+	s.Equal(dto.ProcessingStatusOk, completed.Processing[0].Status)
+	s.Equal(1, streamer.calls)
+}
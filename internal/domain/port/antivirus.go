@@ -0,0 +1,17 @@
+package port
+
+import (
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"io"
+)
+
+type VirusScanner interface {
+	IsEnabled() bool
+	Scan(stream io.Reader) (dto.ScanVerdict, error)
+}
+
+type AntivirusConfig interface {
+	GetAction() string
+	GetQuarantineBucket() string
+	IsFailOpen() bool
+}
@@ -0,0 +1,14 @@
+package port
+
+import "github.com/satmaelstorm/filup/internal/domain/dto"
+
+type TokenVerifier interface {
+	IsEnabled() bool
+	Verify(token string) (dto.TokenClaims, error)
+}
+
+type AuthConfig interface {
+	GetTokenHeader() string
+	IsTokenRequired() bool
+	IsSkipCallbacks() bool
+}
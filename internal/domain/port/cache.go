@@ -4,4 +4,5 @@ type MetaCacheController interface {
 	Add(key string, value []byte)
 	Get(key string) ([]byte, bool)
 	Delete(key string)
+	Purge()
 }
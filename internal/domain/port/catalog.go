@@ -0,0 +1,17 @@
+package port
+
+import (
+	"errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+)
+
+// ErrInvalidCatalogCursor - cursor of list was not made by catalog
+var ErrInvalidCatalogCursor = errors.New("invalid cursor")
+
+type FileCatalog interface {
+	IsEnabled() bool
+	Put(entry dto.CatalogEntry) error
+	Get(uuid string) (entry dto.CatalogEntry, found bool, err error)
+	List(filter dto.CatalogFilter) (dto.CatalogPage, error)
+	Delete(uuid string) error
+}
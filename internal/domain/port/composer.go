@@ -1,7 +1,16 @@
 package port
 
-import "github.com/satmaelstorm/filup/internal/domain/dto"
+import (
+	"errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+)
 
+// ErrAlreadyComposing - upload is waiting in queue of composer or is being composed
+var ErrAlreadyComposing = errors.New("upload is already sent to composer")
+
+// PartComposerRunner composes upload once: while upload is in queue or is being composed, it is rejected
+// with ErrAlreadyComposing
 type PartComposerRunner interface {
-	Run(metaInfo dto.UploaderStartResult)
+	Run(metaInfo dto.UploaderStartResult) error
+	Compose(metaInfo dto.UploaderStartResult) error
 }
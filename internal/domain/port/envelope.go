@@ -0,0 +1,16 @@
+package port
+
+type EnvelopeConfig interface {
+	IsEnabled() bool
+	GetMasterKey() []byte
+}
+
+// RawFileStreamer - storage streamer without envelope decryption
+type RawFileStreamer interface {
+	FileStreamer
+}
+
+// RawStorageFiles - storage files without envelope encryption
+type RawStorageFiles interface {
+	StorageFiles
+}
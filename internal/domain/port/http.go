@@ -3,6 +3,7 @@ package port
 import (
 	"bufio"
 	"context"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
 	"io"
 	"net/url"
 	"time"
@@ -34,13 +35,60 @@ type HttpError interface {
 }
 
 type HandlerJson interface {
-	Handle(headers [][2]string, body []byte) ([]byte, error)
+	Handle(ctx context.Context, headers [][2]string, body []byte) ([]byte, error)
 }
 
 type HandlerMultipart interface {
-	Handle(string, int64, io.ReadCloser) (bool, error)
+	Handle(ctx context.Context, headers [][2]string, secret string, filename string, size int64, file io.ReadCloser) (bool, error)
+}
+
+type HandlerUploadSession interface {
+	Status(ctx context.Context, secret string, uuid string) ([]byte, error)
+	Abort(ctx context.Context, secret string, uuid string) error
 }
 
 type HandlerStreamer interface {
-	GetStreamer(headers [][2]string, fileName string) (func(writer *bufio.Writer), FileInfo, error)
+	GetStreamer(ctx context.Context, headers [][2]string, fileName string, options dto.DownloadOptions) (func(writer *bufio.Writer), dto.DownloadResult, error)
+}
+
+type HandlerImage interface {
+	GetStreamer(ctx context.Context, headers [][2]string, fileName string, args [][2]string, options dto.DownloadOptions) (func(writer *bufio.Writer), dto.DownloadResult, error)
+}
+
+type HandlerArchive interface {
+	GetStreamer(ctx context.Context, headers [][2]string, body []byte) (func(writer *bufio.Writer), dto.DownloadResult, error)
+}
+
+type HandlerCatalog interface {
+	List(headers [][2]string, args [][2]string) ([]byte, error)
+	Get(headers [][2]string, uuid string) ([]byte, error)
+}
+
+type HandlerDelete interface {
+	Delete(ctx context.Context, headers [][2]string, uuid string) error
+}
+
+type HandlerLogLevel interface {
+	Get(headers [][2]string) ([]byte, error)
+	Set(headers [][2]string, body []byte) ([]byte, error)
+}
+
+type HandlerAdminHealth interface {
+	Details(ctx context.Context, headers [][2]string) ([]byte, error)
+}
+
+type HandlerAdminUploads interface {
+	ListUploads(headers [][2]string, args [][2]string) ([]byte, error)
+	GetUpload(headers [][2]string, uuid string) ([]byte, error)
+	Compose(ctx context.Context, headers [][2]string, uuid string) error
+	Expire(ctx context.Context, headers [][2]string, uuid string) error
+	ResendCallbackAfter(ctx context.Context, headers [][2]string, uuid string) error
+	ListFailedCallbacks(headers [][2]string) ([]byte, error)
+	ReplayCallback(ctx context.Context, headers [][2]string, id string) error
+	FlushCache(ctx context.Context, headers [][2]string) error
+}
+
+type HandlerSigner interface {
+	Sign(headers [][2]string, body []byte) ([]byte, error)
+	Verify(uuid string, args [][2]string, headers [][2]string, remoteIp string) (dto.DownloadOptions, error)
 }
@@ -0,0 +1,16 @@
+package port
+
+import (
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"io"
+)
+
+type ImageTransformer interface {
+	Transform(source io.Reader, params dto.ImageParams) ([]byte, error)
+}
+
+type ImagesConfig interface {
+	GetBucket() string
+	GetMaxWidth() int
+	GetMaxHeight() int
+}
@@ -1,10 +1,49 @@
 package port
 
-import "log"
+import (
+	"context"
+	"log"
+)
+
+// fields of records, which relate to request or upload
+const (
+	LogFieldUuid      = "uuid"
+	LogFieldPart      = "part"
+	LogFieldRequestId = "request_id"
+	LogFieldRemoteIp  = "remote_ip"
+)
 
 type Logger interface {
 	Critical() *log.Logger
 	Error() *log.Logger
 	Trace() *log.Logger
 	Debug() *log.Logger
+	With(fields ...[2]string) Logger
+}
+
+type LogLevelController interface {
+	Level() string
+	SetLevel(level string) error
+}
+
+type loggerKey struct{}
+
+type requestIdKey struct{}
+
+// ContextWithLogger returns context of request, which carries logger with fields of request
+func ContextWithLogger(ctx context.Context, logger Logger, requestId string) context.Context {
+	return context.WithValue(context.WithValue(ctx, loggerKey{}, logger), requestIdKey{}, requestId)
+}
+
+// LoggerFromContext returns logger of request or defaultLogger, if context has no logger
+func LoggerFromContext(ctx context.Context, defaultLogger Logger) Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(Logger); ok {
+		return logger
+	}
+	return defaultLogger
+}
+
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdKey{}).(string)
+	return requestId
 }
@@ -0,0 +1,22 @@
+package port
+
+import (
+	"context"
+	"time"
+)
+
+// UploadMetrics - domain metrics of uploads, composing and callbacks
+type UploadMetrics interface {
+	UploadStarted()
+	UploadCompleted()
+	UploadFailed(reason string)
+	ChunkReceived(size int64, duration time.Duration)
+	ComposeDone(duration time.Duration)
+	ComposeQueueDepth(depth int)
+	CallbackDone(callbackType string, outcome string, duration time.Duration)
+}
+
+// HealthReporter - state of instance with results of readiness checks
+type HealthReporter interface {
+	Details(ctx context.Context) (bool, []byte, error)
+}
@@ -0,0 +1,22 @@
+package port
+
+import (
+	"io"
+)
+
+// FileProcessor - source is composed file, which is fetched once for all processors of upload.
+// Objects made by processor are written to output and are stored next to the original
+type FileProcessor interface {
+	GetName() string
+	Accepts(contentType string) bool
+	Process(source *io.SectionReader, contentType string, output DerivedOutput) (map[string]interface{}, error)
+}
+
+// DerivedOutput - writer of derived object is valid until Process returns, object key is key of the original with suffix
+type DerivedOutput interface {
+	Create(suffix string, contentType string) (io.Writer, error)
+}
+
+type ProcessorsRegistry interface {
+	GetProcessor(name string) (FileProcessor, bool)
+}
@@ -0,0 +1,15 @@
+package port
+
+import "time"
+
+type SignedUrlConfig interface {
+	GetSignSecret() []byte
+	GetBaseUrl() string
+	GetDefaultTtl() time.Duration
+	GetMaxTtl() time.Duration
+	GetIpHeader() string
+}
+
+type AdminConfig interface {
+	GetAdminToken() string
+}
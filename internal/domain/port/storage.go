@@ -1,24 +1,66 @@
 package port
 
-import "io"
+import (
+	"context"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"io"
+)
+
+// Storage - all storage ports, it is implemented by S3 storage and can be replaced by embedding application
+type Storage interface {
+	StorageMeta
+	StorageMetaLister
+	StoragePart
+	StorageCleaner
+	StorageEncryption
+	PartsComposer
+	RawFileStreamer
+	RawStorageFiles
+	// CheckBuckets is readiness check of storage
+	CheckBuckets(ctx context.Context) error
+}
 
 type StorageCleaner interface {
 	RemoveMeta(fileName string) error
 	RemoveParts(partsNames []string) error
 }
 
+type StorageFiles interface {
+	IsFileExists(location dto.FileLocation) (bool, error)
+	RemoveFile(location dto.FileLocation) error
+	TagFile(location dto.FileLocation, tags map[string]string) error
+	MoveFile(src dto.FileLocation, dest dto.FileLocation) error
+	PutFile(location dto.FileLocation, contentType string, size int64, content io.Reader) error
+	// RemoveFiles removes all objects of bucket, which keys start with prefix
+	RemoveFiles(bucket string, prefix string) error
+}
+
+// StorageMeta - GetMetaFile returns empty content without error if file does not exist
 type StorageMeta interface {
 	PutMetaFile(fileName string, content []byte) error
 	GetMetaFile(fileName string) ([]byte, error)
 }
 
+// StorageMetaLister returns names of meta files, which start with prefix, in lexical order.
+// Names are listed after marker, limit less than 1 returns all names
+type StorageMetaLister interface {
+	ListMetaFiles(prefix string, marker string, limit int) ([]string, error)
+}
+
+// StorageEncryption - customer keys of SSE-C are sealed by master key before saving in meta
+type StorageEncryption interface {
+	IsCustomerKeyAllowed() bool
+	SealCustomerKey(key []byte) (string, error)
+}
+
+// StoragePart - parts are encrypted with SSE-C key of upload, if it is enabled
 type StoragePart interface {
-	PutFilePart(fullPartName string, filesize int64, content io.Reader) error
+	PutFilePart(fullPartName string, filesize int64, content io.Reader, encryption *dto.Encryption) error
 	GetLoadedFilePartsNames(fileName string) ([]string, error)
 }
 
 type PartsComposer interface {
-	ComposeFileParts(destFileName string, fullPartsName []string, tags map[string]string) (PartsComposerResult, error)
+	ComposeFileParts(dest dto.FileLocation, fullPartsName []string, tags map[string]string, contentType string) (PartsComposerResult, error)
 }
 
 type PartsComposerResult interface {
@@ -30,8 +72,13 @@ type PartsComposerResult interface {
 type FileInfo interface {
 	GetSize() int64
 	GetContentType() string
+	GetUserMetadata() map[string]string
+	GetETag() string
 }
 
 type FileStreamer interface {
-	GetFileStream(fileName string) (stream io.ReadCloser, info FileInfo, err error)
+	GetFileInfo(location dto.FileLocation) (FileInfo, error)
+	GetFileStream(location dto.FileLocation, byteRange *dto.ByteRange) (stream io.ReadCloser, info FileInfo, err error)
+	// GetFileTags is separate request to storage, so tags are read only when they are needed
+	GetFileTags(location dto.FileLocation) (map[string]string, error)
 }
@@ -0,0 +1,19 @@
+package port
+
+import "context"
+
+// Tracer - spans of upload lifecycle
+type Tracer interface {
+	// Extract returns ctx with span of W3C traceparent header of incoming request
+	Extract(ctx context.Context, headers [][2]string) context.Context
+	// Start starts child span of ctx, links are W3C traceparent values of spans, which caused this span
+	Start(ctx context.Context, name string, links ...string) (context.Context, Span)
+	// TraceParent returns W3C traceparent of span of ctx
+	TraceParent(ctx context.Context) string
+}
+
+type Span interface {
+	SetAttribute(key string, value string)
+	// End ends span, span with error has error status
+	End(err error)
+}
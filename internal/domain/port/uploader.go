@@ -1,6 +1,7 @@
 package port
 
 import (
+	"github.com/satmaelstorm/filup/internal/domain/dto"
 	"net/url"
 	"time"
 )
@@ -11,9 +12,19 @@ type UploaderConfig interface {
 	GetCallbackBefore() *url.URL
 	GetCallbackAfter() *url.URL
 	GetCallbackDownload() *url.URL
+	GetCallbackDownloadMethod() string
+	GetCallbackDelete() *url.URL
+	GetCallbackDeleted() *url.URL
+	GetCallbackSecret() []byte
 	GetHttpTimeout() time.Duration
 	GetHttpRetries() int
 	GetComposerWorkers() int
+	GetNamespace(tenant, route string) (bucket string, keyTemplate string)
+	GetContentPolicy() dto.ContentPolicy
+	GetProcessors(tenant, route string) []string
+	GetArchiveMaxFiles() int
+	// GetFailedCallbacksTtl - undelivered callbacks are kept for this time, zero keeps them until replay
+	GetFailedCallbacksTtl() time.Duration
 }
 
 type UploaderConfigWithConstants interface {
@@ -0,0 +1,199 @@
+package domain
+
+import (
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+type ProcessingPipeline struct {
+	registry port.ProcessorsRegistry
+	streamer port.FileStreamer
+	files    port.StorageFiles
+	logger   port.Logger
+}
+
+func ProvideProcessingPipeline(
+	registry port.ProcessorsRegistry,
+	streamer port.FileStreamer,
+	files port.StorageFiles,
+	logger port.Logger,
+) *ProcessingPipeline {
+	return &ProcessingPipeline{
+		registry: registry,
+		streamer: streamer,
+		files:    files,
+		logger:   logger,
+	}
+}
+
+// Run applies processors of upload to composed file one by one. Failure of processor does not stop the chain.
+// File is fetched into temporary file once, when the first processor accepts it
+func (pp *ProcessingPipeline) Run(metaInfo dto.UploaderStartResult) []dto.ProcessorResult {
+	if len(metaInfo.Processors) == 0 {
+		return nil
+	}
+	source := &processingSource{streamer: pp.streamer, location: metaInfo.GetLocation(), logger: pp.logger}
+	defer source.close()
+	results := make([]dto.ProcessorResult, 0, len(metaInfo.Processors))
+	for _, name := range metaInfo.Processors {
+		result := pp.runProcessor(name, metaInfo, source)
+		if result.Status == dto.ProcessingStatusError {
+			pp.logger.Error().Println("ProcessingPipeline.Run(): " + metaInfo.GetUUID() + ": " + name + ": " + result.Error)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func (pp *ProcessingPipeline) runProcessor(name string, metaInfo dto.UploaderStartResult, source *processingSource) dto.ProcessorResult {
+	result := dto.ProcessorResult{Name: name, Status: dto.ProcessingStatusOk}
+	processor, ok := pp.registry.GetProcessor(name)
+	if !ok {
+		return pp.failed(result, errors.New("unknown processor"))
+	}
+	contentType := metaInfo.GetFileContentType()
+	if !processor.Accepts(contentType) {
+		result.Status = dto.ProcessingStatusSkipped
+		return result
+	}
+	reader, err := source.open()
+	if err != nil {
+		return pp.failed(result, err)
+	}
+	output := &derivedOutput{logger: pp.logger}
+	defer output.close()
+	result.Data, err = processor.Process(reader, contentType, output)
+	if err != nil {
+		result.Data = nil
+		return pp.failed(result, err)
+	}
+	location := metaInfo.GetLocation()
+	for _, derived := range output.objects {
+		key := location.GetKey() + derived.suffix
+		derivedLocation := dto.FileLocation{
+			Bucket:     location.GetBucket(),
+			Key:        key,
+			Encryption: location.Encryption.ForObject(key),
+		}
+		if err = pp.putDerived(derivedLocation, derived); err != nil {
+			return pp.failed(result, err)
+		}
+		result.Objects = append(result.Objects, derivedLocation)
+	}
+	return result
+}
+
+func (pp *ProcessingPipeline) putDerived(location dto.FileLocation, derived derivedObject) error {
+	info, err := derived.file.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err = derived.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return pp.files.PutFile(location, derived.contentType, info.Size(), derived.file)
+}
+
+func (pp *ProcessingPipeline) failed(result dto.ProcessorResult, err error) dto.ProcessorResult {
+	result.Status = dto.ProcessingStatusError
+	result.Error = err.Error()
+	return result
+}
+
+// processingSource - composed file, which is shared by processors of upload
+type processingSource struct {
+	streamer port.FileStreamer
+	location dto.FileLocation
+	logger   port.Logger
+	file     *os.File
+	size     int64
+	err      error
+}
+
+func (ps *processingSource) open() (*io.SectionReader, error) {
+	if ps.file == nil && ps.err == nil {
+		ps.err = ps.fetch()
+	}
+	if ps.err != nil {
+		return nil, ps.err
+	}
+	return io.NewSectionReader(ps.file, 0, ps.size), nil
+}
+
+func (ps *processingSource) fetch() error {
+	stream, _, err := ps.streamer.GetFileStream(ps.location, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := stream.Close(); closeErr != nil {
+			ps.logger.Error().Println(errors.Wrap(closeErr, "ProcessingPipeline.fetch()"))
+		}
+	}()
+	file, err := ioutil.TempFile("", "filup-source-")
+	if err != nil {
+		return err
+	}
+	size, err := io.Copy(file, stream)
+	if err != nil {
+		removeTempFile(file, ps.logger)
+		return err
+	}
+	ps.file, ps.size = file, size
+	return nil
+}
+
+func (ps *processingSource) close() {
+	if ps.file != nil {
+		removeTempFile(ps.file, ps.logger)
+	}
+}
+
+type derivedObject struct {
+	suffix      string
+	contentType string
+	file        *os.File
+}
+
+// derivedOutput keeps derived objects in temporary files, so size of object is known before it is stored
+type derivedOutput struct {
+	logger  port.Logger
+	objects []derivedObject
+}
+
+func (do *derivedOutput) Create(suffix string, contentType string) (io.Writer, error) {
+	file, err := ioutil.TempFile("", "filup-derived-")
+	if err != nil {
+		return nil, err
+	}
+	do.objects = append(do.objects, derivedObject{suffix: suffix, contentType: contentType, file: file})
+	return file, nil
+}
+
+func (do *derivedOutput) close() {
+	for _, derived := range do.objects {
+		removeTempFile(derived.file, do.logger)
+	}
+}
+
+func removeTempFile(file *os.File, logger port.Logger) {
+	if err := file.Close(); err != nil {
+		logger.Error().Println(errors.Wrap(err, "removeTempFile()"))
+	}
+	if err := os.Remove(file.Name()); err != nil {
+		logger.Error().Println(errors.Wrap(err, "removeTempFile()"))
+	}
+}
+
+// DerivedObjects returns all objects, which were made by processors
+func DerivedObjects(results []dto.ProcessorResult) []dto.FileLocation {
+	var locations []dto.FileLocation
+	for _, result := range results {
+		locations = append(locations, result.Objects...)
+	}
+	return locations
+}
@@ -0,0 +1,127 @@
+package domain
+
+import (
+	"errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+type fakeProcessor struct {
+	name    string
+	accepts string
+	data    map[string]interface{}
+	derived string
+	err     error
+}
+
+func (f fakeProcessor) GetName() string {
+	return f.name
+}
+
+func (f fakeProcessor) Accepts(contentType string) bool {
+	return f.accepts == "" || f.accepts == contentType
+}
+
+func (f fakeProcessor) Process(source *io.SectionReader, contentType string, output port.DerivedOutput) (map[string]interface{}, error) {
+	if f.derived != "" {
+		content, err := ioutil.ReadAll(source)
+		if err != nil {
+			return nil, err
+		}
+		writer, err := output.Create(f.derived, "image/jpeg")
+		if err != nil {
+			return nil, err
+		}
+		_, _ = writer.Write(content)
+	}
+	return f.data, f.err
+}
+
+// countingStreamer counts downloads of source file
+type countingStreamer struct {
+	fakeFileStreamer
+	calls int
+}
+
+func (c *countingStreamer) GetFileStream(location dto.FileLocation, byteRange *dto.ByteRange) (io.ReadCloser, port.FileInfo, error) {
+	c.calls++
+	return c.fakeFileStreamer.GetFileStream(location, byteRange)
+}
+
+type fakeProcessorsRegistry map[string]port.FileProcessor
+
+func (f fakeProcessorsRegistry) GetProcessor(name string) (port.FileProcessor, bool) {
+	processor, ok := f[name]
+	return processor, ok
+}
+
+type suiteProcessingPipeline struct {
+	suite.Suite
+	files    *fakeStorageFiles
+	streamer *countingStreamer
+	pipeline *ProcessingPipeline
+}
+
+func TestProcessingPipeline(t *testing.T) {
+	suite.Run(t, new(suiteProcessingPipeline))
+}
+
+func (s *suiteProcessingPipeline) SetupTest() {
+	s.files = &fakeStorageFiles{}
+	loggers := logsEngine.InitLoggersEmpty("test")
+	registry := fakeProcessorsRegistry{
+		"checksum":  fakeProcessor{name: "checksum", data: map[string]interface{}{"md5": "hash"}},
+		"thumbnail": fakeProcessor{name: "thumbnail", accepts: "image/png", derived: ".thumb.jpg"},
+		"broken":    fakeProcessor{name: "broken", err: errors.New("broken file")},
+	}
+	s.streamer = &countingStreamer{}
+	s.pipeline = ProvideProcessingPipeline(registry, s.streamer, s.files, &loggers)
+}
+
+func (s *suiteProcessingPipeline) makeMeta(contentType string, processors ...string) dto.UploaderStartResult {
+	meta := dto.UploaderStartResult{Bucket: "files", Key: "a/b", Processors: processors}
+	meta.DetectedContentType = contentType
+	return meta
+}
+
+func (s *suiteProcessingPipeline) TestNoProcessors() {
+	s.Nil(s.pipeline.Run(s.makeMeta("image/png")))
+}
+
+func (s *suiteProcessingPipeline) TestChain() {
+	results := s.pipeline.Run(s.makeMeta("image/png", "broken", "unknown", "checksum", "thumbnail"))
+	s.Require().Equal(4, len(results))
+	s.Equal(dto.ProcessingStatusError, results[0].Status)
+	s.Equal("broken file", results[0].Error)
+	s.Equal(dto.ProcessingStatusError, results[1].Status)
+	s.Equal(dto.ProcessingStatusOk, results[2].Status)
+	s.Equal("hash", results[2].Data["md5"])
+	s.Equal(dto.ProcessingStatusOk, results[3].Status)
+	s.Require().Equal(1, len(s.files.put))
+	s.Equal("files", s.files.put[0].GetBucket())
+	s.Equal("a/b.thumb.jpg", s.files.put[0].GetKey())
+	s.Equal([]string{"content"}, s.files.contents)
+	s.Equal(s.files.put, DerivedObjects(results))
+	s.Equal(1, s.streamer.calls)
+}
+
+func (s *suiteProcessingPipeline) TestSkipped() {
+	results := s.pipeline.Run(s.makeMeta("text/plain", "thumbnail"))
+	s.Require().Equal(1, len(results))
+	s.Equal(dto.ProcessingStatusSkipped, results[0].Status)
+	s.Equal(0, len(s.files.put))
+	s.Equal(0, s.streamer.calls)
+}
+
+func (s *suiteProcessingPipeline) TestDerivedFailed() {
+	s.files.failErr = errors.New("access denied")
+	results := s.pipeline.Run(s.makeMeta("image/png", "thumbnail"))
+	s.Require().Equal(1, len(results))
+	s.Equal(dto.ProcessingStatusError, results[0].Status)
+	s.Nil(DerivedObjects(results))
+}
@@ -0,0 +1,7 @@
+package domain
+
+// attributes of spans
+const (
+	uploadUuidAttribute  = "upload.uuid"
+	uploadChunkAttribute = "upload.chunk"
+)
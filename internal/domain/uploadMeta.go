@@ -2,6 +2,9 @@ package domain
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
 	"github.com/satmaelstorm/filup/internal/domain/dto"
@@ -10,6 +13,20 @@ import (
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SseCustomerKeyHeader - base64 encoded 32 bytes SSE-C key of upload, it is never sent to callbacks
+const SseCustomerKeyHeader = "X-Sse-Customer-Key"
+
+var (
+	namespaceNameRegexp  = regexp.MustCompile("^[a-zA-Z0-9_.-]*$")
+	keyTemplateVarRegexp = regexp.MustCompile("{[a-z]+}")
 )
 
 type innerMeta struct {
@@ -17,20 +34,46 @@ type innerMeta struct {
 	uuid          string
 	uuidGenerated bool
 	userTags      map[string]string
+	tenant        string
+	route         string
+	fileName      string
+	contentType   string
+	owner         string
+	overwrite     bool
+	contentPolicy *dto.ContentPolicy
+	processors    []string
 }
 
 func ProvideMetaUploader(
 	ctxProvider port.ContextProvider,
 	config port.UploaderConfig,
 	storage port.StorageMeta,
+	guard *MetaGuard,
+	files port.StorageFiles,
+	records *FileRecords,
 	uuidProvider UuidProvider,
 	poster port.Poster,
+	auth *Authenticator,
+	encryption port.StorageEncryption,
+	envelope *Envelope,
+	metrics port.UploadMetrics,
+	tracer port.Tracer,
+	logger port.Logger,
 ) *MetaUploader {
 	return &MetaUploader{
 		uploaderCfg:  config,
 		metaStorage:  storage,
+		guard:        guard,
+		files:        files,
+		records:      records,
 		UuidProvider: uuidProvider,
 		poster:       poster,
+		auth:         auth,
+		encryption:   encryption,
+		envelope:     envelope,
+		metrics:      metrics,
+		tracer:       tracer,
+		logger:       logger,
 		ctx:          ctxProvider.Ctx(),
 	}
 }
@@ -38,21 +81,72 @@ func ProvideMetaUploader(
 type MetaUploader struct {
 	uploaderCfg  port.UploaderConfig
 	metaStorage  port.StorageMeta
+	guard        *MetaGuard
+	files        port.StorageFiles
+	records      *FileRecords
 	UuidProvider UuidProvider
 	poster       port.Poster
+	auth         *Authenticator
+	encryption   port.StorageEncryption
+	envelope     *Envelope
+	metrics      port.UploadMetrics
+	tracer       port.Tracer
+	logger       port.Logger
 	ctx          context.Context
+	starting     sync.Map
+}
+
+// Handle starts upload, span of start is child of span of incoming request
+func (m *MetaUploader) Handle(ctx context.Context, headers [][2]string, body []byte) (result []byte, err error) {
+	logger := port.LoggerFromContext(ctx, m.logger)
+	ctx, span := m.tracer.Start(m.tracer.Extract(m.ctx, headers), "upload.start")
+	defer func() {
+		span.End(err)
+	}()
+	return m.start(ctx, span, logger, headers, body)
 }
 
-func (m *MetaUploader) Handle(headers [][2]string, body []byte) ([]byte, error) {
+func (m *MetaUploader) start(ctx context.Context, span port.Span, logger port.Logger, headers [][2]string, body []byte) ([]byte, error) {
 	im, err := m.extractParams(body)
 	if err != nil {
 		return nil, err
 	}
-	if im.uuidGenerated {
-		body, err = m.addUuidToBody(body, im.uuid)
+	span.SetAttribute(uploadUuidAttribute, im.uuid)
+	logger = logger.With([2]string{port.LogFieldUuid, im.uuid})
+	claims, err := m.auth.Authenticate(headers, scopeUpload)
+	if err != nil {
+		return nil, err
+	}
+	im, err = m.applyClaims(im, claims)
+	if err != nil {
+		return nil, err
+	}
+	if !im.uuidGenerated {
+		if _, loaded := m.starting.LoadOrStore(im.uuid, struct{}{}); loaded {
+			return nil, exceptions.NewApiError(http.StatusConflict, errors.New("upload "+im.uuid+" is starting already"))
+		}
+		defer m.starting.Delete(im.uuid)
+		plan, err := m.findStartedUpload(im)
 		if err != nil {
 			return nil, err
 		}
+		if plan != nil {
+			return m.replayPlan(ctx, headers, body, im, claims, *plan)
+		}
+	}
+	encryptedKey, err := m.sealCustomerKey(findHeader(headers, SseCustomerKeyHeader))
+	if err != nil {
+		return nil, err
+	}
+	headers = removeHeader(headers, SseCustomerKeyHeader)
+	existingFile, err := m.findExistingFile(im)
+	if err != nil {
+		return nil, err
+	}
+	// uuid can be generated or set by token, callbackBefore gets it in any case
+	body, err = m.addUuidToBody(body, im.uuid)
+	if err != nil {
+		return nil, err
 	}
 
 	chunks := m.prepareChunks(im)
@@ -62,10 +156,48 @@ func (m *MetaUploader) Handle(headers [][2]string, body []byte) ([]byte, error)
 		return nil, err
 	}
 
-	err = m.postBeforeUpload(headers, body)
+	var callbackResponse []byte
+	if !m.auth.SkipCallback(claims, newFileUuid(im)) {
+		callbackResponse, err = m.postBeforeUpload(ctx, headers, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+	chunks.RequestHash = im.fingerprint()
+	im = m.applyBeforeDecision(im, callbackResponse)
+	if err = m.checkDeclaredContentType(im); err != nil {
+		return nil, err
+	}
+	if existingFile != nil {
+		chunks.Bucket, chunks.Key = existingFile.Bucket, existingFile.Key
+	} else if err = m.setLocation(&chunks, im, now); err != nil {
+		return nil, err
+	}
+	m.setDescription(&chunks, im, now)
+	chunks.SseC = m.encryption.IsCustomerKeyAllowed()
+	chunks.EncryptedKey = encryptedKey
+	if chunks.DataKey, err = m.envelope.NewDataKey(); err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+
+	result, err := m.storePlan(chunks)
 	if err != nil {
 		return nil, err
 	}
+	m.metrics.UploadStarted()
+	logger.Trace().Println("MetaUploader.Handle(): upload is started")
+	return result, nil
+}
+
+// storePlan issues new upload secret and saves plan of upload. Only response contains secret, meta contains its hash
+func (m *MetaUploader) storePlan(chunks dto.UploaderStartResult) ([]byte, error) {
+	secret, secretHash, err := NewUploadSecret()
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	chunks.SecretHash = secretHash
 
 	metaContent, err := m.renderMetaContent(chunks)
 	if err != nil {
@@ -77,7 +209,195 @@ func (m *MetaUploader) Handle(headers [][2]string, body []byte) ([]byte, error)
 		return nil, err
 	}
 
-	return metaContent, nil
+	return renderPlanResponse(metaContent, secret)
+}
+
+// sealCustomerKey returns SSE-C key of client sealed by master key. Without key of client SSE-C key is derived from master key
+func (m *MetaUploader) sealCustomerKey(key string) (string, error) {
+	if key == "" {
+		return "", nil
+	}
+	if !m.encryption.IsCustomerKeyAllowed() {
+		return "", exceptions.NewApiError(http.StatusBadRequest, errors.New("customer keys are not allowed"))
+	}
+	rawKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(rawKey) != 32 {
+		return "", exceptions.NewApiError(http.StatusBadRequest, errors.New(SseCustomerKeyHeader+" must be base64 encoded 32 bytes key"))
+	}
+	sealed, err := m.encryption.SealCustomerKey(rawKey)
+	if err != nil {
+		return "", exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	return sealed, nil
+}
+
+func renderPlanResponse(metaContent []byte, secret string) ([]byte, error) {
+	response, err := sjson.DeleteBytes(metaContent, "secret_hash")
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	for _, field := range []string{"encrypted_key", "data_key"} {
+		if response, err = sjson.DeleteBytes(response, field); err != nil {
+			return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+		}
+	}
+	if secret == "" {
+		return response, nil
+	}
+	response, err = sjson.SetBytes(response, "upload_secret", secret)
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	return response, nil
+}
+
+// applyClaims restricts upload with token claims: size limit, uuid of file and forced user tags.
+// Subject of token becomes owner of file, callbackBefore can override it
+func (m *MetaUploader) applyClaims(im innerMeta, claims *dto.TokenClaims) (innerMeta, error) {
+	if claims == nil {
+		return im, nil
+	}
+	if claims.GetMaxSize() > 0 && im.size > claims.GetMaxSize() {
+		return im, exceptions.NewApiError(http.StatusRequestEntityTooLarge, errors.New("file size exceeds size allowed by token"))
+	}
+	if claims.GetUuid() != "" && im.uuidGenerated {
+		if !IsCorrectUuid(claims.GetUuid()) {
+			return im, exceptions.NewApiError(http.StatusForbidden, errors.New("token contains incorrect uuid"))
+		}
+		im.uuid = claims.GetUuid()
+		im.uuidGenerated = false
+	} else if err := m.auth.CheckUuid(claims, im.uuid); err != nil {
+		return im, err
+	}
+	for name, value := range claims.GetTags() {
+		if tagValue, ok := im.userTags[name]; ok && tagValue != value {
+			return im, exceptions.NewApiError(http.StatusForbidden, errors.New("user tag "+name+" is not allowed by token"))
+		}
+		im.userTags[name] = value
+	}
+	im.owner = claims.GetSubject()
+	return im, nil
+}
+
+// newFileUuid returns empty uuid for new file with generated uuid and uuid of file otherwise
+func newFileUuid(im innerMeta) string {
+	if im.uuidGenerated {
+		return ""
+	}
+	return im.uuid
+}
+
+// findStartedUpload returns plan of in-flight upload with client-supplied uuid if start request is a replay
+// of the same upload, and 409 if the plan was created by a different request
+func (m *MetaUploader) findStartedUpload(im innerMeta) (*dto.UploaderStartResult, error) {
+	metaContent, err := m.metaStorage.GetMetaFile(MetaFileName(im.uuid))
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	if len(metaContent) == 0 {
+		return nil, nil
+	}
+	if gjson.GetBytes(metaContent, "request_hash").String() != im.fingerprint() {
+		return nil, exceptions.NewApiError(http.StatusConflict, errors.New("upload "+im.uuid+" is already started with other parameters"))
+	}
+	var plan dto.UploaderStartResult
+	if err = jsoniter.Unmarshal(metaContent, &plan); err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, errors.Wrap(err, "error while deserialize meta"))
+	}
+	return &plan, nil
+}
+
+// replayPlan returns plan of started upload. Client, which knows secret of upload, gets the plan with the same secret.
+// Without secret replay is authorized like the first start and secret is rotated
+func (m *MetaUploader) replayPlan(
+	ctx context.Context,
+	headers [][2]string,
+	body []byte,
+	im innerMeta,
+	claims *dto.TokenClaims,
+	plan dto.UploaderStartResult,
+) ([]byte, error) {
+	if secret := findHeader(headers, uploadSecretHeader); secret != "" {
+		if err := CheckUploadSecret(secret, plan.SecretHash); err != nil {
+			return nil, err
+		}
+		metaContent, err := m.renderMetaContent(plan)
+		if err != nil {
+			return nil, err
+		}
+		return renderPlanResponse(metaContent, secret)
+	}
+	if err := m.authorizeReplay(ctx, headers, body, im, claims); err != nil {
+		return nil, err
+	}
+	return m.rotateSecret(plan.GetUUID())
+}
+
+// rotateSecret issues new secret of started upload. Only hash of secret is changed in meta, type of file could be
+// detected by first chunk since plan was loaded
+func (m *MetaUploader) rotateSecret(uuid string) ([]byte, error) {
+	secret, secretHash, err := NewUploadSecret()
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	plan, err := m.guard.Update(uuid, func(metaInfo *dto.UploaderStartResult) {
+		metaInfo.SecretHash = secretHash
+	})
+	if err != nil {
+		return nil, err
+	}
+	metaContent, err := m.renderMetaContent(plan)
+	if err != nil {
+		return nil, err
+	}
+	return renderPlanResponse(metaContent, secret)
+}
+
+// authorizeReplay - replay without secret is authorized by callbackBefore with the same body as the first start
+// or by token issued for this uuid. Secret of upload is never rotated without authorization
+func (m *MetaUploader) authorizeReplay(ctx context.Context, headers [][2]string, body []byte, im innerMeta, claims *dto.TokenClaims) error {
+	if m.auth.SkipCallback(claims, im.uuid) {
+		return nil
+	}
+	if m.uploaderCfg.GetCallbackBefore() == nil {
+		if claims != nil && claims.GetUuid() == im.uuid {
+			return nil
+		}
+		return exceptions.NewApiError(http.StatusConflict, errors.New("upload "+im.uuid+" is already started, "+uploadSecretHeader+" is required"))
+	}
+	body, err := m.addUuidToBody(body, im.uuid)
+	if err != nil {
+		return err
+	}
+	body, err = m.addChunksToBody(body, m.prepareChunks(im))
+	if err != nil {
+		return err
+	}
+	_, err = m.postBeforeUpload(ctx, removeHeader(headers, SseCustomerKeyHeader), body)
+	return err
+}
+
+// findExistingFile returns record of composed file with client-supplied uuid.
+// Such file can be only overwritten in place and only if overwrite flag is set.
+func (m *MetaUploader) findExistingFile(im innerMeta) (*dto.FileRecord, error) {
+	if im.uuidGenerated {
+		return nil, nil
+	}
+	record, err := m.records.Load(im.uuid)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := m.files.IsFileExists(record.GetLocation())
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	if !exists {
+		return nil, nil
+	}
+	if !im.overwrite {
+		return nil, exceptions.NewApiError(http.StatusConflict, errors.New("file "+im.uuid+" already exists"))
+	}
+	return &record, nil
 }
 
 func (m *MetaUploader) renderMetaContent(chunks dto.UploaderStartResult) ([]byte, error) {
@@ -96,20 +416,119 @@ func (m *MetaUploader) putMetaFile(uuid string, content []byte) error {
 	return nil
 }
 
-func (m *MetaUploader) postBeforeUpload(headers [][2]string, body []byte) error {
+func (m *MetaUploader) postBeforeUpload(ctx context.Context, headers [][2]string, body []byte) ([]byte, error) {
 	if nil == m.uploaderCfg.GetCallbackBefore() {
+		return nil, nil
+	}
+	httpResult, httpCode, err := postCallback(ctx, m.poster, m.metrics, m.uploaderCfg, callbackTypeBefore, *m.uploaderCfg.GetCallbackBefore(), body, headers...)
+	if err = callbackError(httpResult, httpCode, err); err != nil {
+		return nil, err
+	}
+	return httpResult, nil
+}
+
+// applyBeforeDecision - callbackBefore can override tenant and route of upload, set owner of file, restrict
+// types of file and select processors with JSON response
+// {"tenant": "...", "route": "...", "owner": "...", "content_types": {"allow": [], "deny": []}, "processors": []}
+func (m *MetaUploader) applyBeforeDecision(im innerMeta, callbackResponse []byte) innerMeta {
+	if !gjson.ValidBytes(callbackResponse) {
+		return im
+	}
+	decision := gjson.ParseBytes(callbackResponse)
+	if tenant := decision.Get("tenant"); tenant.Exists() {
+		im.tenant = tenant.String()
+	}
+	if route := decision.Get("route"); route.Exists() {
+		im.route = route.String()
+	}
+	if owner := decision.Get("owner"); owner.Exists() {
+		if owner.Type == gjson.String {
+			im.owner = owner.String()
+		} else {
+			im.owner = owner.Raw
+		}
+	}
+	if contentTypes := decision.Get("content_types"); contentTypes.IsObject() {
+		var policy dto.ContentPolicy
+		if err := jsoniter.UnmarshalFromString(contentTypes.Raw, &policy); err == nil && !policy.IsEmpty() {
+			im.contentPolicy = &policy
+		}
+	}
+	if processors := decision.Get("processors"); processors.IsArray() {
+		im.processors = []string{}
+		for _, name := range processors.Array() {
+			im.processors = append(im.processors, name.String())
+		}
+	}
+	return im
+}
+
+// checkDeclaredContentType rejects upload before any chunk, if declared by client type is not allowed.
+// Real type of file is checked with first chunk
+func (m *MetaUploader) checkDeclaredContentType(im innerMeta) error {
+	if im.contentType == "" {
 		return nil
 	}
-	httpResult, httpCode, err := m.poster.Post(m.ctx, *m.uploaderCfg.GetCallbackBefore(), m.uploaderCfg.GetHttpTimeout(), body, headers...)
+	if !m.uploaderCfg.GetContentPolicy().IsAllowed(im.contentType) ||
+		(im.contentPolicy != nil && !im.contentPolicy.IsAllowed(im.contentType)) {
+		return exceptions.NewApiError(http.StatusUnsupportedMediaType, errors.New("content type "+im.contentType+" is not allowed"))
+	}
+	return nil
+}
+
+func (m *MetaUploader) setDescription(chunks *dto.UploaderStartResult, im innerMeta, now time.Time) {
+	chunks.FileName = im.fileName
+	chunks.ContentType = im.contentType
+	chunks.Tenant = im.tenant
+	chunks.Route = im.route
+	chunks.Owner = im.owner
+	chunks.ContentPolicy = im.contentPolicy
+	chunks.Processors = im.processors
+	if chunks.Processors == nil {
+		chunks.Processors = m.uploaderCfg.GetProcessors(im.tenant, im.route)
+	}
+	chunks.CreatedAt = now.Unix()
+}
+
+func (m *MetaUploader) setLocation(chunks *dto.UploaderStartResult, im innerMeta, now time.Time) error {
+	if !namespaceNameRegexp.MatchString(im.tenant) || !namespaceNameRegexp.MatchString(im.route) {
+		return exceptions.NewApiError(http.StatusBadRequest, errors.New("tenant and route may contain only latin letters, digits, '_', '-' and '.'"))
+	}
+	bucket, keyTemplate := m.uploaderCfg.GetNamespace(im.tenant, im.route)
+	key, err := renderKeyTemplate(keyTemplate, map[string]string{
+		"uuid":   im.uuid,
+		"tenant": im.tenant,
+		"route":  im.route,
+		"yyyy":   now.Format("2006"),
+		"mm":     now.Format("01"),
+		"dd":     now.Format("02"),
+	})
 	if err != nil {
-		return exceptions.NewApiError(http.StatusBadGateway, errors.Wrap(err, "Post error"))
+		return err
 	}
-	if httpCode < 200 || httpCode > 299 {
-		return exceptions.NewApiError(httpCode, errors.New(string(httpResult)))
+	chunks.Bucket = bucket
+	if key != im.uuid {
+		chunks.Key = key
 	}
 	return nil
 }
 
+func renderKeyTemplate(keyTemplate string, vars map[string]string) (string, error) {
+	var renderErr error
+	key := keyTemplateVarRegexp.ReplaceAllStringFunc(keyTemplate, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		value, ok := vars[name]
+		if !ok || value == "" {
+			renderErr = exceptions.NewApiError(http.StatusBadRequest, errors.New("no value for "+placeholder+" in key template"))
+		}
+		return value
+	})
+	if renderErr != nil {
+		return "", renderErr
+	}
+	return key, nil
+}
+
 func (m *MetaUploader) addUuidToBody(body []byte, uid string) ([]byte, error) {
 	newBody, err := sjson.SetBytes(body, m.uploaderCfg.GetInfoFieldName()+".uuid", uid)
 	if err != nil {
@@ -159,6 +578,29 @@ func (m *MetaUploader) prepareChunks(im innerMeta) dto.UploaderStartResult {
 	return dto.NewUploaderStartResult(im.uuid, chunks, im.size, im.userTags)
 }
 
+// fingerprint identifies start request by parameters which affect the upload plan
+func (im innerMeta) fingerprint() string {
+	tagsNames := make([]string, 0, len(im.userTags))
+	for name := range im.userTags {
+		tagsNames = append(tagsNames, name)
+	}
+	sort.Strings(tagsNames)
+	parts := []string{
+		im.uuid,
+		strconv.FormatInt(im.size, 10),
+		im.tenant,
+		im.route,
+		im.fileName,
+		im.contentType,
+		strconv.FormatBool(im.overwrite),
+	}
+	for _, name := range tagsNames {
+		parts = append(parts, name+"="+im.userTags[name])
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
 func (m *MetaUploader) extractParams(body []byte) (innerMeta, error) {
 	im := innerMeta{}
 	uploaderInfo := gjson.GetBytes(body, m.uploaderCfg.GetInfoFieldName())
@@ -177,11 +619,19 @@ func (m *MetaUploader) extractParams(body []byte) (innerMeta, error) {
 	uid := uploaderInfo.Get("uuid")
 	if uid.Exists() {
 		im.uuid = uid.String()
+		if !IsCorrectUuid(im.uuid) {
+			return im, exceptions.NewApiError(http.StatusBadRequest, errors.New("field "+m.uploaderCfg.GetInfoFieldName()+".uuid must be correct uuid"))
+		}
 	} else {
 		im.uuid = m.UuidProvider.NewUuid()
 		im.uuidGenerated = true
 	}
 	im.userTags = m.extractUserTags(uploaderInfo)
+	im.tenant = uploaderInfo.Get("tenant").String()
+	im.route = uploaderInfo.Get("route").String()
+	im.fileName = uploaderInfo.Get("file_name").String()
+	im.contentType = uploaderInfo.Get("content_type").String()
+	im.overwrite = uploaderInfo.Get("overwrite").Bool()
 	return im, nil
 }
 
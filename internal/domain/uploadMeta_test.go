@@ -2,17 +2,33 @@ package domain
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
 	"github.com/satmaelstorm/filup/internal/domain/exceptions"
 	"github.com/satmaelstorm/filup/internal/infrastructure/config"
 	"github.com/stretchr/testify/suite"
 	"github.com/tidwall/gjson"
 	"net/http"
 	"net/url"
+	"strconv"
 	"testing"
 	"time"
 )
 
+type fakeStorageEncryption struct {
+	allowed bool
+}
+
+func (f fakeStorageEncryption) IsCustomerKeyAllowed() bool {
+	return f.allowed
+}
+
+func (f fakeStorageEncryption) SealCustomerKey(key []byte) (string, error) {
+	return "sealed:" + strconv.Itoa(len(key)), nil
+}
+
 const (
 	uploadMetaTestJson1 = `
 {
@@ -78,15 +94,17 @@ const (
 
 type fakeMetaStorage struct {
 	lastFilename string
+	content      []byte
 }
 
 func (f *fakeMetaStorage) PutMetaFile(fileName string, content []byte) error {
 	f.lastFilename = fileName
+	f.content = content
 	return nil
 }
 
 func (f *fakeMetaStorage) GetMetaFile(fileName string) ([]byte, error) {
-	return nil, nil
+	return f.content, nil
 }
 
 type fakePoster struct {
@@ -119,6 +137,7 @@ func (s *suiteUploadMeta) SetupSuite() {
 		uploaderCfg:  cfg,
 		metaStorage:  &fakeMetaStorage{},
 		poster:       fakePoster{},
+		metrics:      new(fakeUploadMetrics),
 		UuidProvider: ProvideUuidProvider(),
 	}
 
@@ -271,7 +290,7 @@ func (s *suiteUploadMeta) TestPostBeforeHook() {
 
 	var err error
 
-	err = s.uploader.postBeforeUpload([][2]string{{"API-KEY", "qwerty"}}, []byte(uploadMetaTestJson1))
+	_, err = s.uploader.postBeforeUpload(context.Background(), [][2]string{{"API-KEY", "qwerty"}}, []byte(uploadMetaTestJson1))
 	s.Require().Nil(err)
 
 	rErr := errors.New("test error")
@@ -280,7 +299,7 @@ func (s *suiteUploadMeta) TestPostBeforeHook() {
 		retCode: 200,
 	}
 	s.uploader.poster = fp
-	err = s.uploader.postBeforeUpload([][2]string{{"API-KEY", "qwerty"}}, []byte(uploadMetaTestJson1))
+	_, err = s.uploader.postBeforeUpload(context.Background(), [][2]string{{"API-KEY", "qwerty"}}, []byte(uploadMetaTestJson1))
 	s.Require().NotNil(err)
 	apiErr, ok := err.(exceptions.ApiError)
 	s.Require().True(ok)
@@ -291,7 +310,7 @@ func (s *suiteUploadMeta) TestPostBeforeHook() {
 		retCode: 404,
 	}
 	s.uploader.poster = fp
-	err = s.uploader.postBeforeUpload([][2]string{{"API-KEY", "qwerty"}}, []byte(uploadMetaTestJson1))
+	_, err = s.uploader.postBeforeUpload(context.Background(), [][2]string{{"API-KEY", "qwerty"}}, []byte(uploadMetaTestJson1))
 	s.Require().NotNil(err)
 	apiErr, ok = err.(exceptions.ApiError)
 	s.Require().True(ok)
@@ -307,8 +326,268 @@ func (s *suiteUploadMeta) TestPostBeforeHookNil() {
 	}
 	s.uploaderWithoutCallback.poster = fp
 
-	err := s.uploaderWithoutCallback.postBeforeUpload([][2]string{{"API-KEY", "qwerty"}}, []byte(uploadMetaTestJson1))
+	_, err := s.uploaderWithoutCallback.postBeforeUpload(context.Background(), [][2]string{{"API-KEY", "qwerty"}}, []byte(uploadMetaTestJson1))
 	s.Require().Nil(err)
 
 	s.uploaderWithoutCallback.poster = fakePoster{}
 }
+
+func (s *suiteUploadMeta) TestRenderKeyTemplate() {
+	key, err := renderKeyTemplate("{tenant}/{yyyy}/{mm}/{uuid}", map[string]string{
+		"uuid":   "870915da-76bb-11ec-8686-e4e7494803df",
+		"tenant": "shop",
+		"yyyy":   "2022",
+		"mm":     "01",
+	})
+	s.Require().Nil(err)
+	s.Equal("shop/2022/01/870915da-76bb-11ec-8686-e4e7494803df", key)
+
+	_, err = renderKeyTemplate("{tenant}/{uuid}", map[string]string{"uuid": "870915da-76bb-11ec-8686-e4e7494803df"})
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusBadRequest, apiErr.GetCode())
+}
+
+func (s *suiteUploadMeta) TestSetLocation() {
+	cfg := config.Uploader{
+		InfoFieldName: "_upload_info",
+		KeyTemplate:   "{uuid}",
+		Tenants: map[string]config.Namespace{
+			"shop": {Bucket: "filup-shop", KeyTemplate: "{tenant}/{yyyy}/{mm}/{uuid}"},
+		},
+		Routes: map[string]config.Namespace{
+			"avatars": {KeyTemplate: "{route}/{uuid}"},
+		},
+	}.AfterLoad()
+	uploader := MetaUploader{uploaderCfg: cfg, UuidProvider: ProvideUuidProvider()}
+	uid := uploader.UuidProvider.NewUuid()
+	now := time.Date(2022, 1, 30, 0, 0, 0, 0, time.UTC)
+
+	im := innerMeta{uuid: uid, size: 100}
+	result := uploader.prepareChunks(im)
+	s.Require().Nil(uploader.setLocation(&result, im, now))
+	s.Equal("", result.Bucket)
+	s.Equal("", result.Key)
+	s.Equal(uid, result.GetLocation().GetKey())
+
+	im.tenant = "shop"
+	s.Require().Nil(uploader.setLocation(&result, im, now))
+	s.Equal("filup-shop", result.GetLocation().GetBucket())
+	s.Equal("shop/2022/01/"+uid, result.GetLocation().GetKey())
+
+	im.route = "avatars"
+	s.Require().Nil(uploader.setLocation(&result, im, now))
+	s.Equal("filup-shop", result.GetLocation().GetBucket())
+	s.Equal("avatars/"+uid, result.GetLocation().GetKey())
+
+	im.tenant = "../shop"
+	s.Require().NotNil(uploader.setLocation(&result, im, now))
+}
+
+func (s *suiteUploadMeta) TestApplyBeforeDecision() {
+	im := innerMeta{tenant: "shop"}
+	im = s.uploader.applyBeforeDecision(im, []byte(`{"tenant": "blog", "route": "avatars"}`))
+	s.Equal("blog", im.tenant)
+	s.Equal("avatars", im.route)
+
+	im = s.uploader.applyBeforeDecision(im, []byte(`OK`))
+	s.Equal("blog", im.tenant)
+	s.Nil(im.contentPolicy)
+
+	im = s.uploader.applyBeforeDecision(im, []byte(`{"content_types": {"allow": ["image/"]}}`))
+	s.Require().NotNil(im.contentPolicy)
+	s.Equal([]string{"image/"}, im.contentPolicy.Allow)
+
+	im.contentType = "application/x-msdownload"
+	err := s.uploader.checkDeclaredContentType(im)
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusUnsupportedMediaType, apiErr.GetCode())
+	im.contentType = "image/jpeg"
+	s.Nil(s.uploader.checkDeclaredContentType(im))
+}
+
+func (s *suiteUploadMeta) TestFindExistingFile() {
+	files := &fakeStorageFiles{exists: false}
+	uploader := MetaUploader{
+		uploaderCfg:  s.uploader.uploaderCfg,
+		files:        files,
+		records:      ProvideFileRecords(&fakeMetaStorage{}),
+		UuidProvider: ProvideUuidProvider(),
+	}
+	im := innerMeta{uuid: uploader.UuidProvider.NewUuid()}
+
+	record, err := uploader.findExistingFile(im)
+	s.Require().Nil(err)
+	s.Nil(record)
+
+	files.exists = true
+	_, err = uploader.findExistingFile(im)
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusConflict, apiErr.GetCode())
+
+	im.overwrite = true
+	record, err = uploader.findExistingFile(im)
+	s.Require().Nil(err)
+	s.Require().NotNil(record)
+	s.Equal(im.uuid, record.GetLocation().GetKey())
+
+	im.uuidGenerated = true
+	im.overwrite = false
+	record, err = uploader.findExistingFile(im)
+	s.Require().Nil(err)
+	s.Nil(record)
+}
+
+func (s *suiteUploadMeta) TestFindStartedUpload() {
+	storage := &fakeMetaStorage{}
+	uploader := MetaUploader{
+		uploaderCfg:  s.uploader.uploaderCfg,
+		metaStorage:  storage,
+		UuidProvider: ProvideUuidProvider(),
+	}
+	im := innerMeta{
+		uuid:     uploader.UuidProvider.NewUuid(),
+		size:     100,
+		userTags: map[string]string{"a": "1", "b": "2"},
+	}
+
+	plan, err := uploader.findStartedUpload(im)
+	s.Require().Nil(err)
+	s.Nil(plan)
+
+	chunks := uploader.prepareChunks(im)
+	chunks.RequestHash = im.fingerprint()
+	storage.content, err = uploader.renderMetaContent(chunks)
+	s.Require().Nil(err)
+
+	replay := im
+	replay.userTags = map[string]string{"b": "2", "a": "1"}
+	plan, err = uploader.findStartedUpload(replay)
+	s.Require().Nil(err)
+	s.Require().NotNil(plan)
+	s.Equal(chunks, *plan)
+
+	replay.size = 200
+	_, err = uploader.findStartedUpload(replay)
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusConflict, apiErr.GetCode())
+}
+
+func (s *suiteUploadMeta) TestAuthorizeReplay() {
+	im := innerMeta{uuid: ProvideUuidProvider().NewUuid(), size: 100, userTags: map[string]string{}}
+
+	s.uploader.poster = fakePoster{retCode: http.StatusForbidden}
+	err := s.uploader.authorizeReplay(context.Background(), nil, []byte(uploadMetaTestJson1), im, nil)
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusForbidden, apiErr.GetCode())
+
+	s.uploader.poster = fakePoster{retCode: http.StatusOK}
+	s.Require().Nil(s.uploader.authorizeReplay(context.Background(), nil, []byte(uploadMetaTestJson1), im, nil))
+
+	s.uploader.poster = fakePoster{}
+}
+
+func (s *suiteUploadMeta) TestReplayPlan() {
+	storage := &fakeMetaStorage{}
+	uploader := MetaUploader{
+		uploaderCfg:  s.uploaderWithoutCallback.uploaderCfg,
+		metaStorage:  storage,
+		guard:        ProvideMetaGuard(storage),
+		auth:         ProvideAuthenticator(fakeTokenVerifier{}, config.AuthConfig{}.AfterLoad()),
+		UuidProvider: ProvideUuidProvider(),
+	}
+	im := innerMeta{uuid: uploader.UuidProvider.NewUuid(), size: 100, userTags: map[string]string{}}
+	plan := uploader.prepareChunks(im)
+	secret, secretHash, err := NewUploadSecret()
+	s.Require().Nil(err)
+	plan.SecretHash = secretHash
+	// type of file is detected by first chunk after plan was loaded by replay
+	stored := plan
+	stored.DetectedContentType = "image/png"
+	storage.content, err = jsoniter.Marshal(stored)
+	s.Require().Nil(err)
+
+	response, err := uploader.replayPlan(context.Background(), [][2]string{{uploadSecretHeader, secret}}, nil, im, nil, plan)
+	s.Require().Nil(err)
+	s.Equal(secret, gjson.GetBytes(response, "upload_secret").String())
+	s.Empty(storage.lastFilename)
+
+	_, err = uploader.replayPlan(context.Background(), [][2]string{{uploadSecretHeader, "wrong"}}, nil, im, nil, plan)
+	s.assertCode(err, http.StatusForbidden)
+
+	_, err = uploader.replayPlan(context.Background(), nil, nil, im, nil, plan)
+	s.assertCode(err, http.StatusConflict)
+	_, err = uploader.replayPlan(context.Background(), nil, nil, im, &dto.TokenClaims{Subject: "user1"}, plan)
+	s.assertCode(err, http.StatusConflict)
+	s.Empty(storage.lastFilename)
+
+	response, err = uploader.replayPlan(context.Background(), nil, nil, im, &dto.TokenClaims{Uuid: im.uuid}, plan)
+	s.Require().Nil(err)
+	rotated := gjson.GetBytes(response, "upload_secret").String()
+	s.NotEqual(secret, rotated)
+	s.Equal(MetaFileName(im.uuid), storage.lastFilename)
+	s.Equal("image/png", gjson.GetBytes(storage.content, "detected_content_type").String())
+	s.Nil(CheckUploadSecret(rotated, gjson.GetBytes(storage.content, "secret_hash").String()))
+}
+
+func (s *suiteUploadMeta) assertCode(err error, code int) {
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(code, apiErr.GetCode())
+}
+
+func (s *suiteUploadMeta) TestStorePlan() {
+	storage := &fakeMetaStorage{}
+	uploader := MetaUploader{
+		uploaderCfg:  s.uploader.uploaderCfg,
+		metaStorage:  storage,
+		UuidProvider: ProvideUuidProvider(),
+	}
+	chunks := uploader.prepareChunks(innerMeta{uuid: uploader.UuidProvider.NewUuid(), size: 100})
+	response, err := uploader.storePlan(chunks)
+	s.Require().Nil(err)
+	secret := gjson.GetBytes(response, "upload_secret").String()
+	s.NotEmpty(secret)
+	s.False(gjson.GetBytes(response, "secret_hash").Exists())
+	s.Equal(MetaFileName(chunks.GetUUID()), storage.lastFilename)
+
+	chunks.EncryptedKey = "sealed"
+	response, err = uploader.storePlan(chunks)
+	s.Require().Nil(err)
+	s.False(gjson.GetBytes(response, "encrypted_key").Exists())
+}
+
+func (s *suiteUploadMeta) TestSealCustomerKey() {
+	uploader := MetaUploader{encryption: fakeStorageEncryption{}}
+	sealed, err := uploader.sealCustomerKey("")
+	s.Require().Nil(err)
+	s.Equal("", sealed)
+
+	_, err = uploader.sealCustomerKey(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	s.Require().NotNil(err)
+	s.Equal(http.StatusBadRequest, err.(exceptions.ApiError).GetCode())
+
+	uploader.encryption = fakeStorageEncryption{allowed: true}
+	_, err = uploader.sealCustomerKey(base64.StdEncoding.EncodeToString(make([]byte, 16)))
+	s.Require().NotNil(err)
+	s.Equal(http.StatusBadRequest, err.(exceptions.ApiError).GetCode())
+
+	sealed, err = uploader.sealCustomerKey(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	s.Require().Nil(err)
+	s.Equal("sealed:32", sealed)
+}
+
+func (s *suiteUploadMeta) TestRemoveHeader() {
+	headers := [][2]string{{"Authorization", "token"}, {"x-sse-customer-key", "key"}}
+	s.Equal([][2]string{{"Authorization", "token"}}, removeHeader(headers, SseCustomerKeyHeader))
+}
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"bytes"
+	"context"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
 	"github.com/satmaelstorm/filup/internal/domain/dto"
@@ -8,13 +10,24 @@ import (
 	"github.com/satmaelstorm/filup/internal/domain/port"
 	"io"
 	"net/http"
+	"sort"
 	"strconv"
+	"time"
 )
 
+// sniffLength - http.DetectContentType considers at most 512 bytes
+const sniffLength = 512
+
 type UploadParts struct {
 	config      port.UploaderConfig
 	storage     port.StoragePart
 	storageMeta port.StorageMeta
+	guard       *MetaGuard
+	cleaner     port.StorageCleaner
+	envelope    *Envelope
+	metrics     port.UploadMetrics
+	tracer      port.Tracer
+	logger      port.Logger
 
 	partsComposer port.PartComposerRunner
 }
@@ -23,37 +36,78 @@ func ProvideUploadParts(
 	cfg port.UploaderConfig,
 	storage port.StoragePart,
 	storageMeta port.StorageMeta,
+	guard *MetaGuard,
+	cleaner port.StorageCleaner,
 	composer port.PartComposerRunner,
+	envelope *Envelope,
+	metrics port.UploadMetrics,
+	tracer port.Tracer,
+	logger port.Logger,
 ) *UploadParts {
 	up := new(UploadParts)
 	up.config = cfg
 	up.storage = storage
 	up.storageMeta = storageMeta
+	up.guard = guard
+	up.cleaner = cleaner
 	up.partsComposer = composer
+	up.envelope = envelope
+	up.metrics = metrics
+	up.tracer = tracer
+	up.logger = logger
 	return up
 }
 
-func (up *UploadParts) Handle(filename string, size int64, file io.ReadCloser) (isComplete bool, err error) {
+// Handle saves chunk of upload. Span of composing is linked to span of request with last chunk
+func (up *UploadParts) Handle(
+	ctx context.Context,
+	headers [][2]string,
+	secret string,
+	filename string,
+	size int64,
+	file io.ReadCloser,
+) (isComplete bool, err error) {
 	defer func() {
 		_ = file.Close()
 	}()
+	requestId := port.RequestIdFromContext(ctx)
+	logger := port.LoggerFromContext(ctx, up.logger)
+	ctx, span := up.tracer.Start(up.tracer.Extract(ctx, headers), "upload.part")
+	defer func() {
+		span.End(err)
+	}()
+	span.SetAttribute(uploadChunkAttribute, filename)
 
 	uuid, err := up.extractUuid(filename)
 	if err != nil {
 		return false, err
 	}
+	span.SetAttribute(uploadUuidAttribute, uuid)
+	logger = logger.With([2]string{port.LogFieldUuid, uuid}, [2]string{port.LogFieldPart, filename})
 	metaInfo, err := up.loadMeta(uuid)
 	if err != nil {
 		return false, err
 	}
-
+	if err = CheckUploadSecret(secret, metaInfo.GetSecretHash()); err != nil {
+		return false, err
+	}
 	if err = up.checkPart(filename, size, metaInfo); err != nil {
 		return false, err
 	}
 
-	if err = up.savePart(filename, size, file); err != nil {
+	var content io.Reader = file
+	if metaInfo.GetChunks()[filename].GetOffset() == 0 {
+		if content, err = up.sniffContentType(&metaInfo, file); err != nil {
+			return false, err
+		}
+	}
+
+	start := time.Now()
+	if err = up.savePart(filename, size, content, metaInfo); err != nil {
 		return false, err
 	}
+	up.metrics.ChunkReceived(size, time.Since(start))
+	logger.Trace().Println("UploadParts.Handle(): part is saved")
 
 	done, err := up.checkAllParts(metaInfo)
 	if err != nil {
@@ -61,11 +115,119 @@ func (up *UploadParts) Handle(filename string, size int64, file io.ReadCloser) (
 	}
 
 	if done {
-		up.partsComposer.Run(metaInfo)
+		// meta is reloaded, because type of file can be detected by request with first chunk
+		if metaInfo, err = up.loadMeta(uuid); err != nil {
+			return false, err
+		}
+		metaInfo.TraceParent = up.tracer.TraceParent(ctx)
+		metaInfo.RequestId = requestId
+		if err = up.partsComposer.Run(metaInfo); err != nil {
+			// the same last chunk is sent again, while upload is composed
+			logger.Error().Println(errors.Wrap(err, "UploadParts.Handle()"))
+		}
 	}
 	return done, nil
 }
 
+// sniffContentType detects type of file by first bytes of first chunk and saves only it in meta, meta could be
+// changed by replayed start since it was loaded. Upload is aborted if type is not allowed by global policy or policy from callbackBefore
+func (up *UploadParts) sniffContentType(metaInfo *dto.UploaderStartResult, file io.Reader) (io.Reader, error) {
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, exceptions.NewApiError(http.StatusBadRequest, err)
+	}
+	head = head[:n]
+	detected := http.DetectContentType(head)
+	if !up.config.GetContentPolicy().IsAllowed(detected) ||
+		(metaInfo.ContentPolicy != nil && !metaInfo.ContentPolicy.IsAllowed(detected)) {
+		if abortErr := up.removeUpload(*metaInfo); abortErr != nil {
+			return nil, abortErr
+		}
+		up.metrics.UploadFailed(uploadFailedContentType)
+		return nil, exceptions.NewApiError(http.StatusUnsupportedMediaType, errors.New("content type "+detected+" is not allowed"))
+	}
+	metaInfo.DetectedContentType = detected
+	_, err = up.guard.Update(metaInfo.GetUUID(), func(fresh *dto.UploaderStartResult) {
+		fresh.DetectedContentType = detected
+	})
+	if err != nil {
+		return nil, err
+	}
+	return io.MultiReader(bytes.NewReader(head), file), nil
+}
+
+// Status returns plan of upload with names of already uploaded chunks
+func (up *UploadParts) Status(ctx context.Context, secret string, uuid string) ([]byte, error) {
+	metaInfo, err := up.loadSession(secret, uuid)
+	if err != nil {
+		return nil, err
+	}
+	loaded, err := up.getLoadedParts(metaInfo)
+	if err != nil {
+		return nil, err
+	}
+	content, err := jsoniter.Marshal(dto.UploadStatus{UploaderStartResult: metaInfo, UploadedChunks: loaded})
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	return renderPlanResponse(content, "")
+}
+
+// Abort removes meta and already uploaded chunks of upload
+func (up *UploadParts) Abort(ctx context.Context, secret string, uuid string) error {
+	metaInfo, err := up.loadSession(secret, uuid)
+	if err != nil {
+		return err
+	}
+	if err = up.removeUpload(metaInfo); err != nil {
+		return err
+	}
+	up.metrics.UploadFailed(uploadFailedAborted)
+	port.LoggerFromContext(ctx, up.logger).With([2]string{port.LogFieldUuid, uuid}).Trace().Println("UploadParts.Abort(): upload is aborted")
+	return nil
+}
+
+func (up *UploadParts) removeUpload(metaInfo dto.UploaderStartResult) error {
+	if err := up.cleaner.RemoveMeta(MetaFileName(metaInfo.GetUUID())); err != nil {
+		return exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	loaded, err := up.getLoadedParts(metaInfo)
+	if err != nil {
+		return err
+	}
+	if err = up.cleaner.RemoveParts(loaded); err != nil {
+		return exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	return nil
+}
+
+func (up *UploadParts) loadSession(secret string, uuid string) (dto.UploaderStartResult, error) {
+	metaInfo, err := up.loadUpload(uuid)
+	if err != nil {
+		return metaInfo, err
+	}
+	if err = CheckUploadSecret(secret, metaInfo.GetSecretHash()); err != nil {
+		return metaInfo, err
+	}
+	return metaInfo, nil
+}
+
+// loadUpload returns meta of upload, which is in progress
+func (up *UploadParts) loadUpload(uuid string) (dto.UploaderStartResult, error) {
+	if !IsCorrectUuid(uuid) {
+		return dto.UploaderStartResult{}, exceptions.NewApiError(http.StatusBadRequest, errors.New("uuid must be correct uuid"))
+	}
+	metaInfo, err := up.loadMeta(uuid)
+	if err != nil {
+		if apiErr, ok := err.(exceptions.ApiError); ok && apiErr.GetCode() == http.StatusBadRequest {
+			return metaInfo, exceptions.NewApiError(http.StatusNotFound, errors.New("upload "+uuid+" not found"))
+		}
+		return metaInfo, err
+	}
+	return metaInfo, nil
+}
+
 func (up *UploadParts) extractUuid(filename string) (string, error) {
 	uuid, err := ExtractUuidFromPartName(filename)
 	if err != nil {
@@ -106,8 +268,18 @@ func (up *UploadParts) checkPart(filename string, filesize int64, metaInfo dto.U
 	return nil
 }
 
-func (up *UploadParts) savePart(filename string, filesize int64, file io.Reader) error {
-	err := up.storage.PutFilePart(filename, filesize, file)
+// savePart encrypts chunk by segments of file, if upload has data key of envelope encryption
+func (up *UploadParts) savePart(filename string, filesize int64, file io.Reader, metaInfo dto.UploaderStartResult) error {
+	encryption := metaInfo.GetLocation().Encryption
+	if dataKey := encryption.GetDataKey(); dataKey != "" {
+		offset := metaInfo.GetChunks()[filename].GetOffset()
+		encrypted, cipherSize, err := up.envelope.EncryptPart(dataKey, offset, filesize, metaInfo.GetSize(), file)
+		if err != nil {
+			return exceptions.NewApiError(http.StatusInternalServerError, err)
+		}
+		file, filesize = encrypted, cipherSize
+	}
+	err := up.storage.PutFilePart(filename, filesize, file, encryption)
 	if err != nil {
 		return exceptions.NewApiError(http.StatusInternalServerError, err)
 	}
@@ -115,23 +287,29 @@ func (up *UploadParts) savePart(filename string, filesize int64, file io.Reader)
 }
 
 func (up *UploadParts) checkAllParts(metaInfo dto.UploaderStartResult) (bool, error) {
-	list, err := up.storage.GetLoadedFilePartsNames(metaInfo.GetUUID())
+	loaded, err := up.getLoadedParts(metaInfo)
 	if err != nil {
-		return false, exceptions.NewApiError(http.StatusInternalServerError, err)
+		return false, err
 	}
-	mapList := make(map[string]bool, len(list))
-	for _, fn := range list {
-		mapList[fn] = true
+	if len(loaded) >= len(metaInfo.GetChunks()) {
+		return true, nil
+	}
+	return false, nil
+}
+
+// getLoadedParts returns sorted names of uploaded chunks of upload plan
+func (up *UploadParts) getLoadedParts(metaInfo dto.UploaderStartResult) ([]string, error) {
+	list, err := up.storage.GetLoadedFilePartsNames(metaInfo.GetUUID())
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
 	}
 	parts := metaInfo.GetChunks()
-	foundParts := 0
-	for partName := range parts {
-		if _, ok := mapList[partName]; ok {
-			foundParts += 1
+	result := make([]string, 0, len(list))
+	for _, fn := range list {
+		if _, ok := parts[fn]; ok {
+			result = append(result, fn)
 		}
 	}
-	if foundParts >= len(parts) {
-		return true, nil
-	}
-	return false, nil
+	sort.Strings(result)
+	return result, nil
 }
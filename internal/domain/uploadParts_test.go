@@ -2,14 +2,20 @@ package domain
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"github.com/satmaelstorm/filup/internal/domain/dto"
 	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
 	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
 	"github.com/stretchr/testify/suite"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 	"io"
 	"net/http"
 	"testing"
+	"time"
 )
 
 const testMeta = `{"uuid":"31991bd9-8064-11ec-829b-e4e7494803df","size":91,"user_tags":{"0":"test"},"chunks":{"31991bd9-8064-11ec-829b-e4e7494803df_part_0":{"size":91,"name":"31991bd9-8064-11ec-829b-e4e7494803df_part_0"}}}`
@@ -21,14 +27,17 @@ type clearMock interface {
 type fakePartsMetaStorage struct {
 	willReturn []byte
 	willError  error
+	saved      []byte
 }
 
 func (f *fakePartsMetaStorage) ClearMock() {
 	f.willReturn = nil
 	f.willError = nil
+	f.saved = nil
 }
 
 func (f *fakePartsMetaStorage) PutMetaFile(fileName string, content []byte) error {
+	f.saved = content
 	return f.willError
 }
 
@@ -39,6 +48,7 @@ func (f *fakePartsMetaStorage) GetMetaFile(fileName string) ([]byte, error) {
 type fakePartsPartStorage struct {
 	willReturn []string
 	willError  error
+	listed     int
 }
 
 func (f *fakePartsPartStorage) ClearMock() {
@@ -46,31 +56,92 @@ func (f *fakePartsPartStorage) ClearMock() {
 	f.willError = nil
 }
 
-func (f *fakePartsPartStorage) PutFilePart(fullPartName string, filesize int64, content io.Reader) error {
+func (f *fakePartsPartStorage) PutFilePart(fullPartName string, filesize int64, content io.Reader, encryption *dto.Encryption) error {
 	return f.willError
 }
 
 func (f *fakePartsPartStorage) GetLoadedFilePartsNames(fileName string) ([]string, error) {
+	f.listed++
 	return f.willReturn, f.willError
 }
 
 type fakePartsComposerRunner struct {
-	hasRun bool
+	hasRun    bool
+	composed  bool
+	willError error
 }
 
-func (f *fakePartsComposerRunner) Run(metaInfo dto.UploaderStartResult) {
+func (f *fakePartsComposerRunner) Run(metaInfo dto.UploaderStartResult) error {
+	if f.willError != nil {
+		return f.willError
+	}
 	f.hasRun = true
+	return nil
+}
+
+func (f *fakePartsComposerRunner) Compose(metaInfo dto.UploaderStartResult) error {
+	if f.willError != nil {
+		return f.willError
+	}
+	f.composed = true
+	return nil
 }
 
 func (f *fakePartsComposerRunner) ClearMock() {
 	f.hasRun = false
+	f.composed = false
+}
+
+type fakeUploadMetrics struct {
+	failed []string
+}
+
+func (f *fakeUploadMetrics) ClearMock() {
+	f.failed = nil
+}
+
+func (f *fakeUploadMetrics) UploadStarted() {}
+
+func (f *fakeUploadMetrics) UploadCompleted() {}
+
+func (f *fakeUploadMetrics) UploadFailed(reason string) {
+	f.failed = append(f.failed, reason)
+}
+
+func (f *fakeUploadMetrics) ChunkReceived(size int64, duration time.Duration) {}
+
+func (f *fakeUploadMetrics) ComposeDone(duration time.Duration) {}
+
+func (f *fakeUploadMetrics) ComposeQueueDepth(depth int) {}
+
+func (f *fakeUploadMetrics) CallbackDone(callbackType string, outcome string, duration time.Duration) {
 }
 
+type fakeTracer struct{}
+
+func (f fakeTracer) Extract(ctx context.Context, headers [][2]string) context.Context {
+	return ctx
+}
+
+func (f fakeTracer) Start(ctx context.Context, name string, links ...string) (context.Context, port.Span) {
+	return ctx, fakeSpan{}
+}
+
+func (f fakeTracer) TraceParent(ctx context.Context) string {
+	return ""
+}
+
+type fakeSpan struct{}
+
+func (f fakeSpan) SetAttribute(key string, value string) {}
+
+func (f fakeSpan) End(err error) {}
+
 type fakeReadCloser struct {
 }
 
 func (f *fakeReadCloser) Read(p []byte) (n int, err error) {
-	return 0, nil
+	return 0, io.EOF
 }
 
 func (f *fakeReadCloser) Close() error {
@@ -92,12 +163,20 @@ func (s *suiteUploadParts) SetupSuite() {
 		ChunkLength:    1024 * 1024 * 5,
 		CallbackBefore: "http://localhost",
 	}.AfterLoad()
+	loggers := logsEngine.InitLoggersEmpty("test")
 
+	metaStorage := new(fakePartsMetaStorage)
 	s.up = ProvideUploadParts(
 		cfg,
 		new(fakePartsPartStorage),
-		new(fakePartsMetaStorage),
+		metaStorage,
+		ProvideMetaGuard(metaStorage),
+		new(fakeStorageCleaner),
 		new(fakePartsComposerRunner),
+		ProvideEnvelope(config.EnvelopeConfig{}),
+		new(fakeUploadMetrics),
+		fakeTracer{},
+		&loggers,
 	)
 }
 
@@ -105,6 +184,7 @@ func (s *suiteUploadParts) TearDownTest() {
 	s.up.storageMeta.(clearMock).ClearMock()
 	s.up.storage.(clearMock).ClearMock()
 	s.up.partsComposer.(clearMock).ClearMock()
+	s.up.metrics.(clearMock).ClearMock()
 }
 
 func (s *suiteUploadParts) TestExtractUuid() {
@@ -190,11 +270,11 @@ func (s *suiteUploadParts) TestCheckPart() {
 func (s *suiteUploadParts) TestSavePart() {
 	buf := new(bytes.Reader)
 	filename := ChunkFileName("31991bd9-8064-11ec-829b-e4e7494803df", 0)
-	err := s.up.savePart(filename, 91, buf)
+	err := s.up.savePart(filename, 91, buf, dto.UploaderStartResult{})
 	s.Nil(err)
 
 	s.up.storage.(*fakePartsPartStorage).willError = errors.New("object size must be provided with disable multipart upload")
-	err = s.up.savePart(filename, 91, buf)
+	err = s.up.savePart(filename, 91, buf, dto.UploaderStartResult{})
 	s.NotNil(err)
 }
 
@@ -235,7 +315,7 @@ func (s *suiteUploadParts) TestHandleNotComlete() {
 	s.up.storageMeta.(*fakePartsMetaStorage).willReturn = []byte(testMeta)
 	s.up.storage.(*fakePartsPartStorage).willReturn = []string{}
 
-	complete, err := s.up.Handle(ChunkFileName(uuid, 0), 91, new(fakeReadCloser))
+	complete, err := s.up.Handle(context.Background(), nil, "", ChunkFileName(uuid, 0), 91, new(fakeReadCloser))
 	s.Require().Nil(err)
 	s.False(complete)
 }
@@ -245,7 +325,95 @@ func (s *suiteUploadParts) TestHandleComlete() {
 	s.up.storageMeta.(*fakePartsMetaStorage).willReturn = []byte(testMeta)
 	s.up.storage.(*fakePartsPartStorage).willReturn = []string{ChunkFileName(uuid, 0)}
 
-	complete, err := s.up.Handle(ChunkFileName(uuid, 0), 91, new(fakeReadCloser))
+	complete, err := s.up.Handle(context.Background(), nil, "", ChunkFileName(uuid, 0), 91, new(fakeReadCloser))
 	s.Require().Nil(err)
 	s.True(complete)
 }
+
+func (s *suiteUploadParts) TestHandleSecret() {
+	uuid := "31991bd9-8064-11ec-829b-e4e7494803df"
+	secret, secretHash, err := NewUploadSecret()
+	s.Require().Nil(err)
+	s.up.storageMeta.(*fakePartsMetaStorage).willReturn = []byte(testMeta[:len(testMeta)-1] + `,"secret_hash":"` + secretHash + `"}`)
+
+	_, err = s.up.Handle(context.Background(), nil, "", ChunkFileName(uuid, 0), 91, new(fakeReadCloser))
+	s.Require().NotNil(err)
+	e, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusUnauthorized, e.GetCode())
+
+	_, err = s.up.Handle(context.Background(), nil, "wrong", ChunkFileName(uuid, 0), 91, new(fakeReadCloser))
+	s.Require().NotNil(err)
+	e, ok = err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusForbidden, e.GetCode())
+
+	_, err = s.up.Handle(context.Background(), nil, secret, ChunkFileName(uuid, 0), 91, new(fakeReadCloser))
+	s.Require().Nil(err)
+}
+
+func (s *suiteUploadParts) TestStatusAndAbort() {
+	uuid := "31991bd9-8064-11ec-829b-e4e7494803df"
+	_, err := s.up.Status(context.Background(), "", uuid)
+	s.Require().NotNil(err)
+	e, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusNotFound, e.GetCode())
+
+	s.up.storageMeta.(*fakePartsMetaStorage).willReturn = []byte(testMeta)
+	s.up.storage.(*fakePartsPartStorage).willReturn = []string{ChunkFileName(uuid, 0), "unknown"}
+	content, err := s.up.Status(context.Background(), "", uuid)
+	s.Require().Nil(err)
+	s.Equal(`["`+ChunkFileName(uuid, 0)+`"]`, gjson.GetBytes(content, "uploaded_chunks").Raw)
+	s.False(gjson.GetBytes(content, "secret_hash").Exists())
+
+	cleaner := new(fakeStorageCleaner)
+	s.up.cleaner = cleaner
+	s.Require().Nil(s.up.Abort(context.Background(), "", uuid))
+	s.Equal([]string{MetaFileName(uuid)}, cleaner.removedMeta)
+}
+
+func (s *suiteUploadParts) TestSniffContentType() {
+	uuid := "31991bd9-8064-11ec-829b-e4e7494803df"
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 83)...)
+	s.up.storageMeta.(*fakePartsMetaStorage).willReturn = []byte(testMeta)
+	metaInfo, err := s.up.loadMeta(uuid)
+	s.Require().Nil(err)
+	metaInfo.ContentPolicy = &dto.ContentPolicy{Allow: []string{"image/"}}
+	// secret is rotated by replayed start after meta was loaded
+	rotated, err := sjson.SetBytes([]byte(testMeta), "secret_hash", "rotated")
+	s.Require().Nil(err)
+	s.up.storageMeta.(*fakePartsMetaStorage).willReturn = rotated
+
+	content, err := s.up.sniffContentType(&metaInfo, bytes.NewReader(png))
+	s.Require().Nil(err)
+	s.Equal("image/png", metaInfo.DetectedContentType)
+	s.Equal("image/png", metaInfo.GetFileContentType())
+	saved, err := io.ReadAll(content)
+	s.Require().Nil(err)
+	s.Equal(png, saved)
+	stored := s.up.storageMeta.(*fakePartsMetaStorage).saved
+	s.Equal("rotated", gjson.GetBytes(stored, "secret_hash").String())
+	s.Equal("image/png", gjson.GetBytes(stored, "detected_content_type").String())
+
+	cleaner := new(fakeStorageCleaner)
+	s.up.cleaner = cleaner
+	_, err = s.up.sniffContentType(&metaInfo, bytes.NewReader([]byte("#!/bin/sh\nrm -rf /")))
+	s.Require().NotNil(err)
+	e, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(http.StatusUnsupportedMediaType, e.GetCode())
+	s.Equal([]string{MetaFileName(uuid)}, cleaner.removedMeta)
+}
+
+func (s *suiteUploadParts) TestContentPolicy() {
+	s.True(dto.ContentPolicy{}.IsAllowed("application/octet-stream"))
+	policy := dto.ContentPolicy{Allow: []string{"image/", "application/pdf"}, Deny: []string{"image/svg"}}
+	s.True(policy.IsAllowed("image/png"))
+	s.True(policy.IsAllowed("Application/PDF"))
+	s.False(policy.IsAllowed("image/svg+xml"))
+	s.False(policy.IsAllowed("text/plain; charset=utf-8"))
+	policy = dto.ContentPolicy{Deny: []string{"application/octet-stream"}}
+	s.False(policy.IsAllowed("application/octet-stream"))
+	s.True(policy.IsAllowed("text/plain; charset=utf-8"))
+}
@@ -0,0 +1,368 @@
+package domain
+
+import (
+	"context"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	adminUploadsDefaultLimit = 100
+	adminUploadsMaxLimit     = 1000
+	// adminUploadsMaxScan limits names of meta files, which are listed by one page, meta of files is listed too
+	adminUploadsMaxScan = 10 * adminUploadsMaxLimit
+)
+
+// UploadsAdmin handles admin api for operating uploads, which are in progress, and undelivered callbacks
+type UploadsAdmin struct {
+	adminCfg port.AdminConfig
+	cfg      port.UploaderConfig
+	parts    *UploadParts
+	composer port.PartComposerRunner
+	records  *FileRecords
+	failures *FailedCallbacks
+	lister   port.StorageMetaLister
+	cache    port.MetaCacheController
+	poster   port.Poster
+	metrics  port.UploadMetrics
+	logger   port.Logger
+	ctx      context.Context
+}
+
+func ProvideUploadsAdmin(
+	ctxProvider port.ContextProvider,
+	adminCfg port.AdminConfig,
+	cfg port.UploaderConfig,
+	parts *UploadParts,
+	composer port.PartComposerRunner,
+	records *FileRecords,
+	failures *FailedCallbacks,
+	lister port.StorageMetaLister,
+	cache port.MetaCacheController,
+	poster port.Poster,
+	metrics port.UploadMetrics,
+	logger port.Logger,
+) *UploadsAdmin {
+	return &UploadsAdmin{
+		adminCfg: adminCfg,
+		cfg:      cfg,
+		parts:    parts,
+		composer: composer,
+		records:  records,
+		failures: failures,
+		lister:   lister,
+		cache:    cache,
+		poster:   poster,
+		metrics:  metrics,
+		logger:   logger,
+		ctx:      ctxProvider.Ctx(),
+	}
+}
+
+// ListUploads returns page of uploads from meta storage, args are limit and marker, which is next_marker of previous page
+func (ua *UploadsAdmin) ListUploads(headers [][2]string, args [][2]string) ([]byte, error) {
+	if err := CheckAdminToken(ua.adminCfg, headers); err != nil {
+		return nil, err
+	}
+	marker, limit := "", adminUploadsDefaultLimit
+	for _, arg := range args {
+		switch arg[0] {
+		case "marker":
+			marker = arg[1]
+		case "limit":
+			var err error
+			if limit, err = strconv.Atoi(arg[1]); err != nil {
+				limit = 0
+			}
+		}
+	}
+	if limit < 1 || limit > adminUploadsMaxLimit {
+		return nil, exceptions.NewApiError(http.StatusBadRequest, errors.New("limit must be from 1 to "+strconv.Itoa(adminUploadsMaxLimit)))
+	}
+	page, err := ua.Uploads(marker, limit)
+	if err != nil {
+		return nil, err
+	}
+	return ua.render(page)
+}
+
+// GetUpload returns upload with counts of received and missing chunks
+func (ua *UploadsAdmin) GetUpload(headers [][2]string, uuid string) ([]byte, error) {
+	if err := CheckAdminToken(ua.adminCfg, headers); err != nil {
+		return nil, err
+	}
+	upload, err := ua.Upload(uuid)
+	if err != nil {
+		return nil, err
+	}
+	return ua.render(upload)
+}
+
+// Uploads returns page of uploads, which are in progress, ordered by uuid. Names of meta files are listed
+// by pages after marker, page can have less than limit uploads, listing is finished, when NextMarker is empty
+func (ua *UploadsAdmin) Uploads(marker string, limit int) (dto.AdminUploadsPage, error) {
+	page := dto.AdminUploadsPage{Items: make([]dto.AdminUpload, 0), Limit: limit}
+	for scanned := 0; scanned < adminUploadsMaxScan; {
+		names, err := ua.lister.ListMetaFiles("", marker, limit)
+		if err != nil {
+			return page, exceptions.NewApiError(http.StatusInternalServerError, err)
+		}
+		for _, name := range names {
+			marker = name
+			uuid := strings.TrimSuffix(name, metaFilenamePiece)
+			if uuid == name || !IsCorrectUuid(uuid) {
+				continue
+			}
+			metaInfo, err := ua.parts.loadUpload(uuid)
+			if apiErr, ok := err.(exceptions.ApiError); ok && apiErr.GetCode() == http.StatusNotFound {
+				// upload was composed or aborted after listing
+				continue
+			}
+			if err != nil {
+				return page, err
+			}
+			page.Items = append(page.Items, adminUpload(metaInfo))
+			if len(page.Items) >= limit {
+				page.NextMarker = name
+				return page, nil
+			}
+		}
+		if len(names) < limit {
+			return page, nil
+		}
+		scanned += len(names)
+	}
+	page.NextMarker = marker
+	return page, nil
+}
+
+// Upload returns state of one upload, which is in progress
+func (ua *UploadsAdmin) Upload(uuid string) (dto.AdminUploadState, error) {
+	metaInfo, err := ua.parts.loadUpload(uuid)
+	if err != nil {
+		return dto.AdminUploadState{}, err
+	}
+	loaded, err := ua.parts.getLoadedParts(metaInfo)
+	if err != nil {
+		return dto.AdminUploadState{}, err
+	}
+	return dto.AdminUploadState{
+		AdminUpload:    adminUpload(metaInfo),
+		ReceivedChunks: len(loaded),
+		MissingChunks:  len(metaInfo.GetChunks()) - len(loaded),
+	}, nil
+}
+
+// Compose sends upload to composer, for example if instance was stopped before composing. All chunks are required
+func (ua *UploadsAdmin) Compose(ctx context.Context, headers [][2]string, uuid string) error {
+	if err := CheckAdminToken(ua.adminCfg, headers); err != nil {
+		return err
+	}
+	metaInfo, err := ua.completeUpload(ctx, uuid)
+	if err != nil {
+		return err
+	}
+	if err = ua.composer.Run(metaInfo); err != nil {
+		return composeError(err)
+	}
+	ua.requestLogger(ctx, uuid).Critical().Println("UploadsAdmin.Compose(): upload is sent to composer")
+	return nil
+}
+
+// ComposeUpload composes upload and waits for result
+func (ua *UploadsAdmin) ComposeUpload(ctx context.Context, uuid string) error {
+	metaInfo, err := ua.completeUpload(ctx, uuid)
+	if err != nil {
+		return err
+	}
+	if err = ua.composer.Compose(metaInfo); err != nil {
+		return composeError(err)
+	}
+	ua.requestLogger(ctx, uuid).Critical().Println("UploadsAdmin.ComposeUpload(): upload is composed")
+	return nil
+}
+
+// Expire removes stale upload with its chunks, it is counted as aborted
+func (ua *UploadsAdmin) Expire(ctx context.Context, headers [][2]string, uuid string) error {
+	metaInfo, err := ua.loadUpload(headers, uuid)
+	if err != nil {
+		return err
+	}
+	if err = ua.parts.removeUpload(metaInfo); err != nil {
+		return err
+	}
+	ua.metrics.UploadFailed(uploadFailedAborted)
+	ua.requestLogger(ctx, uuid).Critical().Println("UploadsAdmin.Expire(): upload is expired")
+	return nil
+}
+
+// AbortUpload removes upload with its chunks as aborted, upload secret is not required
+func (ua *UploadsAdmin) AbortUpload(ctx context.Context, uuid string) error {
+	metaInfo, err := ua.parts.loadUpload(uuid)
+	if err != nil {
+		return err
+	}
+	if err = ua.parts.removeUpload(metaInfo); err != nil {
+		return err
+	}
+	ua.metrics.UploadFailed(uploadFailedAborted)
+	ua.requestLogger(ctx, uuid).Critical().Println("UploadsAdmin.AbortUpload(): upload is aborted")
+	return nil
+}
+
+// ResendCallbackAfter sends kept body of callbackAfter of composed file once more
+func (ua *UploadsAdmin) ResendCallbackAfter(ctx context.Context, headers [][2]string, uuid string) error {
+	if err := CheckAdminToken(ua.adminCfg, headers); err != nil {
+		return err
+	}
+	callbackAfter := ua.cfg.GetCallbackAfter()
+	if callbackAfter == nil {
+		return exceptions.NewApiError(http.StatusConflict, errors.New("callbackAfter is not configured"))
+	}
+	body, err := ua.records.LoadCallbackAfter(uuid)
+	if err != nil {
+		return err
+	}
+	if err = ua.post(callbackTypeAfter, callbackAfter, body); err != nil {
+		return err
+	}
+	ua.requestLogger(ctx, uuid).Critical().Println("UploadsAdmin.ResendCallbackAfter(): callbackAfter is resent")
+	return nil
+}
+
+func (ua *UploadsAdmin) ListFailedCallbacks(headers [][2]string) ([]byte, error) {
+	if err := CheckAdminToken(ua.adminCfg, headers); err != nil {
+		return nil, err
+	}
+	callbacks, err := ua.FailedCallbacks()
+	if err != nil {
+		return nil, err
+	}
+	return ua.render(callbacks)
+}
+
+// FailedCallbacks returns undelivered callbacks ordered by time of last failure
+func (ua *UploadsAdmin) FailedCallbacks() ([]dto.FailedCallback, error) {
+	return ua.failures.List()
+}
+
+// ReplayCallback sends failed callback once. Delivered callback is removed, otherwise its errors are updated
+func (ua *UploadsAdmin) ReplayCallback(ctx context.Context, headers [][2]string, id string) error {
+	if err := CheckAdminToken(ua.adminCfg, headers); err != nil {
+		return err
+	}
+	return ua.ReplayFailedCallback(ctx, id)
+}
+
+// ReplayFailedCallback sends failed callback once without authorization
+func (ua *UploadsAdmin) ReplayFailedCallback(ctx context.Context, id string) error {
+	callback, err := ua.failures.Load(id)
+	if err != nil {
+		return err
+	}
+	callbackUrl, err := url.Parse(callback.Url)
+	if err != nil {
+		return exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	logger := port.LoggerFromContext(ctx, ua.logger)
+	if postErr := ua.post(callback.Type, callbackUrl, callback.Body); postErr != nil {
+		callback.Attempts++
+		callback.Errors = append(callback.Errors, postErr.Error())
+		callback.FailedAt = time.Now().Unix()
+		if err = ua.failures.Save(callback); err != nil {
+			logger.Error().Println(err)
+		}
+		return postErr
+	}
+	if err = ua.failures.Remove(id); err != nil {
+		return exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	logger.Critical().Println("UploadsAdmin.ReplayCallback(): callback " + id + " is delivered")
+	return nil
+}
+
+// FlushCache drops all entries of meta cache, so meta is read from storage
+func (ua *UploadsAdmin) FlushCache(ctx context.Context, headers [][2]string) error {
+	if err := CheckAdminToken(ua.adminCfg, headers); err != nil {
+		return err
+	}
+	ua.cache.Purge()
+	port.LoggerFromContext(ctx, ua.logger).Critical().Println("UploadsAdmin.FlushCache(): meta cache is flushed")
+	return nil
+}
+
+// completeUpload loads upload, which has all chunks
+func (ua *UploadsAdmin) completeUpload(ctx context.Context, uuid string) (dto.UploaderStartResult, error) {
+	metaInfo, err := ua.parts.loadUpload(uuid)
+	if err != nil {
+		return dto.UploaderStartResult{}, err
+	}
+	loaded, err := ua.parts.getLoadedParts(metaInfo)
+	if err != nil {
+		return dto.UploaderStartResult{}, err
+	}
+	if missing := len(metaInfo.GetChunks()) - len(loaded); missing > 0 {
+		return dto.UploaderStartResult{}, exceptions.NewApiError(http.StatusConflict, errors.New("upload "+uuid+" has "+strconv.Itoa(missing)+" missing chunks"))
+	}
+	metaInfo.RequestId = port.RequestIdFromContext(ctx)
+	return metaInfo, nil
+}
+
+func adminUpload(metaInfo dto.UploaderStartResult) dto.AdminUpload {
+	return dto.AdminUpload{
+		Uuid:        metaInfo.GetUUID(),
+		Size:        metaInfo.GetSize(),
+		FileName:    metaInfo.FileName,
+		Tenant:      metaInfo.Tenant,
+		Owner:       metaInfo.Owner,
+		CreatedAt:   metaInfo.CreatedAt,
+		ChunksCount: len(metaInfo.GetChunks()),
+	}
+}
+
+// composeError - upload, which is already composed by another request, is conflict
+func composeError(err error) error {
+	if errors.Is(err, port.ErrAlreadyComposing) {
+		return exceptions.NewApiError(http.StatusConflict, err)
+	}
+	return exceptions.NewApiError(http.StatusInternalServerError, err)
+}
+
+func (ua *UploadsAdmin) loadUpload(headers [][2]string, uuid string) (dto.UploaderStartResult, error) {
+	if err := CheckAdminToken(ua.adminCfg, headers); err != nil {
+		return dto.UploaderStartResult{}, err
+	}
+	// upload secret is not required for admin
+	return ua.parts.loadUpload(uuid)
+}
+
+func (ua *UploadsAdmin) post(callbackType string, callback *url.URL, body []byte) error {
+	_, code, err := postCallback(ua.ctx, ua.poster, ua.metrics, ua.cfg, callbackType, *callback, body)
+	if err != nil {
+		return exceptions.NewApiError(http.StatusBadGateway, errors.Wrap(err, "Post error"))
+	}
+	if code < 200 || code > 299 {
+		return exceptions.NewApiError(http.StatusBadGateway, errors.New("callback responded with code "+strconv.Itoa(code)))
+	}
+	return nil
+}
+
+func (ua *UploadsAdmin) requestLogger(ctx context.Context, uuid string) port.Logger {
+	return port.LoggerFromContext(ctx, ua.logger).With([2]string{port.LogFieldUuid, uuid})
+}
+
+func (ua *UploadsAdmin) render(value interface{}) ([]byte, error) {
+	content, err := jsoniter.Marshal(value)
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	return content, nil
+}
@@ -0,0 +1,276 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/stretchr/testify/suite"
+	"github.com/tidwall/gjson"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAdminMetaStorage keeps meta files in memory
+type fakeAdminMetaStorage struct {
+	files map[string][]byte
+}
+
+func (f *fakeAdminMetaStorage) PutMetaFile(fileName string, content []byte) error {
+	f.files[fileName] = content
+	return nil
+}
+
+func (f *fakeAdminMetaStorage) GetMetaFile(fileName string) ([]byte, error) {
+	return f.files[fileName], nil
+}
+
+func (f *fakeAdminMetaStorage) ListMetaFiles(prefix string, marker string, limit int) ([]string, error) {
+	var result []string
+	for name := range f.files {
+		if strings.HasPrefix(name, prefix) && name > marker {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (f *fakeAdminMetaStorage) RemoveMeta(fileName string) error {
+	delete(f.files, fileName)
+	return nil
+}
+
+func (f *fakeAdminMetaStorage) RemoveParts(partsNames []string) error {
+	return nil
+}
+
+type fakeMetaCache struct {
+	purged bool
+}
+
+func (f *fakeMetaCache) Add(key string, value []byte) {}
+
+func (f *fakeMetaCache) Get(key string) ([]byte, bool) {
+	return nil, false
+}
+
+func (f *fakeMetaCache) Delete(key string) {}
+
+func (f *fakeMetaCache) Purge() {
+	f.purged = true
+}
+
+type suiteUploadsAdmin struct {
+	suite.Suite
+	meta     *fakeAdminMetaStorage
+	parts    *fakePartsPartStorage
+	composer *fakePartsComposerRunner
+	cache    *fakeMetaCache
+	failures *FailedCallbacks
+	admin    [][2]string
+	uid      string
+}
+
+func TestUploadsAdmin(t *testing.T) {
+	suite.Run(t, new(suiteUploadsAdmin))
+}
+
+func (s *suiteUploadsAdmin) SetupTest() {
+	s.meta = &fakeAdminMetaStorage{files: make(map[string][]byte)}
+	s.parts = new(fakePartsPartStorage)
+	s.composer = new(fakePartsComposerRunner)
+	s.cache = new(fakeMetaCache)
+	s.failures = ProvideFailedCallbacks(s.meta, s.meta, s.meta, ProvideUuidProvider(),
+		config.Uploader{FailedCallbacksTtl: 3600}.AfterLoad())
+	s.admin = [][2]string{{"X-Admin-Token", "admin"}}
+	s.uid = ProvideUuidProvider().NewUuid()
+	s.meta.files[MetaFileName(s.uid)] = []byte(`{"uuid":"` + s.uid + `","size":20,"secret_hash":"hash","chunks":{` +
+		`"` + ChunkFileName(s.uid, 0) + `":{"size":10,"name":"` + ChunkFileName(s.uid, 0) + `"},` +
+		`"` + ChunkFileName(s.uid, 1) + `":{"size":10,"name":"` + ChunkFileName(s.uid, 1) + `","offset":10}}}`)
+	s.meta.files[FileRecordName(ProvideUuidProvider().NewUuid())] = []byte(`{}`)
+	s.parts.willReturn = []string{ChunkFileName(s.uid, 0)}
+}
+
+func (s *suiteUploadsAdmin) makeAdmin(poster fakePoster) *UploadsAdmin {
+	loggers := logsEngine.InitLoggersEmpty("test")
+	cfg := config.Uploader{ChunkLength: 10, CallbackAfter: "http://localhost/after"}.AfterLoad()
+	parts := ProvideUploadParts(cfg, s.parts, s.meta, ProvideMetaGuard(s.meta), s.meta, s.composer, ProvideEnvelope(config.EnvelopeConfig{}),
+		new(fakeUploadMetrics), fakeTracer{}, &loggers)
+	return ProvideUploadsAdmin(fakeContextProvider{}, config.AdminConfig{Token: "admin"}, cfg, parts, s.composer,
+		ProvideFileRecords(s.meta), s.failures, s.meta, s.cache, poster, new(fakeUploadMetrics), &loggers)
+}
+
+func (s *suiteUploadsAdmin) assertCode(err error, code int) {
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(code, apiErr.GetCode())
+}
+
+func (s *suiteUploadsAdmin) TestListUploads() {
+	ua := s.makeAdmin(fakePoster{retCode: http.StatusOK})
+	_, err := ua.ListUploads(nil, nil)
+	s.assertCode(err, http.StatusUnauthorized)
+	_, err = ua.ListUploads(s.admin, [][2]string{{"limit", "0"}})
+	s.assertCode(err, http.StatusBadRequest)
+
+	content, err := ua.ListUploads(s.admin, nil)
+	s.Require().Nil(err)
+	uploads := gjson.GetBytes(content, "items").Array()
+	s.Require().Len(uploads, 1)
+	s.Equal(s.uid, uploads[0].Get("uuid").String())
+	s.Equal(int64(2), uploads[0].Get("chunks_count").Int())
+	s.False(uploads[0].Get("received_chunks").Exists())
+	s.False(gjson.GetBytes(content, "next_marker").Exists())
+	// chunks in storage are listed only for one upload
+	s.Equal(0, s.parts.listed)
+
+	content, err = ua.GetUpload(s.admin, s.uid)
+	s.Require().Nil(err)
+	s.Equal(int64(1), gjson.GetBytes(content, "received_chunks").Int())
+	s.Equal(int64(1), gjson.GetBytes(content, "missing_chunks").Int())
+}
+
+func (s *suiteUploadsAdmin) TestUploadsPages() {
+	ua := s.makeAdmin(fakePoster{retCode: http.StatusOK})
+	expected := []string{s.uid}
+	for i := 0; i < 4; i++ {
+		uid := ProvideUuidProvider().NewUuid()
+		s.meta.files[MetaFileName(uid)] = []byte(`{"uuid":"` + uid + `","size":1}`)
+		s.meta.files[FileRecordName(uid)] = []byte(`{}`)
+		expected = append(expected, uid)
+	}
+	sort.Strings(expected)
+
+	var listed []string
+	marker := ""
+	for pages := 0; pages < 10; pages++ {
+		page, err := ua.Uploads(marker, 2)
+		s.Require().Nil(err)
+		s.LessOrEqual(len(page.Items), 2)
+		for _, upload := range page.Items {
+			listed = append(listed, upload.Uuid)
+		}
+		if marker = page.NextMarker; marker == "" {
+			break
+		}
+	}
+	s.Equal(expected, listed)
+}
+
+func (s *suiteUploadsAdmin) TestComposeAndExpire() {
+	ua := s.makeAdmin(fakePoster{retCode: http.StatusOK})
+	s.assertCode(ua.Compose(context.Background(), s.admin, s.uid), http.StatusConflict)
+	s.False(s.composer.hasRun)
+
+	s.parts.willReturn = []string{ChunkFileName(s.uid, 0), ChunkFileName(s.uid, 1)}
+	s.Require().Nil(ua.Compose(context.Background(), s.admin, s.uid))
+	s.True(s.composer.hasRun)
+
+	s.composer.willError = port.ErrAlreadyComposing
+	s.assertCode(ua.Compose(context.Background(), s.admin, s.uid), http.StatusConflict)
+	s.assertCode(ua.ComposeUpload(context.Background(), s.uid), http.StatusConflict)
+	s.composer.willError = nil
+
+	s.Require().Nil(ua.Expire(context.Background(), s.admin, s.uid))
+	s.assertCode(ua.Expire(context.Background(), s.admin, s.uid), http.StatusNotFound)
+}
+
+func (s *suiteUploadsAdmin) TestResendCallbackAfter() {
+	ua := s.makeAdmin(fakePoster{retCode: http.StatusOK})
+	s.assertCode(ua.ResendCallbackAfter(context.Background(), s.admin, s.uid), http.StatusNotFound)
+	s.meta.files[CallbackAfterName(s.uid)] = []byte(`{"uuid":"` + s.uid + `"}`)
+	s.Require().Nil(ua.ResendCallbackAfter(context.Background(), s.admin, s.uid))
+
+	ua = s.makeAdmin(fakePoster{retCode: http.StatusInternalServerError})
+	s.assertCode(ua.ResendCallbackAfter(context.Background(), s.admin, s.uid), http.StatusBadGateway)
+}
+
+func (s *suiteUploadsAdmin) TestReplayFailedCallback() {
+	loggers := logsEngine.InitLoggersEmpty("test")
+	cfg := config.Uploader{HttpRetries: 2}.AfterLoad()
+	callback := cfg.GetCallbackBefore()
+	s.Require().Nil(callback)
+	after := config.Uploader{CallbackAfter: "http://localhost/after"}.AfterLoad().GetCallbackAfter()
+	delivered := postWithRetries(context.Background(), fakePoster{retErr: errors.New("refused")}, new(fakeUploadMetrics), cfg,
+		&loggers, s.failures, callbackTypeAfter, "CallbackAfter", after, []byte(`{"uuid":"`+s.uid+`"}`))
+	s.False(delivered)
+
+	ua := s.makeAdmin(fakePoster{retCode: http.StatusServiceUnavailable})
+	content, err := ua.ListFailedCallbacks(s.admin)
+	s.Require().Nil(err)
+	failed := gjson.ParseBytes(content).Array()
+	s.Require().Len(failed, 1)
+	s.Equal(callbackTypeAfter, failed[0].Get("type").String())
+	s.Equal(s.uid, failed[0].Get("body.uuid").String())
+	s.Equal(int64(2), failed[0].Get("attempts").Int())
+	id := failed[0].Get("id").String()
+
+	s.assertCode(ua.ReplayCallback(context.Background(), s.admin, id), http.StatusBadGateway)
+	callbacks, err := s.failures.List()
+	s.Require().Nil(err)
+	s.Require().Len(callbacks, 1)
+	s.Equal(3, callbacks[0].Attempts)
+
+	ua = s.makeAdmin(fakePoster{retCode: http.StatusOK})
+	s.Require().Nil(ua.ReplayCallback(context.Background(), s.admin, id))
+	callbacks, err = s.failures.List()
+	s.Require().Nil(err)
+	s.Empty(callbacks)
+	s.assertCode(ua.ReplayCallback(context.Background(), s.admin, id), http.StatusNotFound)
+}
+
+func (s *suiteUploadsAdmin) TestFailedCallbacksRetention() {
+	old := ProvideUuidProvider().NewUuid()
+	content := []byte(`{"id":"` + old + `","type":"after","failed_at":` + strconv.FormatInt(time.Now().Add(-2*time.Hour).Unix(), 10) + `}`)
+	s.meta.files[FailedCallbackName(old)] = content
+
+	callbacks, err := s.failures.List()
+	s.Require().Nil(err)
+	s.Empty(callbacks)
+
+	s.Require().Nil(s.failures.Save(dto.FailedCallback{Type: callbackTypeAfter, FailedAt: time.Now().Unix()}))
+	_, found := s.meta.files[FailedCallbackName(old)]
+	s.False(found)
+	callbacks, err = s.failures.List()
+	s.Require().Nil(err)
+	s.Len(callbacks, 1)
+}
+
+func (s *suiteUploadsAdmin) TestFlushCache() {
+	ua := s.makeAdmin(fakePoster{retCode: http.StatusOK})
+	s.assertCode(ua.FlushCache(context.Background(), nil), http.StatusUnauthorized)
+	s.False(s.cache.purged)
+	s.Require().Nil(ua.FlushCache(context.Background(), s.admin))
+	s.True(s.cache.purged)
+}
+
+func (s *suiteUploadsAdmin) TestComposeUploadAndAbort() {
+	ua := s.makeAdmin(fakePoster{retCode: http.StatusOK})
+	s.assertCode(ua.ComposeUpload(context.Background(), s.uid), http.StatusConflict)
+	s.False(s.composer.composed)
+
+	upload, err := ua.Upload(s.uid)
+	s.Require().Nil(err)
+	s.Equal(1, upload.MissingChunks)
+
+	s.parts.willReturn = []string{ChunkFileName(s.uid, 0), ChunkFileName(s.uid, 1)}
+	s.Require().Nil(ua.ComposeUpload(context.Background(), s.uid))
+	s.True(s.composer.composed)
+	s.False(s.composer.hasRun)
+
+	s.Require().Nil(ua.AbortUpload(context.Background(), s.uid))
+	_, err = ua.Upload(s.uid)
+	s.assertCode(err, http.StatusNotFound)
+}
@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signedExpiresArg     = "expires"
+	signedIpArg          = "ip"
+	signedDispositionArg = "disposition"
+	signedSignatureArg   = "signature"
+	adminTokenHeader     = "X-Admin-Token"
+	downloadPath         = "/download/"
+)
+
+type UrlSigner struct {
+	cfg      port.SignedUrlConfig
+	adminCfg port.AdminConfig
+	now      func() time.Time
+}
+
+func ProvideUrlSigner(cfg port.SignedUrlConfig, adminCfg port.AdminConfig) *UrlSigner {
+	return &UrlSigner{cfg: cfg, adminCfg: adminCfg, now: time.Now}
+}
+
+// Sign handles admin API request, which is authorized by admin token
+func (us *UrlSigner) Sign(headers [][2]string, body []byte) ([]byte, error) {
+	if err := CheckAdminToken(us.adminCfg, headers); err != nil {
+		return nil, err
+	}
+	var req dto.SignUrlRequest
+	if err := jsoniter.Unmarshal(body, &req); err != nil {
+		return nil, exceptions.NewApiError(http.StatusBadRequest, err)
+	}
+	signed, err := us.SignUrl(req)
+	if err != nil {
+		return nil, err
+	}
+	content, err := jsoniter.Marshal(signed)
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusInternalServerError, err)
+	}
+	return content, nil
+}
+
+// SignUrl makes download url, which is valid until expiration without callbackDownload
+func (us *UrlSigner) SignUrl(req dto.SignUrlRequest) (dto.SignedUrl, error) {
+	if len(us.cfg.GetSignSecret()) == 0 {
+		return dto.SignedUrl{}, exceptions.NewApiError(http.StatusForbidden, errors.New("signed urls are disabled"))
+	}
+	if !IsCorrectUuid(req.Uuid) {
+		return dto.SignedUrl{}, exceptions.NewApiError(http.StatusBadRequest, errors.New("uuid must be correct uuid"))
+	}
+	ttl := us.cfg.GetDefaultTtl()
+	if req.Ttl > 0 {
+		ttl = time.Duration(req.Ttl) * time.Second
+	}
+	if maxTtl := us.cfg.GetMaxTtl(); maxTtl > 0 && ttl > maxTtl {
+		return dto.SignedUrl{}, exceptions.NewApiError(http.StatusBadRequest, errors.New("ttl must not exceed "+maxTtl.String()))
+	}
+	expiresAt := us.now().Add(ttl).Unix()
+	expires := strconv.FormatInt(expiresAt, 10)
+	query := url.Values{}
+	query.Set(signedExpiresArg, expires)
+	if req.Ip != "" {
+		query.Set(signedIpArg, req.Ip)
+	}
+	if req.Disposition != "" {
+		query.Set(signedDispositionArg, req.Disposition)
+	}
+	query.Set(signedSignatureArg, us.signature(req.Uuid, expires, req.Ip, req.Disposition))
+	return dto.SignedUrl{
+		Url:     strings.TrimRight(us.cfg.GetBaseUrl(), "/") + downloadPath + req.Uuid + "?" + query.Encode(),
+		Expires: expiresAt,
+	}, nil
+}
+
+// Verify checks signature of download url. Url without signature is not signed and is allowed to be checked by callbacks
+func (us *UrlSigner) Verify(uuid string, args [][2]string, headers [][2]string, remoteIp string) (dto.DownloadOptions, error) {
+	signature := findArg(args, signedSignatureArg)
+	if signature == "" {
+		return dto.DownloadOptions{}, nil
+	}
+	forbidden := func(msg string) (dto.DownloadOptions, error) {
+		return dto.DownloadOptions{}, exceptions.NewApiError(http.StatusForbidden, errors.New(msg))
+	}
+	if len(us.cfg.GetSignSecret()) == 0 {
+		return forbidden("signed urls are disabled")
+	}
+	expires := findArg(args, signedExpiresArg)
+	ip := findArg(args, signedIpArg)
+	disposition := findArg(args, signedDispositionArg)
+	if !hmac.Equal([]byte(signature), []byte(us.signature(uuid, expires, ip, disposition))) {
+		return forbidden("invalid signature")
+	}
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || us.now().Unix() > expiresUnix {
+		return forbidden("url is expired")
+	}
+	if ip != "" && ip != us.clientIp(headers, remoteIp) {
+		return forbidden("url is signed for other ip")
+	}
+	return dto.DownloadOptions{Signed: true, Disposition: disposition}, nil
+}
+
+func (us *UrlSigner) clientIp(headers [][2]string, remoteIp string) string {
+	if ipHeader := us.cfg.GetIpHeader(); ipHeader != "" {
+		if ip := findHeader(headers, ipHeader); ip != "" {
+			return strings.TrimSpace(strings.Split(ip, ",")[0])
+		}
+	}
+	return remoteIp
+}
+
+func (us *UrlSigner) signature(uuid, expires, ip, disposition string) string {
+	mac := hmac.New(sha256.New, us.cfg.GetSignSecret())
+	mac.Write([]byte(strings.Join([]string{uuid, expires, ip, disposition}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func findArg(args [][2]string, name string) string {
+	for _, arg := range args {
+		if arg[0] == name {
+			return arg[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/stretchr/testify/suite"
+	"github.com/tidwall/gjson"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type suiteUrlSigner struct {
+	suite.Suite
+	signer *UrlSigner
+	uid    string
+}
+
+func TestUrlSigner(t *testing.T) {
+	suite.Run(t, new(suiteUrlSigner))
+}
+
+func (s *suiteUrlSigner) SetupTest() {
+	s.signer = ProvideUrlSigner(
+		config.SignedUrlsConfig{
+			Secret:     "secret",
+			BaseUrl:    "https://files.example.com/",
+			DefaultTtl: 60,
+			MaxTtl:     3600,
+			IpHeader:   "X-Forwarded-For",
+		},
+		config.AdminConfig{Token: "admin"},
+	)
+	s.signer.now = func() time.Time {
+		return time.Unix(1000, 0)
+	}
+	s.uid = ProvideUuidProvider().NewUuid()
+}
+
+func (s *suiteUrlSigner) parseArgs(signedUrl string) [][2]string {
+	u, err := url.Parse(signedUrl)
+	s.Require().Nil(err)
+	var args [][2]string
+	for name, values := range u.Query() {
+		args = append(args, [2]string{name, values[0]})
+	}
+	return args
+}
+
+func (s *suiteUrlSigner) assertCode(err error, code int) {
+	s.Require().NotNil(err)
+	apiErr, ok := err.(exceptions.ApiError)
+	s.Require().True(ok)
+	s.Equal(code, apiErr.GetCode())
+}
+
+func (s *suiteUrlSigner) TestSignAndVerify() {
+	signed, err := s.signer.SignUrl(dto.SignUrlRequest{Uuid: s.uid, Ip: "10.0.0.1", Disposition: "attachment"})
+	s.Require().Nil(err)
+	s.Equal(int64(1060), signed.Expires)
+	s.Contains(signed.Url, "https://files.example.com/download/"+s.uid+"?")
+	args := s.parseArgs(signed.Url)
+
+	options, err := s.signer.Verify(s.uid, args, [][2]string{{"X-Forwarded-For", "10.0.0.1, 10.0.0.2"}}, "127.0.0.1")
+	s.Require().Nil(err)
+	s.True(options.IsSigned())
+	s.Equal("attachment", options.GetDisposition())
+
+	_, err = s.signer.Verify(s.uid, args, nil, "127.0.0.1")
+	s.assertCode(err, http.StatusForbidden)
+
+	_, err = s.signer.Verify(ProvideUuidProvider().NewUuid(), args, nil, "10.0.0.1")
+	s.assertCode(err, http.StatusForbidden)
+
+	s.signer.now = func() time.Time {
+		return time.Unix(1061, 0)
+	}
+	_, err = s.signer.Verify(s.uid, args, nil, "10.0.0.1")
+	s.assertCode(err, http.StatusForbidden)
+
+	options, err = s.signer.Verify(s.uid, nil, nil, "10.0.0.1")
+	s.Require().Nil(err)
+	s.False(options.IsSigned())
+}
+
+func (s *suiteUrlSigner) TestSignTtl() {
+	_, err := s.signer.SignUrl(dto.SignUrlRequest{Uuid: s.uid, Ttl: 3601})
+	s.assertCode(err, http.StatusBadRequest)
+	_, err = s.signer.SignUrl(dto.SignUrlRequest{Uuid: "incorrect"})
+	s.assertCode(err, http.StatusBadRequest)
+}
+
+func (s *suiteUrlSigner) TestSignApi() {
+	body := []byte(`{"uuid":"` + s.uid + `","ttl":120}`)
+	_, err := s.signer.Sign(nil, body)
+	s.assertCode(err, http.StatusUnauthorized)
+
+	content, err := s.signer.Sign([][2]string{{"x-admin-token", "admin"}}, body)
+	s.Require().Nil(err)
+	s.Equal(int64(1120), gjson.GetBytes(content, "expires").Int())
+
+	s.signer.adminCfg = config.AdminConfig{}
+	_, err = s.signer.Sign([][2]string{{"X-Admin-Token", ""}}, body)
+	s.assertCode(err, http.StatusForbidden)
+}
@@ -0,0 +1,144 @@
+package antivirus
+
+import (
+	"bufio"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	instreamCommand = "zINSTREAM\x00"
+	foundSuffix     = " FOUND"
+	errorSuffix     = " ERROR"
+	okSuffix        = " OK"
+)
+
+// ClamdScanner sends file to clamd with INSTREAM command
+type ClamdScanner struct {
+	network   string
+	address   string
+	timeout   time.Duration
+	chunkSize int
+	maxLength int64
+}
+
+func ProvideClamdScanner(cfg config.Configuration) (*ClamdScanner, error) {
+	result := &ClamdScanner{
+		timeout:   cfg.Antivirus.GetTimeout(),
+		chunkSize: cfg.Antivirus.ChunkSize,
+		maxLength: cfg.Antivirus.StreamMaxLength,
+	}
+	if cfg.Antivirus.Address == "" {
+		return result, nil
+	}
+	address, err := url.Parse(cfg.Antivirus.Address)
+	if err != nil {
+		return nil, errors.Wrap(err, "ClamdScanner.ParseAddress")
+	}
+	switch address.Scheme {
+	case "tcp":
+		result.network, result.address = "tcp", address.Host
+	case "unix":
+		result.network, result.address = "unix", address.Path
+	default:
+		return nil, errors.New("ClamdScanner: unsupported address scheme " + address.Scheme)
+	}
+	return result, nil
+}
+
+func (c *ClamdScanner) IsEnabled() bool {
+	return c.network != ""
+}
+
+func (c *ClamdScanner) Scan(stream io.Reader) (dto.ScanVerdict, error) {
+	conn, err := net.DialTimeout(c.network, c.address, c.timeout)
+	if err != nil {
+		return dto.ScanVerdict{}, errors.Wrap(err, "ClamdScanner.Dial")
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if err = c.sendStream(conn, stream); err != nil {
+		return dto.ScanVerdict{}, err
+	}
+	if err = c.extendDeadline(conn); err != nil {
+		return dto.ScanVerdict{}, err
+	}
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return dto.ScanVerdict{}, errors.Wrap(err, "ClamdScanner.ReadReply")
+	}
+	return parseReply(reply)
+}
+
+// extendDeadline - timeout limits every read and write, so large file is not limited by total time of scan
+func (c *ClamdScanner) extendDeadline(conn net.Conn) error {
+	if c.timeout <= 0 {
+		return nil
+	}
+	return errors.Wrap(conn.SetDeadline(time.Now().Add(c.timeout)), "ClamdScanner.SetDeadline")
+}
+
+// sendStream stops before stream exceeds StreamMaxLength of clamd, which would be rejected by clamd anyway
+func (c *ClamdScanner) sendStream(conn net.Conn, stream io.Reader) error {
+	if err := c.extendDeadline(conn); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(conn, instreamCommand); err != nil {
+		return errors.Wrap(err, "ClamdScanner.WriteCommand")
+	}
+	buf := make([]byte, 4+c.chunkSize)
+	var sent int64
+	for {
+		n, err := io.ReadFull(stream, buf[4:])
+		if n > 0 {
+			sent += int64(n)
+			if c.maxLength > 0 && sent > c.maxLength {
+				return errors.New("ClamdScanner: file is larger than antivirus.streamMaxLength " + strconv.FormatInt(c.maxLength, 10))
+			}
+			if derr := c.extendDeadline(conn); derr != nil {
+				return derr
+			}
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				return errors.Wrap(werr, "ClamdScanner.WriteChunk")
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "ClamdScanner.ReadStream")
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return errors.Wrap(err, "ClamdScanner.WriteEnd")
+	}
+	return nil
+}
+
+// parseReply parses replies like "stream: OK", "stream: Eicar-Signature FOUND", "INSTREAM size limit exceeded. ERROR"
+func parseReply(reply string) (dto.ScanVerdict, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	switch {
+	case strings.HasSuffix(reply, foundSuffix):
+		signature := strings.TrimSuffix(reply, foundSuffix)
+		if pos := strings.Index(signature, ": "); pos >= 0 {
+			signature = signature[pos+2:]
+		}
+		return dto.ScanVerdict{Status: dto.ScanStatusInfected, Signature: signature}, nil
+	case strings.HasSuffix(reply, okSuffix):
+		return dto.ScanVerdict{Status: dto.ScanStatusClean}, nil
+	case strings.HasSuffix(reply, errorSuffix):
+		return dto.ScanVerdict{}, errors.New("ClamdScanner: " + strings.TrimSuffix(reply, errorSuffix))
+	}
+	return dto.ScanVerdict{}, errors.New("ClamdScanner: unexpected reply " + reply)
+}
@@ -0,0 +1,182 @@
+package antivirus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd reads INSTREAM command and replies with reply, received stream is sent to received
+type fakeClamd struct {
+	listener net.Listener
+	reply    string
+	received chan []byte
+}
+
+func newFakeClamd(reply string) (*fakeClamd, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	f := &fakeClamd{listener: listener, reply: reply, received: make(chan []byte, 1)}
+	go f.serve()
+	return f, nil
+}
+
+func (f *fakeClamd) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		f.handle(conn)
+	}
+}
+
+func (f *fakeClamd) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	reader := bufio.NewReader(conn)
+	command := make([]byte, len(instreamCommand))
+	if _, err := io.ReadFull(reader, command); err != nil || string(command) != instreamCommand {
+		return
+	}
+	received := new(bytes.Buffer)
+	size := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(reader, size); err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint32(size)
+		if n == 0 {
+			break
+		}
+		if _, err := io.CopyN(received, reader, int64(n)); err != nil {
+			return
+		}
+	}
+	f.received <- received.Bytes()
+	_, _ = io.WriteString(conn, f.reply+"\x00")
+}
+
+func (f *fakeClamd) address() string {
+	return "tcp://" + f.listener.Addr().String()
+}
+
+type suiteClamd struct {
+	suite.Suite
+}
+
+func TestClamd(t *testing.T) {
+	suite.Run(t, new(suiteClamd))
+}
+
+func (s *suiteClamd) makeScanner(address string, maxLength int64) *ClamdScanner {
+	var cfg config.Configuration
+	cfg.Antivirus = config.AntivirusConfig{Address: address, Timeout: 5, ChunkSize: 4, StreamMaxLength: maxLength}.AfterLoad()
+	scanner, err := ProvideClamdScanner(cfg)
+	s.Require().Nil(err)
+	return scanner
+}
+
+func (s *suiteClamd) TestParseReply() {
+	verdict, err := parseReply("stream: OK\x00")
+	s.Require().Nil(err)
+	s.Equal(dto.ScanStatusClean, verdict.Status)
+
+	verdict, err = parseReply("stream: Eicar-Test-Signature FOUND\n")
+	s.Require().Nil(err)
+	s.True(verdict.IsInfected())
+	s.Equal("Eicar-Test-Signature", verdict.GetSignature())
+
+	_, err = parseReply("INSTREAM size limit exceeded. ERROR")
+	s.Require().NotNil(err)
+	s.Contains(err.Error(), "size limit exceeded")
+
+	_, err = parseReply("UNKNOWN COMMAND")
+	s.NotNil(err)
+	_, err = parseReply("")
+	s.NotNil(err)
+}
+
+func (s *suiteClamd) TestProvide() {
+	s.False(s.makeScanner("", 0).IsEnabled())
+	scanner := s.makeScanner("unix:///var/run/clamav/clamd.ctl", 0)
+	s.True(scanner.IsEnabled())
+	s.Equal("unix", scanner.network)
+	s.Equal("/var/run/clamav/clamd.ctl", scanner.address)
+
+	var cfg config.Configuration
+	cfg.Antivirus = config.AntivirusConfig{Address: "http://localhost:3310"}.AfterLoad()
+	_, err := ProvideClamdScanner(cfg)
+	s.NotNil(err)
+}
+
+func (s *suiteClamd) TestScan() {
+	clamd, err := newFakeClamd("stream: Eicar-Test-Signature FOUND")
+	s.Require().Nil(err)
+	defer func() {
+		_ = clamd.listener.Close()
+	}()
+	content := "0123456789"
+	verdict, err := s.makeScanner(clamd.address(), 0).Scan(strings.NewReader(content))
+	s.Require().Nil(err)
+	s.True(verdict.IsInfected())
+	s.Equal("Eicar-Test-Signature", verdict.GetSignature())
+	s.Equal(content, string(<-clamd.received))
+}
+
+func (s *suiteClamd) TestStreamMaxLength() {
+	clamd, err := newFakeClamd("stream: OK")
+	s.Require().Nil(err)
+	defer func() {
+		_ = clamd.listener.Close()
+	}()
+	verdict, err := s.makeScanner(clamd.address(), 8).Scan(strings.NewReader("01234567"))
+	s.Require().Nil(err)
+	s.Equal(dto.ScanStatusClean, verdict.Status)
+	s.Equal("01234567", string(<-clamd.received))
+
+	_, err = s.makeScanner(clamd.address(), 8).Scan(strings.NewReader("012345678"))
+	s.Require().NotNil(err)
+	s.Contains(err.Error(), "streamMaxLength")
+}
+
+func (s *suiteClamd) TestDeadlineIsExtended() {
+	client, server := net.Pipe()
+	defer func() {
+		_ = client.Close()
+	}()
+	go func() {
+		_, _ = io.Copy(io.Discard, server)
+	}()
+	scanner := &ClamdScanner{timeout: 50 * time.Millisecond, chunkSize: 1}
+	// every chunk is read slower than half of timeout, the whole stream is longer than timeout
+	err := scanner.sendStream(client, &slowReader{content: []byte("0123"), delay: 20 * time.Millisecond})
+	s.Nil(err)
+	_ = server.Close()
+}
+
+type slowReader struct {
+	content []byte
+	delay   time.Duration
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	if len(r.content) == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(r.delay)
+	n := copy(p, r.content)
+	r.content = r.content[n:]
+	return n, nil
+}
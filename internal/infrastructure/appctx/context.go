@@ -19,8 +19,14 @@ func (c *CoreContext) Cancel() {
 
 func ProvideContext() *CoreContext {
 	if nil == cc {
-		cc = new(CoreContext)
-		cc.ctx, cc.cancel = context.WithCancel(context.Background())
+		cc = NewContext(context.Background())
 	}
 	return cc
 }
+
+// NewContext makes context, which is not shared, it is cancelled with parent
+func NewContext(parent context.Context) *CoreContext {
+	c := new(CoreContext)
+	c.ctx, c.cancel = context.WithCancel(parent)
+	return c
+}
@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"github.com/golang-jwt/jwt/v4"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"math/big"
+	"os"
+	"strings"
+)
+
+var validMethods = []string{
+	jwt.SigningMethodHS256.Alg(),
+	jwt.SigningMethodRS256.Alg(),
+	jwt.SigningMethodES256.Alg(),
+}
+
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	MaxSize int64             `json:"max_size"`
+	Uuid    string            `json:"uuid"`
+	Tags    map[string]string `json:"tags"`
+	// Scope - space separated scopes like in OAuth 2.0
+	Scope string `json:"scope"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+type verificationKey struct {
+	kid string
+	key interface{}
+}
+
+type JwtVerifier struct {
+	keys   []verificationKey
+	parser *jwt.Parser
+}
+
+func ProvideJwtVerifier(cfg config.Configuration) (*JwtVerifier, error) {
+	result := &JwtVerifier{parser: jwt.NewParser(jwt.WithValidMethods(validMethods))}
+	if cfg.Auth.Jwt.Secret != "" {
+		result.keys = append(result.keys, verificationKey{key: []byte(cfg.Auth.Jwt.Secret)})
+	}
+	if cfg.Auth.Jwt.JwksFile != "" {
+		keys, err := loadJwks(cfg.Auth.Jwt.JwksFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "JwtVerifier.loadJwks")
+		}
+		result.keys = append(result.keys, keys...)
+	}
+	return result, nil
+}
+
+func (v *JwtVerifier) IsEnabled() bool {
+	return len(v.keys) > 0
+}
+
+func (v *JwtVerifier) Verify(token string) (dto.TokenClaims, error) {
+	claims := new(tokenClaims)
+	_, err := v.parser.ParseWithClaims(token, claims, v.findKey)
+	if err != nil {
+		return dto.TokenClaims{}, err
+	}
+	if claims.ExpiresAt == nil {
+		return dto.TokenClaims{}, errors.New("token has no exp claim")
+	}
+	return dto.TokenClaims{
+		Subject: claims.Subject,
+		MaxSize: claims.MaxSize,
+		Uuid:    claims.Uuid,
+		Tags:    claims.Tags,
+		Scopes:  strings.Fields(claims.Scope),
+	}, nil
+}
+
+// findKey selects key by kid from token header, if token has no kid - first key suitable for token algorithm is used
+func (v *JwtVerifier) findKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	for _, k := range v.keys {
+		if kid != "" && k.kid != kid {
+			continue
+		}
+		if isKeySuitable(token.Method, k.key) {
+			return k.key, nil
+		}
+	}
+	return nil, errors.New("no key for token with kid '" + kid + "' and alg " + token.Method.Alg())
+}
+
+func isKeySuitable(method jwt.SigningMethod, key interface{}) bool {
+	switch key.(type) {
+	case []byte:
+		return method == jwt.SigningMethodHS256
+	case *rsa.PublicKey:
+		return method == jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		return method == jwt.SigningMethodES256
+	}
+	return false
+}
+
+func loadJwks(fileName string) ([]verificationKey, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err = jsoniter.Unmarshal(content, &jwks); err != nil {
+		return nil, err
+	}
+	result := make([]verificationKey, 0, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, errors.Wrap(err, "key "+jwk.Kid)
+		}
+		result = append(result, verificationKey{kid: jwk.Kid, key: key})
+	}
+	return result, nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "oct":
+		return decodeSegment(k.K)
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, errors.New("unsupported curve " + k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, errors.New("unsupported key type " + k.Kty)
+}
+
+func decodeSegment(value string) ([]byte, error) {
+	if value == "" {
+		return nil, errors.New("empty key parameter")
+	}
+	return base64.RawURLEncoding.DecodeString(value)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := decodeSegment(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
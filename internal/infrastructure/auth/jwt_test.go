@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"github.com/golang-jwt/jwt/v4"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-secret"
+
+type suiteJwtVerifier struct {
+	suite.Suite
+	verifier *JwtVerifier
+}
+
+func TestJwtVerifier(t *testing.T) {
+	suite.Run(t, new(suiteJwtVerifier))
+}
+
+func (s *suiteJwtVerifier) SetupTest() {
+	var cfg config.Configuration
+	cfg.Auth.Jwt.Secret = testSecret
+	verifier, err := ProvideJwtVerifier(cfg)
+	s.Require().Nil(err)
+	s.verifier = verifier
+}
+
+func (s *suiteJwtVerifier) sign(method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	s.Require().Nil(err)
+	return signed
+}
+
+func (s *suiteJwtVerifier) TestVerify() {
+	s.True(s.verifier.IsEnabled())
+	token := s.sign(jwt.SigningMethodHS256, []byte(testSecret), "", jwt.MapClaims{
+		"sub":      "user1",
+		"exp":      time.Now().Add(time.Minute).Unix(),
+		"max_size": 100,
+		"uuid":     "c1a6d9a4-5b8e-4e0a-9f2b-3c4d5e6f7a8b",
+		"tags":     map[string]string{"project": "alpha"},
+		"scope":    "upload  download",
+	})
+	claims, err := s.verifier.Verify(token)
+	s.Require().Nil(err)
+	s.Equal("user1", claims.GetSubject())
+	s.Equal(int64(100), claims.GetMaxSize())
+	s.Equal("c1a6d9a4-5b8e-4e0a-9f2b-3c4d5e6f7a8b", claims.GetUuid())
+	s.Equal("alpha", claims.GetTags()["project"])
+	s.Equal([]string{"upload", "download"}, claims.Scopes)
+	s.True(claims.HasScope("download"))
+	s.False(claims.HasScope("admin"))
+}
+
+func (s *suiteJwtVerifier) TestRejected() {
+	valid := jwt.MapClaims{"exp": time.Now().Add(time.Minute).Unix(), "scope": "upload"}
+
+	_, err := s.verifier.Verify(s.sign(jwt.SigningMethodHS256, []byte(testSecret), "", jwt.MapClaims{"scope": "upload"}))
+	s.NotNil(err, "token without exp")
+
+	_, err = s.verifier.Verify(s.sign(jwt.SigningMethodHS256, []byte(testSecret), "",
+		jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}))
+	s.NotNil(err, "expired token")
+
+	_, err = s.verifier.Verify(s.sign(jwt.SigningMethodHS256, []byte("other"), "", valid))
+	s.NotNil(err, "wrong secret")
+
+	_, err = s.verifier.Verify(s.sign(jwt.SigningMethodHS384, []byte(testSecret), "", valid))
+	s.NotNil(err, "not allowed algorithm")
+
+	_, err = s.verifier.Verify(s.sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", valid))
+	s.NotNil(err, "unsigned token")
+
+	_, err = s.verifier.Verify("not a token")
+	s.NotNil(err)
+}
+
+func (s *suiteJwtVerifier) TestJwks() {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().Nil(err)
+	encode := base64.RawURLEncoding.EncodeToString
+	jwks, err := jsoniter.Marshal(map[string]interface{}{"keys": []jsonWebKey{
+		{Kty: "EC", Kid: "ec1", Crv: "P-256", X: encode(ecKey.X.Bytes()), Y: encode(ecKey.Y.Bytes())},
+		{Kty: "oct", Kid: "oct1", K: encode([]byte("jwks-secret"))},
+	}})
+	s.Require().Nil(err)
+	fileName := filepath.Join(s.T().TempDir(), "jwks.json")
+	s.Require().Nil(os.WriteFile(fileName, jwks, 0600))
+
+	var cfg config.Configuration
+	cfg.Auth.Jwt.JwksFile = fileName
+	verifier, err := ProvideJwtVerifier(cfg)
+	s.Require().Nil(err)
+
+	claims := jwt.MapClaims{"sub": "user1", "exp": time.Now().Add(time.Minute).Unix()}
+	result, err := verifier.Verify(s.sign(jwt.SigningMethodES256, ecKey, "ec1", claims))
+	s.Require().Nil(err)
+	s.Equal("user1", result.GetSubject())
+
+	_, err = verifier.Verify(s.sign(jwt.SigningMethodES256, ecKey, "", claims))
+	s.Nil(err, "key without kid is selected by algorithm")
+
+	_, err = verifier.Verify(s.sign(jwt.SigningMethodHS256, []byte("jwks-secret"), "oct1", claims))
+	s.Nil(err)
+
+	_, err = verifier.Verify(s.sign(jwt.SigningMethodES256, ecKey, "unknown", claims))
+	s.NotNil(err)
+
+	_, err = verifier.Verify(s.sign(jwt.SigningMethodHS256, []byte("jwks-secret"), "ec1", claims))
+	s.NotNil(err, "key of other algorithm")
+}
+
+func (s *suiteJwtVerifier) TestLoadJwksErrors() {
+	dir := s.T().TempDir()
+	for name, content := range map[string]string{
+		"curve.json": `{"keys":[{"kty":"EC","crv":"P-384","x":"AQ","y":"AQ"}]}`,
+		"type.json":  `{"keys":[{"kty":"OKP"}]}`,
+		"empty.json": `{"keys":[{"kty":"oct","k":""}]}`,
+		"json.json":  `{`,
+	} {
+		fileName := filepath.Join(dir, name)
+		s.Require().Nil(os.WriteFile(fileName, []byte(content), 0600))
+		_, err := loadJwks(fileName)
+		s.NotNil(err, name)
+	}
+	_, err := loadJwks(filepath.Join(dir, "absent.json"))
+	s.NotNil(err)
+}
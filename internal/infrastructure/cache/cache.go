@@ -2,10 +2,19 @@ package cache
 
 import (
 	lru "github.com/hashicorp/golang-lru"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/satmaelstorm/filup/internal/infrastructure/config"
 	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/satmaelstorm/filup/internal/infrastructure/metrics"
 )
 
+// cacheRequests - hit ratio of meta cache is hit / (hit + miss)
+var cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: config.ProjectMetricsNamespace,
+	Subsystem: "meta_cache",
+	Name:      "requests_count",
+}, []string{"result"})
+
 type Cache struct {
 	controller  *lru.Cache
 	errorLogger logsEngine.ILogger
@@ -19,6 +28,7 @@ func ProvideMetaCache(
 	if err != nil {
 		return nil, err
 	}
+	metrics.Register(cacheRequests)
 	return &Cache{controller: c, errorLogger: logger}, nil
 }
 
@@ -30,11 +40,17 @@ func (c *Cache) Delete(key string) {
 	c.controller.Remove(key)
 }
 
+func (c *Cache) Purge() {
+	c.controller.Purge()
+}
+
 func (c *Cache) Get(key string) ([]byte, bool) {
 	v, ok := c.controller.Get(key)
 	if !ok {
+		cacheRequests.WithLabelValues("miss").Inc()
 		return nil, false
 	}
+	cacheRequests.WithLabelValues("hit").Inc()
 	if nil == v {
 		return nil, true
 	}
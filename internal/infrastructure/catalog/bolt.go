@@ -0,0 +1,208 @@
+package catalog
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	bolt "go.etcd.io/bbolt"
+	"time"
+)
+
+const openTimeout = time.Second * 5
+
+var (
+	filesBucket       = []byte("files")
+	filesByTimeBucket = []byte("files_by_time")
+)
+
+type BoltCatalog struct {
+	db *bolt.DB
+}
+
+var boltCatalog *BoltCatalog
+
+func ProvideBoltCatalog(cfg config.Configuration, cc port.ContextProvider, logger logsEngine.ILogger) (*BoltCatalog, error) {
+	if nil == boltCatalog {
+		result, err := NewBoltCatalog(cfg, cc, logger)
+		if err != nil {
+			return nil, err
+		}
+		boltCatalog = result
+	}
+	return boltCatalog, nil
+}
+
+// NewBoltCatalog opens catalog, which is not shared, database is closed when context is done
+func NewBoltCatalog(cfg config.Configuration, cc port.ContextProvider, logger logsEngine.ILogger) (*BoltCatalog, error) {
+	result := new(BoltCatalog)
+	if cfg.Catalog.Path == "" {
+		return result, nil
+	}
+	db, err := bolt.Open(cfg.Catalog.Path, 0600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, errors.Wrap(err, "BoltCatalog.Open")
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{filesBucket, filesByTimeBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "BoltCatalog.CreateBuckets")
+	}
+	result.db = db
+	go func() {
+		<-cc.Ctx().Done()
+		if err := db.Close(); err != nil {
+			logger.Error().Println(errors.Wrap(err, "BoltCatalog.Close"))
+		}
+	}()
+	return result, nil
+}
+
+func (b *BoltCatalog) IsEnabled() bool {
+	return b.db != nil
+}
+
+func (b *BoltCatalog) Put(entry dto.CatalogEntry) error {
+	content, err := jsoniter.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "BoltCatalog.Put")
+	}
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		files := tx.Bucket(filesBucket)
+		byTime := tx.Bucket(filesByTimeBucket)
+		if old := files.Get([]byte(entry.GetUUID())); old != nil {
+			var oldEntry dto.CatalogEntry
+			if err := jsoniter.Unmarshal(old, &oldEntry); err == nil {
+				if err := byTime.Delete(timeKey(oldEntry)); err != nil {
+					return err
+				}
+			}
+		}
+		if err := files.Put([]byte(entry.GetUUID()), content); err != nil {
+			return err
+		}
+		return byTime.Put(timeKey(entry), []byte(entry.GetUUID()))
+	})
+	return errors.Wrap(err, "BoltCatalog.Put")
+}
+
+func (b *BoltCatalog) Get(uuid string) (dto.CatalogEntry, bool, error) {
+	var entry dto.CatalogEntry
+	found := false
+	err := b.db.View(func(tx *bolt.Tx) error {
+		content := tx.Bucket(filesBucket).Get([]byte(uuid))
+		if content == nil {
+			return nil
+		}
+		found = true
+		return jsoniter.Unmarshal(content, &entry)
+	})
+	if err != nil {
+		return dto.CatalogEntry{}, false, errors.Wrap(err, "BoltCatalog.Get")
+	}
+	return entry, found, nil
+}
+
+func (b *BoltCatalog) Delete(uuid string) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		files := tx.Bucket(filesBucket)
+		content := files.Get([]byte(uuid))
+		if content == nil {
+			return nil
+		}
+		var entry dto.CatalogEntry
+		if err := jsoniter.Unmarshal(content, &entry); err == nil {
+			if err := tx.Bucket(filesByTimeBucket).Delete(timeKey(entry)); err != nil {
+				return err
+			}
+		}
+		return files.Delete([]byte(uuid))
+	})
+	return errors.Wrap(err, "BoltCatalog.Delete")
+}
+
+// List returns entries from newest to oldest by completion time. Page ends when Limit entries are found
+// or MaxScan entries are read, NextCursor continues listing after the last read entry
+func (b *BoltCatalog) List(filter dto.CatalogFilter) (dto.CatalogPage, error) {
+	page := dto.CatalogPage{Limit: filter.Limit}
+	from, err := decodeCursor(filter.Cursor)
+	if err != nil {
+		return dto.CatalogPage{}, err
+	}
+	err = b.db.View(func(tx *bolt.Tx) error {
+		files := tx.Bucket(filesBucket)
+		cursor := tx.Bucket(filesByTimeBucket).Cursor()
+		scanned := 0
+		var k, uuid []byte
+		if from == nil {
+			k, uuid = cursor.Last()
+		} else if k, _ = cursor.Seek(from); k == nil {
+			k, uuid = cursor.Last()
+		} else {
+			k, uuid = cursor.Prev()
+		}
+		for ; k != nil; k, uuid = cursor.Prev() {
+			// file is created before it is completed, so older entries can't match
+			if filter.CreatedFrom > 0 && int64(binary.BigEndian.Uint64(k)) < filter.CreatedFrom {
+				return nil
+			}
+			if len(page.Items) >= filter.Limit || (filter.MaxScan > 0 && scanned >= filter.MaxScan) {
+				page.NextCursor = hex.EncodeToString(lastKey(cursor))
+				return nil
+			}
+			scanned++
+			content := files.Get(uuid)
+			if content == nil {
+				continue
+			}
+			var entry dto.CatalogEntry
+			if err := jsoniter.Unmarshal(content, &entry); err != nil {
+				return err
+			}
+			if !filter.Matches(entry) {
+				continue
+			}
+			page.Items = append(page.Items, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return dto.CatalogPage{}, errors.Wrap(err, "BoltCatalog.List")
+	}
+	return page, nil
+}
+
+// decodeCursor returns key, before which listing starts
+func decodeCursor(cursor string) ([]byte, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	from, err := hex.DecodeString(cursor)
+	if err != nil || len(from) < 8 {
+		return nil, port.ErrInvalidCatalogCursor
+	}
+	return from, nil
+}
+
+// lastKey returns key of entry, which was read before current position of cursor
+func lastKey(cursor *bolt.Cursor) []byte {
+	k, _ := cursor.Next()
+	return k
+}
+
+func timeKey(entry dto.CatalogEntry) []byte {
+	key := make([]byte, 8, 8+len(entry.GetUUID()))
+	binary.BigEndian.PutUint64(key, uint64(entry.GetCompletedAt()))
+	return append(key, entry.GetUUID()...)
+}
@@ -0,0 +1,152 @@
+package catalog
+
+import (
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/stretchr/testify/suite"
+	bolt "go.etcd.io/bbolt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+type suiteBoltCatalog struct {
+	suite.Suite
+	dir     string
+	catalog *BoltCatalog
+}
+
+func TestBoltCatalog(t *testing.T) {
+	suite.Run(t, new(suiteBoltCatalog))
+}
+
+func (s *suiteBoltCatalog) SetupTest() {
+	dir, err := os.MkdirTemp("", "filup-catalog")
+	s.Require().Nil(err)
+	s.dir = dir
+	db, err := bolt.Open(filepath.Join(dir, "catalog.db"), 0600, nil)
+	s.Require().Nil(err)
+	s.Require().Nil(db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{filesBucket, filesByTimeBucket} {
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	s.catalog = &BoltCatalog{db: db}
+}
+
+func (s *suiteBoltCatalog) TearDownTest() {
+	_ = s.catalog.db.Close()
+	_ = os.RemoveAll(s.dir)
+}
+
+func (s *suiteBoltCatalog) put(n int, owner string) {
+	s.Require().Nil(s.catalog.Put(dto.CatalogEntry{
+		Uuid:        "uuid" + strconv.Itoa(n),
+		Owner:       owner,
+		CreatedAt:   int64(n),
+		CompletedAt: int64(n),
+	}))
+}
+
+func (s *suiteBoltCatalog) uuids(page dto.CatalogPage) []string {
+	result := make([]string, 0, len(page.Items))
+	for _, e := range page.Items {
+		result = append(result, e.Uuid)
+	}
+	return result
+}
+
+func (s *suiteBoltCatalog) TestPutGetDelete() {
+	s.True(s.catalog.IsEnabled())
+	s.put(1, "user1")
+	e, found, err := s.catalog.Get("uuid1")
+	s.Require().Nil(err)
+	s.True(found)
+	s.Equal("user1", e.Owner)
+
+	s.put(1, "user2")
+	page, err := s.catalog.List(dto.CatalogFilter{Limit: 10})
+	s.Require().Nil(err)
+	s.Equal([]string{"uuid1"}, s.uuids(page))
+	s.Equal("user2", page.Items[0].Owner)
+
+	s.Require().Nil(s.catalog.Delete("uuid1"))
+	_, found, err = s.catalog.Get("uuid1")
+	s.Require().Nil(err)
+	s.False(found)
+	page, err = s.catalog.List(dto.CatalogFilter{Limit: 10})
+	s.Require().Nil(err)
+	s.Empty(page.Items)
+}
+
+func (s *suiteBoltCatalog) TestListByCursor() {
+	for i := 1; i <= 5; i++ {
+		s.put(i, "user1")
+	}
+	page, err := s.catalog.List(dto.CatalogFilter{Limit: 2})
+	s.Require().Nil(err)
+	s.Equal([]string{"uuid5", "uuid4"}, s.uuids(page))
+	s.NotEmpty(page.NextCursor)
+
+	page, err = s.catalog.List(dto.CatalogFilter{Limit: 2, Cursor: page.NextCursor})
+	s.Require().Nil(err)
+	s.Equal([]string{"uuid3", "uuid2"}, s.uuids(page))
+
+	page, err = s.catalog.List(dto.CatalogFilter{Limit: 2, Cursor: page.NextCursor})
+	s.Require().Nil(err)
+	s.Equal([]string{"uuid1"}, s.uuids(page))
+	s.Empty(page.NextCursor)
+}
+
+func (s *suiteBoltCatalog) TestListMaxScan() {
+	for i := 1; i <= 5; i++ {
+		owner := "user1"
+		if i%2 == 0 {
+			owner = "user2"
+		}
+		s.put(i, owner)
+	}
+	filter := dto.NewCatalogFilter()
+	filter.Owner = "user1"
+	filter.Limit = 10
+	filter.MaxScan = 2
+	page, err := s.catalog.List(filter)
+	s.Require().Nil(err)
+	s.Equal([]string{"uuid5"}, s.uuids(page))
+	s.NotEmpty(page.NextCursor)
+
+	filter.Cursor = page.NextCursor
+	page, err = s.catalog.List(filter)
+	s.Require().Nil(err)
+	s.Equal([]string{"uuid3"}, s.uuids(page))
+
+	filter.Cursor = page.NextCursor
+	page, err = s.catalog.List(filter)
+	s.Require().Nil(err)
+	s.Equal([]string{"uuid1"}, s.uuids(page))
+	s.Empty(page.NextCursor)
+}
+
+func (s *suiteBoltCatalog) TestListCreatedFrom() {
+	for i := 1; i <= 5; i++ {
+		s.put(i, "user1")
+	}
+	filter := dto.NewCatalogFilter()
+	filter.Limit = 10
+	filter.CreatedFrom = 3
+	page, err := s.catalog.List(filter)
+	s.Require().Nil(err)
+	s.Equal([]string{"uuid5", "uuid4", "uuid3"}, s.uuids(page))
+	s.Empty(page.NextCursor)
+}
+
+func (s *suiteBoltCatalog) TestInvalidCursor() {
+	_, err := s.catalog.List(dto.CatalogFilter{Limit: 10, Cursor: "not-hex"})
+	s.ErrorIs(err, port.ErrInvalidCatalogCursor)
+	_, err = s.catalog.List(dto.CatalogFilter{Limit: 10, Cursor: "00"})
+	s.ErrorIs(err, port.ErrInvalidCatalogCursor)
+}
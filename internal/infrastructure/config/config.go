@@ -1,9 +1,15 @@
 package config
 
 import (
+	"encoding/base64"
 	"github.com/google/uuid"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
 	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"io/ioutil"
+	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,12 +21,22 @@ const (
 )
 
 type Configuration struct {
-	Http     HTTP
-	Storage  Storage
-	Queue    QueueEngine
-	Logs     logsEngine.LogConfigs
-	Uploader Uploader
-	Caches   CachesConfig
+	Http       HTTP
+	Storage    Storage
+	Queue      QueueEngine
+	Logs       logsEngine.LogConfigs
+	Logging    logsEngine.Options
+	Uploader   Uploader
+	Caches     CachesConfig
+	Catalog    CatalogConfig
+	Auth       AuthConfig
+	SignedUrls SignedUrlsConfig
+	Admin      AdminConfig
+	Antivirus  AntivirusConfig
+	Processors ProcessorsConfig
+	Images     ImagesConfig
+	Envelope   EnvelopeConfig
+	Tracing    TracingConfig
 }
 
 func (c *Configuration) AfterLoad() {
@@ -31,18 +47,34 @@ func (c *Configuration) AfterLoad() {
 			c.Logs[idx] = cfg
 		}
 	}
+	c.Storage.S3.Encryption = c.Storage.S3.Encryption.AfterLoad()
 	c.Uploader = c.Uploader.AfterLoad()
+	c.Auth = c.Auth.AfterLoad()
+	c.Antivirus = c.Antivirus.AfterLoad()
+	c.Processors = c.Processors.AfterLoad()
+	c.Images = c.Images.AfterLoad()
+	c.Envelope = c.Envelope.AfterLoad()
+	c.Tracing = c.Tracing.AfterLoad()
+	if c.Envelope.IsEnabled() && c.Uploader.ChunkLength%dto.EnvelopeSegmentSize != 0 {
+		panic("config value uploader.chunkLength must be multiple of " + strconv.Itoa(dto.EnvelopeSegmentSize) + " with envelope encryption")
+	}
 }
 
 type HTTP struct {
-	Port    string
-	Timeout int
+	Port          string
+	Timeout       int
+	ShutdownDelay int
 }
 
 func (h *HTTP) GetTimeout() time.Duration {
 	return time.Duration(h.Timeout) * time.Second
 }
 
+// GetShutdownDelay - time between readiness is off and server is stopped
+func (h *HTTP) GetShutdownDelay() time.Duration {
+	return time.Duration(h.ShutdownDelay) * time.Second
+}
+
 type StorageCredentials struct {
 	Key    string
 	Secret string
@@ -61,6 +93,47 @@ type S3Config struct {
 	Buckets     StorageBuckets
 	Endpoint    string
 	Region      string
+	Encryption  StorageEncryption
+}
+
+const (
+	SseS3  = "sse-s3"
+	SseKms = "sse-kms"
+	SseC   = "sse-c"
+)
+
+// StorageEncryption - server-side encryption of objects. MasterKey is base64 encoded 32 bytes key, it is required by SSE-C
+type StorageEncryption struct {
+	Type       string
+	KmsKeyId   string
+	KmsContext map[string]string
+	MasterKey  string
+
+	masterKey []byte
+}
+
+func (e StorageEncryption) GetMasterKey() []byte {
+	return e.masterKey
+}
+
+func (e StorageEncryption) AfterLoad() StorageEncryption {
+	e.Type = strings.ToLower(e.Type)
+	switch e.Type {
+	case "", SseS3:
+	case SseKms:
+		if e.KmsKeyId == "" {
+			panic("config value storage.s3.encryption.kmsKeyId is required by sse-kms")
+		}
+	case SseC:
+		key, err := base64.StdEncoding.DecodeString(e.MasterKey)
+		if err != nil || len(key) != 32 {
+			panic("config value storage.s3.encryption.masterKey must be base64 encoded 32 bytes key")
+		}
+		e.masterKey = key
+	default:
+		panic("config value storage.s3.encryption.type must be one of sse-s3, sse-kms, sse-c")
+	}
+	return e
 }
 
 func (s *S3Config) GetTimeout() time.Duration {
@@ -84,26 +157,44 @@ func (q *QueueEngine) GetTimeout() time.Duration {
 }
 
 type Uploader struct {
-	InfoFieldName    string
-	ChunkLength      int64
-	UuidNodeId       string
-	CallbackBefore   string
-	CallbackAfter    string
-	CallbackDownload string
-	HttpTimeout      int64
-	HttpRetries      int
-	ComposerWorkers  int
+	InfoFieldName          string
+	ChunkLength            int64
+	UuidNodeId             string
+	CallbackBefore         string
+	CallbackAfter          string
+	CallbackDownload       string
+	CallbackDownloadMethod string
+	CallbackDelete         string
+	CallbackDeleted        string
+	HttpTimeout            int64
+	HttpRetries            int
+	ComposerWorkers        int
+	KeyTemplate            string
+	Tenants                map[string]Namespace
+	Routes                 map[string]Namespace
+	ContentTypes           dto.ContentPolicy
+	ArchiveMaxFiles        int
+	CallbackSecret         string
+	FailedCallbacksTtl     int64
 
 	parsedCallbackBefore   *url.URL
 	parsedCallbackAfter    *url.URL
 	parsedCallbackDownload *url.URL
+	parsedCallbackDelete   *url.URL
+	parsedCallbackDeleted  *url.URL
 	httpTimeout            time.Duration
+	failedCallbacksTtl     time.Duration
 }
 
 func (u Uploader) GetHttpTimeout() time.Duration {
 	return u.httpTimeout
 }
 
+// GetCallbackSecret - key of signatures of callbacks, callbacks are not signed without it
+func (u Uploader) GetCallbackSecret() []byte {
+	return []byte(u.CallbackSecret)
+}
+
 func (u Uploader) GetCallbackBefore() *url.URL {
 	return u.parsedCallbackBefore
 }
@@ -116,6 +207,18 @@ func (u Uploader) GetCallbackDownload() *url.URL {
 	return u.parsedCallbackDownload
 }
 
+func (u Uploader) GetCallbackDownloadMethod() string {
+	return u.CallbackDownloadMethod
+}
+
+func (u Uploader) GetCallbackDelete() *url.URL {
+	return u.parsedCallbackDelete
+}
+
+func (u Uploader) GetCallbackDeleted() *url.URL {
+	return u.parsedCallbackDeleted
+}
+
 func (u Uploader) GetChunkLength() int64 {
 	return u.ChunkLength
 }
@@ -144,6 +247,327 @@ func (u Uploader) GetComposerWorkers() int {
 	return u.ComposerWorkers
 }
 
+func (u Uploader) GetArchiveMaxFiles() int {
+	return u.ArchiveMaxFiles
+}
+
+func (u Uploader) GetFailedCallbacksTtl() time.Duration {
+	return u.failedCallbacksTtl
+}
+
+func (u Uploader) GetNamespace(tenant, route string) (string, string) {
+	bucket, keyTemplate := "", u.KeyTemplate
+	for _, ns := range []Namespace{u.Tenants[strings.ToLower(tenant)], u.Routes[strings.ToLower(route)]} {
+		if ns.Bucket != "" {
+			bucket = ns.Bucket
+		}
+		if ns.KeyTemplate != "" {
+			keyTemplate = ns.KeyTemplate
+		}
+	}
+	return bucket, keyTemplate
+}
+
+// GetNamespacesBuckets returns buckets of tenants and routes, which are differ from default final bucket
+func (u Uploader) GetNamespacesBuckets() []string {
+	var result []string
+	unique := make(map[string]bool)
+	for _, namespaces := range []map[string]Namespace{u.Tenants, u.Routes} {
+		for _, ns := range namespaces {
+			if ns.Bucket != "" && !unique[ns.Bucket] {
+				unique[ns.Bucket] = true
+				result = append(result, ns.Bucket)
+			}
+		}
+	}
+	return result
+}
+
+func (u Uploader) GetContentPolicy() dto.ContentPolicy {
+	return u.ContentTypes
+}
+
+// GetProcessors returns processors of route, or of tenant if route has no processors
+func (u Uploader) GetProcessors(tenant, route string) []string {
+	if processors := u.Routes[strings.ToLower(route)].Processors; len(processors) > 0 {
+		return processors
+	}
+	return u.Tenants[strings.ToLower(tenant)].Processors
+}
+
+type Namespace struct {
+	Bucket      string
+	KeyTemplate string
+	Processors  []string
+}
+
+type CatalogConfig struct {
+	Path string
+}
+
+type AuthConfig struct {
+	Jwt JwtConfig
+}
+
+type JwtConfig struct {
+	Secret        string
+	JwksFile      string
+	Header        string
+	Required      bool
+	SkipCallbacks bool
+}
+
+func (a AuthConfig) GetTokenHeader() string {
+	return a.Jwt.Header
+}
+
+func (a AuthConfig) IsTokenRequired() bool {
+	return a.Jwt.Required
+}
+
+func (a AuthConfig) IsSkipCallbacks() bool {
+	return a.Jwt.SkipCallbacks
+}
+
+func (a AuthConfig) AfterLoad() AuthConfig {
+	if "" == a.Jwt.Header {
+		a.Jwt.Header = "Authorization"
+	}
+	return a
+}
+
+type SignedUrlsConfig struct {
+	Secret     string
+	BaseUrl    string
+	DefaultTtl int64
+	MaxTtl     int64
+	IpHeader   string
+}
+
+func (s SignedUrlsConfig) GetSignSecret() []byte {
+	return []byte(s.Secret)
+}
+
+func (s SignedUrlsConfig) GetBaseUrl() string {
+	return s.BaseUrl
+}
+
+func (s SignedUrlsConfig) GetDefaultTtl() time.Duration {
+	return time.Duration(s.DefaultTtl) * time.Second
+}
+
+func (s SignedUrlsConfig) GetMaxTtl() time.Duration {
+	return time.Duration(s.MaxTtl) * time.Second
+}
+
+func (s SignedUrlsConfig) GetIpHeader() string {
+	return s.IpHeader
+}
+
+type AdminConfig struct {
+	Token string
+}
+
+func (a AdminConfig) GetAdminToken() string {
+	return a.Token
+}
+
+type AntivirusConfig struct {
+	Address          string
+	Timeout          int
+	ChunkSize        int
+	StreamMaxLength  int64
+	Action           string
+	QuarantineBucket string
+	FailOpen         bool
+}
+
+func (a AntivirusConfig) GetTimeout() time.Duration {
+	return time.Duration(a.Timeout) * time.Second
+}
+
+func (a AntivirusConfig) GetAction() string {
+	return a.Action
+}
+
+// IsFailOpen - file, which was not scanned because of error, is kept available
+func (a AntivirusConfig) IsFailOpen() bool {
+	return a.FailOpen
+}
+
+// GetQuarantineBucket returns bucket for infected files, only if it is used
+func (a AntivirusConfig) GetQuarantineBucket() string {
+	if a.Address == "" || a.Action != dto.ScanActionQuarantine {
+		return ""
+	}
+	return a.QuarantineBucket
+}
+
+func (a AntivirusConfig) AfterLoad() AntivirusConfig {
+	switch a.Action {
+	case "":
+		a.Action = dto.ScanActionQuarantine
+	case dto.ScanActionQuarantine, dto.ScanActionTag, dto.ScanActionDelete:
+	default:
+		panic("config value antivirus.action must be one of: quarantine, tag, delete")
+	}
+	if a.Action == dto.ScanActionQuarantine && a.QuarantineBucket == "" {
+		a.QuarantineBucket = ProjectName + "-quarantine"
+	}
+	if a.ChunkSize < 1 {
+		a.ChunkSize = 64 * 1024
+	}
+	return a
+}
+
+type ProcessorsConfig struct {
+	Thumbnail ThumbnailConfig
+	ZipList   ZipListConfig
+}
+
+type ThumbnailConfig struct {
+	Width   int
+	Height  int
+	Quality int
+}
+
+type ZipListConfig struct {
+	MaxEntries int
+}
+
+func (p ProcessorsConfig) AfterLoad() ProcessorsConfig {
+	if p.Thumbnail.Width < 1 {
+		p.Thumbnail.Width = 256
+	}
+	if p.Thumbnail.Height < 1 {
+		p.Thumbnail.Height = 256
+	}
+	if p.Thumbnail.Quality < 1 || p.Thumbnail.Quality > 100 {
+		p.Thumbnail.Quality = 85
+	}
+	if p.ZipList.MaxEntries < 1 {
+		p.ZipList.MaxEntries = 1000
+	}
+	return p
+}
+
+// EnvelopeConfig - encryption of files by Filup. Master key is base64 encoded 32 bytes key from config or file
+type EnvelopeConfig struct {
+	MasterKey     string
+	MasterKeyFile string
+
+	masterKey []byte
+}
+
+func (e EnvelopeConfig) IsEnabled() bool {
+	return len(e.masterKey) > 0
+}
+
+func (e EnvelopeConfig) GetMasterKey() []byte {
+	return e.masterKey
+}
+
+func (e EnvelopeConfig) AfterLoad() EnvelopeConfig {
+	encoded := e.MasterKey
+	if e.MasterKeyFile != "" {
+		content, err := ioutil.ReadFile(e.MasterKeyFile)
+		if err != nil {
+			panic("can not read envelope.masterKeyFile: " + err.Error())
+		}
+		encoded = strings.TrimSpace(string(content))
+	}
+	if encoded == "" {
+		return e
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		panic("envelope master key must be base64 encoded 32 bytes key")
+	}
+	e.masterKey = key
+	return e
+}
+
+const (
+	TracingExporterOtlp   = "otlp"
+	TracingExporterStdout = "stdout"
+	TracingExporterFile   = "file"
+)
+
+// TracingConfig - empty Exporter disables export of spans, traceparent of requests is still propagated to callbacks
+type TracingConfig struct {
+	Exporter    string
+	Endpoint    string
+	Headers     map[string]string
+	File        string
+	ServiceName string
+	SampleRatio float64
+}
+
+func (t TracingConfig) AfterLoad() TracingConfig {
+	switch t.Exporter {
+	case "":
+	case TracingExporterOtlp:
+		if t.Endpoint == "" {
+			panic("config value tracing.endpoint is required for otlp exporter")
+		}
+	case TracingExporterStdout:
+	case TracingExporterFile:
+		if t.File == "" {
+			panic("config value tracing.file is required for file exporter")
+		}
+	default:
+		panic("config value tracing.exporter must be one of otlp, stdout, file")
+	}
+	if t.ServiceName == "" {
+		t.ServiceName = ProjectName
+	}
+	if t.SampleRatio <= 0 || t.SampleRatio > 1 {
+		t.SampleRatio = 1
+	}
+	return t
+}
+
+// ImagesConfig - resized variants of images are cached in Bucket, empty Bucket disables cache
+type ImagesConfig struct {
+	Bucket    string
+	MaxWidth  int
+	MaxHeight int
+	MaxPixels int
+	MaxBytes  int64
+	Quality   int
+}
+
+func (i ImagesConfig) GetBucket() string {
+	return i.Bucket
+}
+
+func (i ImagesConfig) GetMaxWidth() int {
+	return i.MaxWidth
+}
+
+func (i ImagesConfig) GetMaxHeight() int {
+	return i.MaxHeight
+}
+
+func (i ImagesConfig) AfterLoad() ImagesConfig {
+	if i.MaxWidth < 1 {
+		i.MaxWidth = 4096
+	}
+	if i.MaxHeight < 1 {
+		i.MaxHeight = 4096
+	}
+	if i.MaxPixels < 1 {
+		i.MaxPixels = 50000000
+	}
+	if i.MaxBytes < 1 {
+		i.MaxBytes = 1024 * 1024 * 50
+	}
+	if i.Quality < 1 || i.Quality > 100 {
+		i.Quality = 85
+	}
+	return i
+}
+
 type CachesConfig struct {
 	Parts CacheConfig
 }
@@ -168,11 +592,37 @@ func (u Uploader) AfterLoad() Uploader {
 		uuid.SetClockSequence(-1)
 	}
 
+	if "" == u.KeyTemplate {
+		u.KeyTemplate = "{uuid}"
+	}
+	u.checkKeyTemplate(u.KeyTemplate)
+	for _, namespaces := range []map[string]Namespace{u.Tenants, u.Routes} {
+		for _, ns := range namespaces {
+			if ns.KeyTemplate != "" {
+				u.checkKeyTemplate(ns.KeyTemplate)
+			}
+		}
+	}
+
 	u.httpTimeout = time.Duration(u.HttpTimeout) * time.Second
+	u.failedCallbacksTtl = time.Duration(u.FailedCallbacksTtl) * time.Second
+	if u.ArchiveMaxFiles < 1 {
+		u.ArchiveMaxFiles = 1000
+	}
 
 	u.parsedCallbackBefore = u.setParsedUrl(u.CallbackBefore)
 	u.parsedCallbackAfter = u.setParsedUrl(u.CallbackAfter)
 	u.parsedCallbackDownload = u.setParsedUrl(u.CallbackDownload)
+	u.CallbackDownloadMethod = strings.ToUpper(u.CallbackDownloadMethod)
+	switch u.CallbackDownloadMethod {
+	case "":
+		u.CallbackDownloadMethod = http.MethodGet
+	case http.MethodGet, http.MethodPost:
+	default:
+		panic("config value uploader.callbackDownloadMethod must be GET or POST")
+	}
+	u.parsedCallbackDelete = u.setParsedUrl(u.CallbackDelete)
+	u.parsedCallbackDeleted = u.setParsedUrl(u.CallbackDeleted)
 
 	return u
 }
@@ -187,3 +637,9 @@ func (u Uploader) setParsedUrl(in string) *url.URL {
 	}
 	return result
 }
+
+func (u Uploader) checkKeyTemplate(keyTemplate string) {
+	if !strings.Contains(keyTemplate, "{uuid}") {
+		panic("key template " + keyTemplate + " must contain {uuid}")
+	}
+}
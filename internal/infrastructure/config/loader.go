@@ -11,9 +11,32 @@ func ProvideConfig() Configuration {
 	return gConfig
 }
 
-func ProvideUploaderConfig() port.UploaderConfig {
-	c := gConfig.Uploader
-	return c
+func ProvideUploaderConfig(cfg Configuration) port.UploaderConfig {
+	return cfg.Uploader
+}
+
+func ProvideAuthConfig(cfg Configuration) port.AuthConfig {
+	return cfg.Auth
+}
+
+func ProvideSignedUrlsConfig(cfg Configuration) port.SignedUrlConfig {
+	return cfg.SignedUrls
+}
+
+func ProvideAdminConfig(cfg Configuration) port.AdminConfig {
+	return cfg.Admin
+}
+
+func ProvideAntivirusConfig(cfg Configuration) port.AntivirusConfig {
+	return cfg.Antivirus
+}
+
+func ProvideImagesConfig(cfg Configuration) port.ImagesConfig {
+	return cfg.Images
+}
+
+func ProvideEnvelopeConfig(cfg Configuration) port.EnvelopeConfig {
+	return cfg.Envelope
 }
 
 func LoadConfigByViper(name string) (Configuration, error) {
@@ -42,7 +65,9 @@ func LoadConfigByViper(name string) (Configuration, error) {
 		return Configuration{}, err
 	}
 
-	cfg.AfterLoad()
+	if err = cfg.Prepare(); err != nil {
+		return Configuration{}, err
+	}
 	gConfig = cfg
 
 	return cfg, nil
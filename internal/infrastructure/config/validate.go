@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+)
+
+const maskedValue = "******"
+
+// Validate checks values, which are required by any command
+func (c Configuration) Validate() error {
+	required := [][2]string{
+		{"storage.s3.endpoint", c.Storage.S3.Endpoint},
+		{"storage.s3.buckets.parts", c.Storage.S3.Buckets.Parts},
+		{"storage.s3.buckets.final", c.Storage.S3.Buckets.Final},
+		{"storage.s3.buckets.meta", c.Storage.S3.Buckets.Meta},
+	}
+	for _, value := range required {
+		if value[1] == "" {
+			return errors.New("config value " + value[0] + " is required")
+		}
+	}
+	return nil
+}
+
+// Masked returns copy of configuration with hidden secrets, so it can be printed
+func (c Configuration) Masked() Configuration {
+	c.Storage.S3.Credentials.Secret = mask(c.Storage.S3.Credentials.Secret)
+	c.Storage.S3.Credentials.Token = mask(c.Storage.S3.Credentials.Token)
+	c.Storage.S3.Encryption.MasterKey = mask(c.Storage.S3.Encryption.MasterKey)
+	c.Auth.Jwt.Secret = mask(c.Auth.Jwt.Secret)
+	c.SignedUrls.Secret = mask(c.SignedUrls.Secret)
+	c.Admin.Token = mask(c.Admin.Token)
+	c.Uploader.CallbackSecret = mask(c.Uploader.CallbackSecret)
+	c.Envelope.MasterKey = mask(c.Envelope.MasterKey)
+	if len(c.Tracing.Headers) > 0 {
+		headers := make(map[string]string, len(c.Tracing.Headers))
+		for name, value := range c.Tracing.Headers {
+			headers[name] = mask(value)
+		}
+		c.Tracing.Headers = headers
+	}
+	return c
+}
+
+func mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	return maskedValue
+}
+
+// Prepare applies defaults and validates configuration, which is loaded or is made by embedding application
+func (c *Configuration) Prepare() error {
+	if err := c.afterLoad(); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// afterLoad returns error instead of panic of AfterLoad, so invalid config is reported by command
+func (c *Configuration) afterLoad() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprint(r))
+		}
+	}()
+	c.AfterLoad()
+	return nil
+}
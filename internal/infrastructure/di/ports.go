@@ -0,0 +1,85 @@
+package di
+
+import (
+	"github.com/fasthttp/router"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/internal/infrastructure/health"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/satmaelstorm/filup/internal/infrastructure/web/routes"
+	"net/http"
+)
+
+// EmbeddedRoutes - routes of embedded filup for fasthttp and net/http servers, they share the same domain
+type EmbeddedRoutes struct {
+	FastHttp *router.Router
+	Http     http.Handler
+}
+
+// EmbeddedPorts - ports of embedded filup, which are supplied by embedding application
+type EmbeddedPorts struct {
+	Context           port.ContextProvider
+	Config            config.Configuration
+	StorageMeta       port.StorageMeta
+	StorageMetaLister port.StorageMetaLister
+	StoragePart       port.StoragePart
+	StorageCleaner    port.StorageCleaner
+	StorageEncryption port.StorageEncryption
+	PartsComposer     port.PartsComposer
+	RawFileStreamer   port.RawFileStreamer
+	RawStorageFiles   port.RawStorageFiles
+	StorageChecker    health.StorageChecker
+	Poster            port.Poster
+	Getter            port.Getter
+	Logger            port.Logger
+	StdLogger         logsEngine.ILogger
+	LogLevels         port.LogLevelController
+	MetaCache         port.MetaCacheController
+	// Catalog - catalog of files of embedded instance, it is opened by path from configuration of instance
+	Catalog port.FileCatalog
+	// Tracer - tracer of embedded instance, it does not change globals of otel
+	Tracer port.Tracer
+	// Health - readiness of embedded instance, it is not shared with other instances of process
+	Health *health.Health
+	Ops    routes.Ops
+}
+
+// NewEmbeddedPorts binds single storage of application to all storage ports
+func NewEmbeddedPorts(
+	cc port.ContextProvider,
+	cfg config.Configuration,
+	storage port.Storage,
+	poster port.Poster,
+	getter port.Getter,
+	logger port.Logger,
+	levels port.LogLevelController,
+	cache port.MetaCacheController,
+	filesCatalog port.FileCatalog,
+	tracer port.Tracer,
+	instanceHealth *health.Health,
+	ops routes.Ops,
+) EmbeddedPorts {
+	return EmbeddedPorts{
+		Context:           cc,
+		Config:            cfg,
+		StorageMeta:       storage,
+		StorageMetaLister: storage,
+		StoragePart:       storage,
+		StorageCleaner:    storage,
+		StorageEncryption: storage,
+		PartsComposer:     storage,
+		RawFileStreamer:   storage,
+		RawStorageFiles:   storage,
+		StorageChecker:    storage,
+		Poster:            poster,
+		Getter:            getter,
+		Logger:            logger,
+		StdLogger:         logger,
+		LogLevels:         levels,
+		MetaCache:         cache,
+		Catalog:           filesCatalog,
+		Tracer:            tracer,
+		Health:            instanceHealth,
+		Ops:               ops,
+	}
+}
@@ -7,50 +7,165 @@ import (
 	"github.com/google/wire"
 	"github.com/satmaelstorm/filup/internal/domain"
 	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/antivirus"
 	"github.com/satmaelstorm/filup/internal/infrastructure/appctx"
+	"github.com/satmaelstorm/filup/internal/infrastructure/auth"
 	"github.com/satmaelstorm/filup/internal/infrastructure/cache"
+	"github.com/satmaelstorm/filup/internal/infrastructure/catalog"
 	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/internal/infrastructure/health"
+	"github.com/satmaelstorm/filup/internal/infrastructure/images"
 	"github.com/satmaelstorm/filup/internal/infrastructure/logs"
 	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/satmaelstorm/filup/internal/infrastructure/metrics"
+	"github.com/satmaelstorm/filup/internal/infrastructure/processors"
 	"github.com/satmaelstorm/filup/internal/infrastructure/storage"
+	"github.com/satmaelstorm/filup/internal/infrastructure/tracing"
 	"github.com/satmaelstorm/filup/internal/infrastructure/web"
 	"github.com/satmaelstorm/filup/internal/infrastructure/web/handlers"
 	"github.com/satmaelstorm/filup/internal/infrastructure/web/routes"
 )
 
+// coreProviders - graph of filup, which does not depend on replaceable ports
+var coreProviders = wire.NewSet(
+	wire.Bind(new(port.HandlerJson), new(*domain.MetaUploader)),
+	wire.Bind(new(port.HandlerMultipart), new(*domain.UploadParts)),
+	wire.Bind(new(port.HandlerUploadSession), new(*domain.UploadParts)),
+	wire.Bind(new(port.PartComposerRunner), new(*domain.PartsComposer)),
+	wire.Bind(new(port.FileStreamer), new(*domain.EnvelopeStorage)),
+	wire.Bind(new(port.HandlerStreamer), new(*domain.FileDownloader)),
+	wire.Bind(new(port.HandlerCatalog), new(*domain.FilesCatalog)),
+	wire.Bind(new(port.StorageFiles), new(*domain.EnvelopeStorage)),
+	wire.Bind(new(port.HandlerDelete), new(*domain.FileRemover)),
+	wire.Bind(new(port.TokenVerifier), new(*auth.JwtVerifier)),
+	wire.Bind(new(port.HandlerSigner), new(*domain.UrlSigner)),
+	wire.Bind(new(port.VirusScanner), new(*antivirus.ClamdScanner)),
+	wire.Bind(new(port.ProcessorsRegistry), new(*processors.Registry)),
+	wire.Bind(new(port.HandlerImage), new(*domain.ImageResizer)),
+	wire.Bind(new(port.ImageTransformer), new(*images.Transformer)),
+	wire.Bind(new(port.HandlerArchive), new(*domain.FileArchiver)),
+	wire.Bind(new(port.UploadMetrics), new(*metrics.UploadMetrics)),
+	wire.Bind(new(port.HandlerLogLevel), new(*domain.LogLevels)),
+	wire.Bind(new(port.HandlerAdminUploads), new(*domain.UploadsAdmin)),
+	wire.Bind(new(port.HandlerAdminHealth), new(*domain.AdminHealth)),
+	wire.Bind(new(port.HealthReporter), new(*health.Probes)),
+	wire.Bind(new(health.ComposerChecker), new(*domain.PartsComposer)),
+
+	config.ProvideUploaderConfig,
+	config.ProvideAuthConfig,
+	config.ProvideSignedUrlsConfig,
+	config.ProvideAdminConfig,
+	config.ProvideAntivirusConfig,
+	config.ProvideImagesConfig,
+	config.ProvideEnvelopeConfig,
+	routes.ProvideRoutes,
+	routes.ProvideHttpRoutes,
+	handlers.ProvideHandlers,
+	handlers.ProvideHttpHandlers,
+	domain.ProvideMetaUploader,
+	domain.ProvideMetaGuard,
+	domain.ProvideUuidProvider,
+	domain.ProvideUploadParts,
+	domain.ProvidePartsComposer,
+	domain.ProvideFileDownloader,
+	domain.ProvideFileRecords,
+	domain.ProvideFilesCatalog,
+	domain.ProvideFileRemover,
+	domain.ProvideAuthenticator,
+	domain.ProvideUrlSigner,
+	auth.ProvideJwtVerifier,
+	domain.ProvideFileScanner,
+	antivirus.ProvideClamdScanner,
+	domain.ProvideProcessingPipeline,
+	processors.ProvideProcessorsRegistry,
+	domain.ProvideImageResizer,
+	images.ProvideTransformer,
+	domain.ProvideFileArchiver,
+	domain.ProvideEnvelope,
+	domain.ProvideEnvelopeStorage,
+	metrics.ProvideUploadMetrics,
+	domain.ProvideLogLevels,
+	health.ProvideProbes,
+	handlers.ProvideHealthHandlers,
+	handlers.ProvideHttpHealthHandlers,
+	domain.ProvideFailedCallbacks,
+	domain.ProvideUploadsAdmin,
+	domain.ProvideAdminHealth,
+)
+
+// serverProviders - ports of server: S3 storage, loggers and config are shared by process
+var serverProviders = wire.NewSet(
+	wire.Bind(new(port.ContextProvider), new(*appctx.CoreContext)),
+	wire.Bind(new(port.StorageMeta), new(*storage.MinioS3)),
+	wire.Bind(new(port.StoragePart), new(*storage.MinioS3)),
+	wire.Bind(new(port.PartsComposer), new(*storage.MinioS3)),
+	wire.Bind(new(port.StorageCleaner), new(*storage.MinioS3)),
+	wire.Bind(new(port.RawFileStreamer), new(*storage.MinioS3)),
+	wire.Bind(new(port.RawStorageFiles), new(*storage.MinioS3)),
+	wire.Bind(new(port.StorageEncryption), new(*storage.MinioS3)),
+	wire.Bind(new(port.StorageMetaLister), new(*storage.MinioS3)),
+	wire.Bind(new(health.StorageChecker), new(*storage.MinioS3)),
+	wire.Bind(new(port.Poster), new(*web.RequestHelpers)),
+	wire.Bind(new(port.Getter), new(*web.RequestHelpers)),
+	wire.Bind(new(port.Logger), new(*logsEngine.Loggers)),
+	wire.Bind(new(logsEngine.ILogger), new(*logsEngine.Loggers)),
+	wire.Bind(new(port.LogLevelController), new(*logsEngine.Loggers)),
+	wire.Bind(new(port.MetaCacheController), new(*cache.Cache)),
+	wire.Bind(new(port.FileCatalog), new(*catalog.BoltCatalog)),
+	wire.Bind(new(port.Tracer), new(*tracing.Tracer)),
+
+	wire.Value(routes.Ops{Enabled: true}),
+
+	appctx.ProvideContext,
+	config.ProvideConfig,
+	metrics.ProvideHealth,
+	cache.ProvideMetaCache,
+	logs.ProvideLoggers,
+	web.ProvideWebServer,
+	web.ProvideRequestHelpers,
+	storage.ProvideMinioS3,
+	catalog.ProvideBoltCatalog,
+	tracing.ProvideTracer,
+)
+
 func InitWebServer() (*web.Server, error) {
+	wire.Build(coreProviders, serverProviders)
+	return &web.Server{}, nil
+}
+
+func InitUploadsAdmin() (*domain.UploadsAdmin, error) {
+	wire.Build(coreProviders, serverProviders)
+	return &domain.UploadsAdmin{}, nil
+}
+
+// InitEmbedded builds routes of filup from ports of embedding application
+func InitEmbedded(ports EmbeddedPorts) (*EmbeddedRoutes, error) {
 	wire.Build(
-		wire.Bind(new(port.ContextProvider), new(*appctx.CoreContext)),
-		wire.Bind(new(port.StorageMeta), new(*storage.MinioS3)),
-		wire.Bind(new(port.StoragePart), new(*storage.MinioS3)),
-		wire.Bind(new(port.PartsComposer), new(*storage.MinioS3)),
-		wire.Bind(new(port.StorageCleaner), new(*storage.MinioS3)),
-		wire.Bind(new(port.Poster), new(*web.RequestHelpers)),
-		wire.Bind(new(port.Getter), new(*web.RequestHelpers)),
-		wire.Bind(new(port.HandlerJson), new(*domain.MetaUploader)),
-		wire.Bind(new(port.HandlerMultipart), new(*domain.UploadParts)),
-		wire.Bind(new(port.Logger), new(*logsEngine.Loggers)),
-		wire.Bind(new(logsEngine.ILogger), new(*logsEngine.Loggers)),
-		wire.Bind(new(port.PartComposerRunner), new(*domain.PartsComposer)),
-		wire.Bind(new(port.MetaCacheController), new(*cache.Cache)),
-		wire.Bind(new(port.FileStreamer), new(*storage.MinioS3)),
-		wire.Bind(new(port.HandlerStreamer), new(*domain.FileDownloader)),
+		coreProviders,
+		wire.Struct(new(EmbeddedRoutes), "*"),
+		wire.FieldsOf(new(EmbeddedPorts), "Context", "Config", "StorageMeta", "StorageMetaLister", "StoragePart",
+			"StorageCleaner", "StorageEncryption", "PartsComposer", "RawFileStreamer", "RawStorageFiles", "StorageChecker",
+			"Poster", "Getter", "Logger", "StdLogger", "LogLevels", "MetaCache", "Catalog", "Tracer", "Health", "Ops"),
+	)
+	return &EmbeddedRoutes{}, nil
+}
 
+func InitStorageCheck() (*storage.StorageCheck, error) {
+	wire.Build(
+		wire.Bind(new(port.ContextProvider), new(*appctx.CoreContext)),
 		appctx.ProvideContext,
 		config.ProvideConfig,
-		config.ProvideUploaderConfig,
-		cache.ProvideMetaCache,
-		logs.ProvideLoggers,
-		routes.ProvideRoutes,
-		web.ProvideWebServer,
-		handlers.ProvideHandlers,
-		web.ProvideRequestHelpers,
-		storage.ProvideMinioS3,
-		domain.ProvideMetaUploader,
-		domain.ProvideUuidProvider,
-		domain.ProvideUploadParts,
-		domain.ProvidePartsComposer,
-		domain.ProvideFileDownloader,
+		storage.ProvideStorageCheck,
 	)
-	return &web.Server{}, nil
+	return &storage.StorageCheck{}, nil
+}
+
+func InitUrlSigner() *domain.UrlSigner {
+	wire.Build(
+		config.ProvideConfig,
+		config.ProvideSignedUrlsConfig,
+		config.ProvideAdminConfig,
+		domain.ProvideUrlSigner,
+	)
+	return &domain.UrlSigner{}
 }
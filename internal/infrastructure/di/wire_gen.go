@@ -7,12 +7,23 @@
 package di
 
 import (
+	"github.com/google/wire"
 	"github.com/satmaelstorm/filup/internal/domain"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/antivirus"
 	"github.com/satmaelstorm/filup/internal/infrastructure/appctx"
+	"github.com/satmaelstorm/filup/internal/infrastructure/auth"
 	"github.com/satmaelstorm/filup/internal/infrastructure/cache"
+	"github.com/satmaelstorm/filup/internal/infrastructure/catalog"
 	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/internal/infrastructure/health"
+	"github.com/satmaelstorm/filup/internal/infrastructure/images"
 	"github.com/satmaelstorm/filup/internal/infrastructure/logs"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/satmaelstorm/filup/internal/infrastructure/metrics"
+	"github.com/satmaelstorm/filup/internal/infrastructure/processors"
 	"github.com/satmaelstorm/filup/internal/infrastructure/storage"
+	"github.com/satmaelstorm/filup/internal/infrastructure/tracing"
 	"github.com/satmaelstorm/filup/internal/infrastructure/web"
 	"github.com/satmaelstorm/filup/internal/infrastructure/web/handlers"
 	"github.com/satmaelstorm/filup/internal/infrastructure/web/routes"
@@ -24,7 +35,7 @@ func InitWebServer() (*web.Server, error) {
 	coreContext := appctx.ProvideContext()
 	configuration := config.ProvideConfig()
 	loggers := logs.ProvideLoggers(configuration)
-	uploaderConfig := config.ProvideUploaderConfig()
+	uploaderConfig := config.ProvideUploaderConfig(configuration)
 	cacheCache, err := cache.ProvideMetaCache(configuration, loggers)
 	if err != nil {
 		return nil, err
@@ -33,14 +44,211 @@ func InitWebServer() (*web.Server, error) {
 	if err != nil {
 		return nil, err
 	}
+	metaGuard := domain.ProvideMetaGuard(minioS3)
+	envelopeConfig := config.ProvideEnvelopeConfig(configuration)
+	envelope := domain.ProvideEnvelope(envelopeConfig)
+	envelopeStorage := domain.ProvideEnvelopeStorage(minioS3, minioS3, envelope)
+	fileRecords := domain.ProvideFileRecords(minioS3)
 	uuidProvider := domain.ProvideUuidProvider()
 	requestHelpers := web.ProvideRequestHelpers()
-	metaUploader := domain.ProvideMetaUploader(coreContext, uploaderConfig, minioS3, uuidProvider, requestHelpers)
-	partsComposer := domain.ProvidePartsComposer(coreContext, minioS3, minioS3, uploaderConfig, loggers, requestHelpers)
-	uploadParts := domain.ProvideUploadParts(uploaderConfig, minioS3, minioS3, partsComposer)
-	fileDownloader := domain.ProvideFileDownloader(coreContext, uploaderConfig, minioS3, requestHelpers, loggers)
-	handlersHandlers := handlers.ProvideHandlers(loggers, metaUploader, uploadParts, fileDownloader)
-	router := routes.ProvideRoutes(handlersHandlers, loggers)
-	server := web.ProvideWebServer(coreContext, router, configuration, loggers)
+	jwtVerifier, err := auth.ProvideJwtVerifier(configuration)
+	if err != nil {
+		return nil, err
+	}
+	authConfig := config.ProvideAuthConfig(configuration)
+	authenticator := domain.ProvideAuthenticator(jwtVerifier, authConfig)
+	uploadMetrics := metrics.ProvideUploadMetrics()
+	tracer, err := tracing.ProvideTracer(configuration, coreContext, loggers)
+	if err != nil {
+		return nil, err
+	}
+	metaUploader := domain.ProvideMetaUploader(coreContext, uploaderConfig, minioS3, metaGuard, envelopeStorage, fileRecords, uuidProvider, requestHelpers, authenticator, minioS3, envelope, uploadMetrics, tracer, loggers)
+	boltCatalog, err := catalog.ProvideBoltCatalog(configuration, coreContext, loggers)
+	if err != nil {
+		return nil, err
+	}
+	adminConfig := config.ProvideAdminConfig(configuration)
+	filesCatalog := domain.ProvideFilesCatalog(boltCatalog, adminConfig, loggers)
+	clamdScanner, err := antivirus.ProvideClamdScanner(configuration)
+	if err != nil {
+		return nil, err
+	}
+	antivirusConfig := config.ProvideAntivirusConfig(configuration)
+	fileScanner := domain.ProvideFileScanner(clamdScanner, antivirusConfig, envelopeStorage, envelopeStorage, loggers)
+	registry := processors.ProvideProcessorsRegistry(configuration)
+	processingPipeline := domain.ProvideProcessingPipeline(registry, envelopeStorage, envelopeStorage, loggers)
+	failedCallbacks := domain.ProvideFailedCallbacks(minioS3, minioS3, minioS3, uuidProvider, uploaderConfig)
+	partsComposer := domain.ProvidePartsComposer(coreContext, minioS3, minioS3, fileRecords, filesCatalog, fileScanner, processingPipeline, uploaderConfig, loggers, requestHelpers, uploadMetrics, tracer, failedCallbacks)
+	uploadParts := domain.ProvideUploadParts(uploaderConfig, minioS3, minioS3, metaGuard, minioS3, partsComposer, envelope, uploadMetrics, tracer, loggers)
+	fileDownloader := domain.ProvideFileDownloader(coreContext, uploaderConfig, envelopeStorage, fileRecords, requestHelpers, requestHelpers, uploadMetrics, tracer, authenticator, antivirusConfig, loggers)
+	imagesConfig := config.ProvideImagesConfig(configuration)
+	fileRemover := domain.ProvideFileRemover(coreContext, uploaderConfig, imagesConfig, envelopeStorage, minioS3, fileRecords, filesCatalog, requestHelpers, uploadMetrics, tracer, loggers, failedCallbacks)
+	signedUrlConfig := config.ProvideSignedUrlsConfig(configuration)
+	urlSigner := domain.ProvideUrlSigner(signedUrlConfig, adminConfig)
+	transformer := images.ProvideTransformer(configuration)
+	imageResizer := domain.ProvideImageResizer(fileDownloader, envelopeStorage, envelopeStorage, transformer, imagesConfig, loggers)
+	fileArchiver := domain.ProvideFileArchiver(fileDownloader, filesCatalog, envelopeStorage, uploaderConfig, loggers)
+	logLevels := domain.ProvideLogLevels(loggers, adminConfig, loggers)
+	uploadsAdmin := domain.ProvideUploadsAdmin(coreContext, adminConfig, uploaderConfig, uploadParts, partsComposer, fileRecords, failedCallbacks, minioS3, cacheCache, requestHelpers, uploadMetrics, loggers)
+	healthHealth := metrics.ProvideHealth()
+	probes := health.ProvideProbes(healthHealth, minioS3, partsComposer)
+	adminHealth := domain.ProvideAdminHealth(probes, adminConfig)
+	handlersHandlers := handlers.ProvideHandlers(loggers, metaUploader, uploadParts, uploadParts, fileDownloader, filesCatalog, fileRemover, urlSigner, imageResizer, fileArchiver, logLevels, uploadsAdmin, adminHealth)
+	healthHandlers := handlers.ProvideHealthHandlers(probes)
+	ops := _wireOpsValue
+	router := routes.ProvideRoutes(handlersHandlers, healthHandlers, loggers, ops)
+	server := web.ProvideWebServer(coreContext, router, configuration, loggers, healthHealth)
 	return server, nil
 }
+
+var (
+	_wireOpsValue = routes.Ops{Enabled: true}
+)
+
+func InitUploadsAdmin() (*domain.UploadsAdmin, error) {
+	coreContext := appctx.ProvideContext()
+	configuration := config.ProvideConfig()
+	adminConfig := config.ProvideAdminConfig(configuration)
+	uploaderConfig := config.ProvideUploaderConfig(configuration)
+	loggers := logs.ProvideLoggers(configuration)
+	cacheCache, err := cache.ProvideMetaCache(configuration, loggers)
+	if err != nil {
+		return nil, err
+	}
+	minioS3, err := storage.ProvideMinioS3(configuration, coreContext, cacheCache)
+	if err != nil {
+		return nil, err
+	}
+	metaGuard := domain.ProvideMetaGuard(minioS3)
+	fileRecords := domain.ProvideFileRecords(minioS3)
+	boltCatalog, err := catalog.ProvideBoltCatalog(configuration, coreContext, loggers)
+	if err != nil {
+		return nil, err
+	}
+	filesCatalog := domain.ProvideFilesCatalog(boltCatalog, adminConfig, loggers)
+	clamdScanner, err := antivirus.ProvideClamdScanner(configuration)
+	if err != nil {
+		return nil, err
+	}
+	antivirusConfig := config.ProvideAntivirusConfig(configuration)
+	envelopeConfig := config.ProvideEnvelopeConfig(configuration)
+	envelope := domain.ProvideEnvelope(envelopeConfig)
+	envelopeStorage := domain.ProvideEnvelopeStorage(minioS3, minioS3, envelope)
+	fileScanner := domain.ProvideFileScanner(clamdScanner, antivirusConfig, envelopeStorage, envelopeStorage, loggers)
+	registry := processors.ProvideProcessorsRegistry(configuration)
+	processingPipeline := domain.ProvideProcessingPipeline(registry, envelopeStorage, envelopeStorage, loggers)
+	requestHelpers := web.ProvideRequestHelpers()
+	uploadMetrics := metrics.ProvideUploadMetrics()
+	tracer, err := tracing.ProvideTracer(configuration, coreContext, loggers)
+	if err != nil {
+		return nil, err
+	}
+	uuidProvider := domain.ProvideUuidProvider()
+	failedCallbacks := domain.ProvideFailedCallbacks(minioS3, minioS3, minioS3, uuidProvider, uploaderConfig)
+	partsComposer := domain.ProvidePartsComposer(coreContext, minioS3, minioS3, fileRecords, filesCatalog, fileScanner, processingPipeline, uploaderConfig, loggers, requestHelpers, uploadMetrics, tracer, failedCallbacks)
+	uploadParts := domain.ProvideUploadParts(uploaderConfig, minioS3, minioS3, metaGuard, minioS3, partsComposer, envelope, uploadMetrics, tracer, loggers)
+	uploadsAdmin := domain.ProvideUploadsAdmin(coreContext, adminConfig, uploaderConfig, uploadParts, partsComposer, fileRecords, failedCallbacks, minioS3, cacheCache, requestHelpers, uploadMetrics, loggers)
+	return uploadsAdmin, nil
+}
+
+// InitEmbedded builds routes of filup from ports of embedding application
+func InitEmbedded(ports EmbeddedPorts) (*EmbeddedRoutes, error) {
+	logger := ports.Logger
+	contextProvider := ports.Context
+	configuration := ports.Config
+	uploaderConfig := config.ProvideUploaderConfig(configuration)
+	storageMeta := ports.StorageMeta
+	metaGuard := domain.ProvideMetaGuard(storageMeta)
+	rawStorageFiles := ports.RawStorageFiles
+	rawFileStreamer := ports.RawFileStreamer
+	envelopeConfig := config.ProvideEnvelopeConfig(configuration)
+	envelope := domain.ProvideEnvelope(envelopeConfig)
+	envelopeStorage := domain.ProvideEnvelopeStorage(rawStorageFiles, rawFileStreamer, envelope)
+	fileRecords := domain.ProvideFileRecords(storageMeta)
+	uuidProvider := domain.ProvideUuidProvider()
+	poster := ports.Poster
+	jwtVerifier, err := auth.ProvideJwtVerifier(configuration)
+	if err != nil {
+		return nil, err
+	}
+	authConfig := config.ProvideAuthConfig(configuration)
+	authenticator := domain.ProvideAuthenticator(jwtVerifier, authConfig)
+	storageEncryption := ports.StorageEncryption
+	uploadMetrics := metrics.ProvideUploadMetrics()
+	tracer := ports.Tracer
+	metaUploader := domain.ProvideMetaUploader(contextProvider, uploaderConfig, storageMeta, metaGuard, envelopeStorage, fileRecords, uuidProvider, poster, authenticator, storageEncryption, envelope, uploadMetrics, tracer, logger)
+	storagePart := ports.StoragePart
+	storageCleaner := ports.StorageCleaner
+	partsComposer := ports.PartsComposer
+	fileCatalog := ports.Catalog
+	adminConfig := config.ProvideAdminConfig(configuration)
+	filesCatalog := domain.ProvideFilesCatalog(fileCatalog, adminConfig, logger)
+	clamdScanner, err := antivirus.ProvideClamdScanner(configuration)
+	if err != nil {
+		return nil, err
+	}
+	antivirusConfig := config.ProvideAntivirusConfig(configuration)
+	fileScanner := domain.ProvideFileScanner(clamdScanner, antivirusConfig, envelopeStorage, envelopeStorage, logger)
+	registry := processors.ProvideProcessorsRegistry(configuration)
+	processingPipeline := domain.ProvideProcessingPipeline(registry, envelopeStorage, envelopeStorage, logger)
+	storageMetaLister := ports.StorageMetaLister
+	failedCallbacks := domain.ProvideFailedCallbacks(storageMeta, storageMetaLister, storageCleaner, uuidProvider, uploaderConfig)
+	domainPartsComposer := domain.ProvidePartsComposer(contextProvider, partsComposer, storageCleaner, fileRecords, filesCatalog, fileScanner, processingPipeline, uploaderConfig, logger, poster, uploadMetrics, tracer, failedCallbacks)
+	uploadParts := domain.ProvideUploadParts(uploaderConfig, storagePart, storageMeta, metaGuard, storageCleaner, domainPartsComposer, envelope, uploadMetrics, tracer, logger)
+	getter := ports.Getter
+	fileDownloader := domain.ProvideFileDownloader(contextProvider, uploaderConfig, envelopeStorage, fileRecords, poster, getter, uploadMetrics, tracer, authenticator, antivirusConfig, logger)
+	imagesConfig := config.ProvideImagesConfig(configuration)
+	fileRemover := domain.ProvideFileRemover(contextProvider, uploaderConfig, imagesConfig, envelopeStorage, storageCleaner, fileRecords, filesCatalog, poster, uploadMetrics, tracer, logger, failedCallbacks)
+	signedUrlConfig := config.ProvideSignedUrlsConfig(configuration)
+	urlSigner := domain.ProvideUrlSigner(signedUrlConfig, adminConfig)
+	transformer := images.ProvideTransformer(configuration)
+	imageResizer := domain.ProvideImageResizer(fileDownloader, envelopeStorage, envelopeStorage, transformer, imagesConfig, logger)
+	fileArchiver := domain.ProvideFileArchiver(fileDownloader, filesCatalog, envelopeStorage, uploaderConfig, logger)
+	logLevelController := ports.LogLevels
+	logLevels := domain.ProvideLogLevels(logLevelController, adminConfig, logger)
+	metaCacheController := ports.MetaCache
+	uploadsAdmin := domain.ProvideUploadsAdmin(contextProvider, adminConfig, uploaderConfig, uploadParts, domainPartsComposer, fileRecords, failedCallbacks, storageMetaLister, metaCacheController, poster, uploadMetrics, logger)
+	healthHealth := ports.Health
+	storageChecker := ports.StorageChecker
+	probes := health.ProvideProbes(healthHealth, storageChecker, domainPartsComposer)
+	adminHealth := domain.ProvideAdminHealth(probes, adminConfig)
+	handlersHandlers := handlers.ProvideHandlers(logger, metaUploader, uploadParts, uploadParts, fileDownloader, filesCatalog, fileRemover, urlSigner, imageResizer, fileArchiver, logLevels, uploadsAdmin, adminHealth)
+	healthHandlers := handlers.ProvideHealthHandlers(probes)
+	iLogger := ports.StdLogger
+	ops := ports.Ops
+	router := routes.ProvideRoutes(handlersHandlers, healthHandlers, iLogger, ops)
+	httpHandlers := handlers.ProvideHttpHandlers(handlersHandlers, uploaderConfig)
+	httpHealthHandlers := handlers.ProvideHttpHealthHandlers(healthHandlers)
+	handler := routes.ProvideHttpRoutes(httpHandlers, httpHealthHandlers, iLogger, ops)
+	embeddedRoutes := &EmbeddedRoutes{
+		FastHttp: router,
+		Http:     handler,
+	}
+	return embeddedRoutes, nil
+}
+
+func InitStorageCheck() (*storage.StorageCheck, error) {
+	configuration := config.ProvideConfig()
+	coreContext := appctx.ProvideContext()
+	storageCheck, err := storage.ProvideStorageCheck(configuration, coreContext)
+	if err != nil {
+		return nil, err
+	}
+	return storageCheck, nil
+}
+
+func InitUrlSigner() *domain.UrlSigner {
+	configuration := config.ProvideConfig()
+	signedUrlConfig := config.ProvideSignedUrlsConfig(configuration)
+	adminConfig := config.ProvideAdminConfig(configuration)
+	urlSigner := domain.ProvideUrlSigner(signedUrlConfig, adminConfig)
+	return urlSigner
+}
+
+// wire.go:
+
+// coreProviders - graph of filup, which does not depend on replaceable ports
+var coreProviders = wire.NewSet(wire.Bind(new(port.HandlerJson), new(*domain.MetaUploader)), wire.Bind(new(port.HandlerMultipart), new(*domain.UploadParts)), wire.Bind(new(port.HandlerUploadSession), new(*domain.UploadParts)), wire.Bind(new(port.PartComposerRunner), new(*domain.PartsComposer)), wire.Bind(new(port.FileStreamer), new(*domain.EnvelopeStorage)), wire.Bind(new(port.HandlerStreamer), new(*domain.FileDownloader)), wire.Bind(new(port.HandlerCatalog), new(*domain.FilesCatalog)), wire.Bind(new(port.StorageFiles), new(*domain.EnvelopeStorage)), wire.Bind(new(port.HandlerDelete), new(*domain.FileRemover)), wire.Bind(new(port.TokenVerifier), new(*auth.JwtVerifier)), wire.Bind(new(port.HandlerSigner), new(*domain.UrlSigner)), wire.Bind(new(port.VirusScanner), new(*antivirus.ClamdScanner)), wire.Bind(new(port.ProcessorsRegistry), new(*processors.Registry)), wire.Bind(new(port.HandlerImage), new(*domain.ImageResizer)), wire.Bind(new(port.ImageTransformer), new(*images.Transformer)), wire.Bind(new(port.HandlerArchive), new(*domain.FileArchiver)), wire.Bind(new(port.UploadMetrics), new(*metrics.UploadMetrics)), wire.Bind(new(port.HandlerLogLevel), new(*domain.LogLevels)), wire.Bind(new(port.HandlerAdminUploads), new(*domain.UploadsAdmin)), wire.Bind(new(port.HandlerAdminHealth), new(*domain.AdminHealth)), wire.Bind(new(port.HealthReporter), new(*health.Probes)), wire.Bind(new(health.ComposerChecker), new(*domain.PartsComposer)), config.ProvideUploaderConfig, config.ProvideAuthConfig, config.ProvideSignedUrlsConfig, config.ProvideAdminConfig, config.ProvideAntivirusConfig, config.ProvideImagesConfig, config.ProvideEnvelopeConfig, routes.ProvideRoutes, routes.ProvideHttpRoutes, handlers.ProvideHandlers, handlers.ProvideHttpHandlers, domain.ProvideMetaUploader, domain.ProvideMetaGuard, domain.ProvideUuidProvider, domain.ProvideUploadParts, domain.ProvidePartsComposer, domain.ProvideFileDownloader, domain.ProvideFileRecords, domain.ProvideFilesCatalog, domain.ProvideFileRemover, domain.ProvideAuthenticator, domain.ProvideUrlSigner, auth.ProvideJwtVerifier, domain.ProvideFileScanner, antivirus.ProvideClamdScanner, domain.ProvideProcessingPipeline, processors.ProvideProcessorsRegistry, domain.ProvideImageResizer, images.ProvideTransformer, domain.ProvideFileArchiver, domain.ProvideEnvelope, domain.ProvideEnvelopeStorage, metrics.ProvideUploadMetrics, domain.ProvideLogLevels, health.ProvideProbes, handlers.ProvideHealthHandlers, handlers.ProvideHttpHealthHandlers, domain.ProvideFailedCallbacks, domain.ProvideUploadsAdmin, domain.ProvideAdminHealth)
+
+// serverProviders - ports of server: S3 storage, loggers and config are shared by process
+var serverProviders = wire.NewSet(wire.Bind(new(port.ContextProvider), new(*appctx.CoreContext)), wire.Bind(new(port.StorageMeta), new(*storage.MinioS3)), wire.Bind(new(port.StoragePart), new(*storage.MinioS3)), wire.Bind(new(port.PartsComposer), new(*storage.MinioS3)), wire.Bind(new(port.StorageCleaner), new(*storage.MinioS3)), wire.Bind(new(port.RawFileStreamer), new(*storage.MinioS3)), wire.Bind(new(port.RawStorageFiles), new(*storage.MinioS3)), wire.Bind(new(port.StorageEncryption), new(*storage.MinioS3)), wire.Bind(new(port.StorageMetaLister), new(*storage.MinioS3)), wire.Bind(new(health.StorageChecker), new(*storage.MinioS3)), wire.Bind(new(port.Poster), new(*web.RequestHelpers)), wire.Bind(new(port.Getter), new(*web.RequestHelpers)), wire.Bind(new(port.Logger), new(*logsEngine.Loggers)), wire.Bind(new(logsEngine.ILogger), new(*logsEngine.Loggers)), wire.Bind(new(port.LogLevelController), new(*logsEngine.Loggers)), wire.Bind(new(port.MetaCacheController), new(*cache.Cache)), wire.Bind(new(port.FileCatalog), new(*catalog.BoltCatalog)), wire.Bind(new(port.Tracer), new(*tracing.Tracer)), wire.Value(routes.Ops{Enabled: true}), appctx.ProvideContext, config.ProvideConfig, metrics.ProvideHealth, cache.ProvideMetaCache, logs.ProvideLoggers, web.ProvideWebServer, web.ProvideRequestHelpers, storage.ProvideMinioS3, catalog.ProvideBoltCatalog, tracing.ProvideTracer)
@@ -1,6 +1,7 @@
 package health
 
 import (
+	jsoniter "github.com/json-iterator/go"
 	"github.com/klauspost/cpuid"
 	"os"
 	"runtime"
@@ -119,9 +120,25 @@ func (h *Health) UpdateHealth() {
 }
 
 func (h *Health) SetReady(r bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	h.Ready = r
 }
 
+func (h *Health) IsReady() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.Ready
+}
+
+// Json returns current state with updated memory and cpu stats
+func (h *Health) Json() ([]byte, error) {
+	h.UpdateHealth()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return jsoniter.Marshal(h)
+}
+
 func (h *Health) RegisterCollector(namespace string) {
 	NewHealthCollector(namespace, h)
 
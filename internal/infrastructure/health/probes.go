@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"github.com/tidwall/sjson"
+	"sync"
+	"time"
+)
+
+const (
+	checkTimeout = 5 * time.Second
+	checkOk      = "ok"
+	// checksTtl - results of checks are reused by probes during this time, so frequent probes do not load S3
+	checksTtl = 2 * time.Second
+)
+
+type StorageChecker interface {
+	CheckBuckets(ctx context.Context) error
+}
+
+type ComposerChecker interface {
+	CheckBacklog() error
+}
+
+// Probes answers liveness and readiness probes. Instance is ready, if it is not stopping and all checks pass
+type Probes struct {
+	health    *Health
+	checks    map[string]func(ctx context.Context) error
+	mu        sync.Mutex
+	results   map[string]string
+	checkedAt time.Time
+}
+
+func ProvideProbes(health *Health, storage StorageChecker, composer ComposerChecker) *Probes {
+	return &Probes{
+		health: health,
+		checks: map[string]func(ctx context.Context) error{
+			"storage": storage.CheckBuckets,
+			"composer": func(ctx context.Context) error {
+				return composer.CheckBacklog()
+			},
+		},
+	}
+}
+
+// Ready returns results of checks, stopping instance is not ready regardless of cached results
+func (p *Probes) Ready(ctx context.Context) (bool, map[string]string) {
+	results := p.runChecks(ctx)
+	ready := p.health.IsReady()
+	for _, result := range results {
+		if result != checkOk {
+			ready = false
+		}
+	}
+	return ready, results
+}
+
+// runChecks runs all checks concurrently or returns results of previous run, which is not older than checksTtl.
+// Concurrent probes wait for one run. Returned map must not be changed
+func (p *Probes) runChecks(ctx context.Context) map[string]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.results != nil && time.Since(p.checkedAt) < checksTtl {
+		return p.results
+	}
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	results := make(map[string]string, len(p.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range p.checks {
+		wg.Add(1)
+		go func(name string, check func(ctx context.Context) error) {
+			defer wg.Done()
+			result := checkOk
+			if err := check(ctx); err != nil {
+				result = err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			results[name] = result
+		}(name, check)
+	}
+	wg.Wait()
+	p.results, p.checkedAt = results, time.Now()
+	return results
+}
+
+// Details returns state of Health with results of readiness checks
+func (p *Probes) Details(ctx context.Context) (bool, []byte, error) {
+	ready, checks := p.Ready(ctx)
+	content, err := p.health.Json()
+	if err != nil {
+		return false, nil, err
+	}
+	if content, err = sjson.SetBytes(content, "ready", ready); err != nil {
+		return false, nil, err
+	}
+	if content, err = sjson.SetBytes(content, "checks", checks); err != nil {
+		return false, nil, err
+	}
+	return ready, content, nil
+}
@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type countingChecker struct {
+	calls int32
+	err   error
+}
+
+func (c *countingChecker) CheckBuckets(context.Context) error {
+	atomic.AddInt32(&c.calls, 1)
+	return c.err
+}
+
+func (c *countingChecker) CheckBacklog() error {
+	atomic.AddInt32(&c.calls, 1)
+	return c.err
+}
+
+type suiteProbes struct {
+	suite.Suite
+	health   *Health
+	storage  *countingChecker
+	composer *countingChecker
+	probes   *Probes
+}
+
+func TestProbes(t *testing.T) {
+	suite.Run(t, new(suiteProbes))
+}
+
+func (s *suiteProbes) SetupTest() {
+	s.health = NewHealth()
+	s.health.SetReady(true)
+	s.storage = &countingChecker{}
+	s.composer = &countingChecker{}
+	s.probes = ProvideProbes(s.health, s.storage, s.composer)
+}
+
+func (s *suiteProbes) TestChecksAreCached() {
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ready, checks := s.probes.Ready(context.Background())
+			s.True(ready)
+			s.Equal(map[string]string{"storage": checkOk, "composer": checkOk}, checks)
+		}()
+	}
+	wg.Wait()
+	s.Equal(int32(1), atomic.LoadInt32(&s.storage.calls))
+	s.Equal(int32(1), atomic.LoadInt32(&s.composer.calls))
+
+	// stopping instance is not ready at once
+	s.health.SetReady(false)
+	ready, _ := s.probes.Ready(context.Background())
+	s.False(ready)
+	s.Equal(int32(1), atomic.LoadInt32(&s.storage.calls))
+}
+
+func (s *suiteProbes) TestFailedCheck() {
+	s.storage.err = errors.New("bucket does not exist")
+	ready, content, err := s.probes.Details(context.Background())
+	s.Require().Nil(err)
+	s.False(ready)
+	s.Contains(string(content), `"storage":"bucket does not exist"`)
+	s.Contains(string(content), `"pid":`)
+}
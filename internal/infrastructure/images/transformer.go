@@ -0,0 +1,132 @@
+package images
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strconv"
+)
+
+// decodableFormats - formats of image package, which are registered by imports of this file
+var decodableFormats = map[string]bool{
+	"jpeg": true,
+	"png":  true,
+	"gif":  true,
+	"webp": true,
+}
+
+// Transformer resizes images with pure-Go codecs. Decoding of WebP is supported, encoding is not
+type Transformer struct {
+	cfg config.ImagesConfig
+}
+
+func ProvideTransformer(cfg config.Configuration) *Transformer {
+	return &Transformer{cfg: cfg.Images}
+}
+
+// Transform checks header of image before decoding: format is detected by content, not by declared type,
+// and size of image is limited by pixels. Source is read up to MaxBytes
+func (t *Transformer) Transform(source io.Reader, params dto.ImageParams) ([]byte, error) {
+	limited := &io.LimitedReader{R: source, N: t.cfg.MaxBytes + 1}
+	head := new(bytes.Buffer)
+	imageCfg, format, err := image.DecodeConfig(io.TeeReader(limited, head))
+	if err != nil {
+		return nil, errors.Wrap(err, "Transformer.DecodeConfig")
+	}
+	if !decodableFormats[format] {
+		return nil, errors.New("Transformer: format " + format + " can not be resized")
+	}
+	if int64(imageCfg.Width)*int64(imageCfg.Height) > int64(t.cfg.MaxPixels) {
+		return nil, errors.New("Transformer: image is larger than " + strconv.Itoa(t.cfg.MaxPixels) + " pixels")
+	}
+	src, _, err := image.Decode(io.MultiReader(head, limited))
+	if limited.N <= 0 {
+		return nil, errors.New("Transformer: file is larger than " + strconv.FormatInt(t.cfg.MaxBytes, 10) + " bytes")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Transformer.Decode")
+	}
+	dst := Resize(src, params)
+	buf := new(bytes.Buffer)
+	if params.Format == dto.ImageFormatPng {
+		err = png.Encode(buf, dst)
+	} else {
+		err = jpeg.Encode(buf, dst, &jpeg.Options{Quality: t.cfg.Quality})
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Transformer.Encode")
+	}
+	return buf.Bytes(), nil
+}
+
+// Resize scales image by fit mode:
+// contain - image fits in box, cover - image covers box and is cropped by center, fill - image is stretched to box.
+// Image is never enlarged in contain mode
+func Resize(src image.Image, params dto.ImageParams) image.Image {
+	srcRect := src.Bounds()
+	width, height := srcRect.Dx(), srcRect.Dy()
+	boxWidth, boxHeight := params.Width, params.Height
+	switch {
+	case boxWidth == 0 && boxHeight == 0:
+		boxWidth, boxHeight = width, height
+	case boxWidth == 0:
+		boxWidth = max(1, width*boxHeight/height)
+	case boxHeight == 0:
+		boxHeight = max(1, height*boxWidth/width)
+	}
+	dstWidth, dstHeight := boxWidth, boxHeight
+	switch params.Fit {
+	case dto.ImageFitCover:
+		srcRect = coverCrop(srcRect, boxWidth, boxHeight)
+	case dto.ImageFitFill:
+	default:
+		dstWidth, dstHeight = FitSize(width, height, boxWidth, boxHeight)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	if params.Format != dto.ImageFormatPng {
+		// jpeg has no transparency
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	}
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, srcRect, draw.Over, nil)
+	return dst
+}
+
+// coverCrop returns central part of rect with proportions of box
+func coverCrop(rect image.Rectangle, boxWidth, boxHeight int) image.Rectangle {
+	width, height := rect.Dx(), rect.Dy()
+	if width*boxHeight > height*boxWidth {
+		cropWidth := max(1, height*boxWidth/boxHeight)
+		offset := (width - cropWidth) / 2
+		return image.Rect(rect.Min.X+offset, rect.Min.Y, rect.Min.X+offset+cropWidth, rect.Max.Y)
+	}
+	cropHeight := max(1, width*boxHeight/boxWidth)
+	offset := (height - cropHeight) / 2
+	return image.Rect(rect.Min.X, rect.Min.Y+offset, rect.Max.X, rect.Min.Y+offset+cropHeight)
+}
+
+// FitSize scales width and height to fit in box with saving of proportions. Image is never enlarged
+func FitSize(width, height, maxWidth, maxHeight int) (int, int) {
+	if width <= maxWidth && height <= maxHeight {
+		return width, height
+	}
+	if width*maxHeight > height*maxWidth {
+		return maxWidth, max(1, height*maxWidth/width)
+	}
+	return max(1, width*maxHeight/height), maxHeight
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
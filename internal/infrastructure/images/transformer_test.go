@@ -0,0 +1,79 @@
+package images
+
+import (
+	"bytes"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/stretchr/testify/suite"
+	"image"
+	"image/png"
+	"testing"
+)
+
+type suiteTransformer struct {
+	suite.Suite
+	transformer *Transformer
+}
+
+func TestTransformer(t *testing.T) {
+	suite.Run(t, new(suiteTransformer))
+}
+
+func (s *suiteTransformer) SetupTest() {
+	s.transformer = &Transformer{cfg: config.ImagesConfig{}.AfterLoad()}
+}
+
+func (s *suiteTransformer) makePng(width, height int) []byte {
+	buf := new(bytes.Buffer)
+	s.Require().Nil(png.Encode(buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func (s *suiteTransformer) TestTransform() {
+	content, err := s.transformer.Transform(bytes.NewReader(s.makePng(100, 50)), dto.ImageParams{
+		Width:  20,
+		Fit:    dto.ImageFitContain,
+		Format: dto.ImageFormatJpeg,
+	})
+	s.Require().Nil(err)
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(content))
+	s.Require().Nil(err)
+	s.Equal("jpeg", format)
+	s.Equal(20, cfg.Width)
+	s.Equal(10, cfg.Height)
+}
+
+func (s *suiteTransformer) TestNotImage() {
+	_, err := s.transformer.Transform(bytes.NewReader([]byte("<svg></svg>")), dto.ImageParams{Format: dto.ImageFormatPng})
+	s.NotNil(err)
+}
+
+func (s *suiteTransformer) TestMaxPixels() {
+	s.transformer.cfg.MaxPixels = 100
+	_, err := s.transformer.Transform(bytes.NewReader(s.makePng(20, 20)), dto.ImageParams{Format: dto.ImageFormatPng})
+	s.Require().NotNil(err)
+	s.Contains(err.Error(), "pixels")
+}
+
+func (s *suiteTransformer) TestMaxBytes() {
+	content := s.makePng(20, 20)
+	s.transformer.cfg.MaxBytes = int64(len(content)) - 1
+	_, err := s.transformer.Transform(bytes.NewReader(content), dto.ImageParams{Format: dto.ImageFormatPng})
+	s.Require().NotNil(err)
+	s.Contains(err.Error(), "bytes")
+}
+
+func (s *suiteTransformer) TestResizeCover() {
+	dst := Resize(image.NewRGBA(image.Rect(0, 0, 100, 50)), dto.ImageParams{Width: 30, Height: 30, Fit: dto.ImageFitCover})
+	s.Equal(30, dst.Bounds().Dx())
+	s.Equal(30, dst.Bounds().Dy())
+}
+
+func (s *suiteTransformer) TestFitSize() {
+	width, height := FitSize(100, 50, 20, 20)
+	s.Equal(20, width)
+	s.Equal(10, height)
+	width, height = FitSize(10, 5, 20, 20)
+	s.Equal(10, width)
+	s.Equal(5, height)
+}
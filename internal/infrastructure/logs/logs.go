@@ -5,11 +5,11 @@ import (
 	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
 )
 
-var ls logsEngine.Loggers
+var ls *logsEngine.Loggers
 
 func ProvideLoggers(cfg config.Configuration) *logsEngine.Loggers {
 	if nil == ls {
-		ls = logsEngine.InitLoggersByConfig(cfg.Logs, config.ProjectName, true)
+		ls = logsEngine.InitLoggersByConfig(cfg.Logs, cfg.Logging, config.ProjectName, true)
 	}
-	return &ls
+	return ls
 }
@@ -8,5 +8,5 @@ var defaultLoggers = LogConfigs{
 }
 
 func InitLoggersEmpty(projectName string) Loggers {
-	return InitLoggersByConfig(defaultLoggers, projectName, true)
+	return *InitLoggersByConfig(defaultLoggers, Options{}, projectName, true)
 }
@@ -0,0 +1,44 @@
+package logsEngine
+
+import (
+	"github.com/pkg/errors"
+	"sync/atomic"
+)
+
+// levels are ordered by severity, records of level lower than current are dropped
+var levels = []string{LogTrace, LogProfile, LogDebug, LogInfo, LogError, LogCritical, LogFatal}
+
+// Level is minimal level of records, it may be changed concurrently. Zero value passes all records
+type Level struct {
+	rank int32
+}
+
+func (l *Level) Set(name string) error {
+	rank := levelRank(name)
+	if rank < 0 {
+		return errors.New("unknown log level " + name)
+	}
+	atomic.StoreInt32(&l.rank, int32(rank))
+	return nil
+}
+
+func (l *Level) String() string {
+	return levels[atomic.LoadInt32(&l.rank)]
+}
+
+func (l *Level) Enabled(rank int) bool {
+	return int32(rank) >= atomic.LoadInt32(&l.rank)
+}
+
+func levelRank(name string) int {
+	for rank, level := range levels {
+		if level == name {
+			return rank
+		}
+	}
+	return -1
+}
+
+func errUnknownFormat(format string) error {
+	return errors.New("unknown log format " + format)
+}
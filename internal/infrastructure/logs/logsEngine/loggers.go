@@ -2,6 +2,8 @@ package logsEngine
 
 import (
 	"bytes"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"io"
 	"log"
 	"os"
 )
@@ -18,6 +20,11 @@ const (
 	LogProfile  = "profile"
 )
 
+const (
+	FormatText = "text"
+	FormatJson = "json"
+)
+
 type LogConfig struct {
 	Prefix      string      `json:"prefix"`
 	MetricsOpts CounterOpts `json:"metricsOpts"`
@@ -25,69 +32,133 @@ type LogConfig struct {
 
 type LogConfigs map[string]LogConfig
 
-type Loggers map[string]*log.Logger
+// Options are common for all loggers: format of records and minimal level, which is written
+type Options struct {
+	Format string `json:"format"`
+	Level  string `json:"level"`
+}
+
+// Loggers writes records of named levels. Loggers made by With share outputs and level with parent
+type Loggers struct {
+	loggers map[string]*log.Logger
+	outputs map[string]io.Writer
+	configs LogConfigs
+	format  string
+	level   *Level
+	service string
+	host    string
+	fields  [][2]string
+}
 
-func (l Loggers) G(name string) *log.Logger {
-	logger, ok := l[name]
+func (l *Loggers) G(name string) *log.Logger {
+	logger, ok := l.loggers[name]
 	if !ok {
 		panic("No logger " + name)
 	}
 	return logger
 }
 
-func (l Loggers) Trace() *log.Logger {
+func (l *Loggers) Trace() *log.Logger {
 	return l.G(LogTrace)
 }
 
-func (l Loggers) Debug() *log.Logger {
+func (l *Loggers) Debug() *log.Logger {
 	return l.G(LogDebug)
 }
 
-func (l Loggers) Error() *log.Logger {
+func (l *Loggers) Error() *log.Logger {
 	return l.G(LogError)
 }
 
-func (l Loggers) Critical() *log.Logger {
+func (l *Loggers) Critical() *log.Logger {
 	return l.G(LogCritical)
 }
 
-func InitLogger(project string, configs LogConfigs) (Loggers, error) {
-	loggers := make(map[string]*log.Logger)
-	for name, c := range configs {
-		if name == NameForStdLogger {
-			continue
-		}
+// With returns loggers, which add fields to every record
+func (l *Loggers) With(fields ...[2]string) port.Logger {
+	if len(fields) == 0 {
+		return l
+	}
+	result := *l
+	result.fields = make([][2]string, 0, len(l.fields)+len(fields))
+	result.fields = append(result.fields, l.fields...)
+	result.fields = append(result.fields, fields...)
+	result.loggers = make(map[string]*log.Logger, len(l.loggers))
+	for name := range l.loggers {
+		result.loggers[name] = result.newLogger(name)
+	}
+	return &result
+}
 
-		l, err := initLogger(project, c)
+// Level returns name of minimal level, which is written
+func (l *Loggers) Level() string {
+	return l.level.String()
+}
 
-		if err != nil {
-			return nil, err
-		}
+// SetLevel changes minimal level of all loggers at runtime
+func (l *Loggers) SetLevel(level string) error {
+	return l.level.Set(level)
+}
 
-		loggers[name] = l
+func (l *Loggers) newLogger(name string) *log.Logger {
+	writer := &recordWriter{
+		name:    name,
+		rank:    levelRank(name),
+		output:  l.outputs[name],
+		format:  l.format,
+		level:   l.level,
+		service: l.service,
+		host:    l.host,
+		fields:  l.fields,
 	}
+	if l.format == FormatJson {
+		return log.New(writer, "", log.Lshortfile)
+	}
+	return log.New(writer, prefix(l.service+" (host: "+l.host+")", l.configs[name].Prefix), flags())
+}
 
-	c, ok := configs[NameForStdLogger]
-
-	if ok {
-		err := initStdLogger(project, c)
-		if err != nil {
+func InitLogger(project string, host string, configs LogConfigs, opts Options) (*Loggers, error) {
+	level := new(Level)
+	if opts.Level != "" {
+		if err := level.Set(opts.Level); err != nil {
 			return nil, err
 		}
 	}
+	format := opts.Format
+	if format == "" {
+		format = FormatText
+	}
+	if format != FormatText && format != FormatJson {
+		return nil, errUnknownFormat(format)
+	}
+	ls := &Loggers{
+		loggers: make(map[string]*log.Logger),
+		outputs: make(map[string]io.Writer),
+		configs: configs,
+		format:  format,
+		level:   level,
+		service: project,
+		host:    host,
+	}
+	for name, c := range configs {
+		ls.outputs[name] = NewWriterWithCounter(os.Stderr, c.MetricsOpts)
+		if name == NameForStdLogger {
+			continue
+		}
+		ls.loggers[name] = ls.newLogger(name)
+	}
 
-	return loggers, nil
-}
+	if _, ok := configs[NameForStdLogger]; ok {
+		initStdLogger(ls.newLogger(NameForStdLogger))
+	}
 
-func initLogger(p string, c LogConfig) (*log.Logger, error) {
-	return log.New(NewWriterWithCounter(os.Stderr, c.MetricsOpts), prefix(p, c.Prefix), flags()), nil
+	return ls, nil
 }
 
-func initStdLogger(p string, c LogConfig) error {
-	log.SetPrefix(prefix(p, c.Prefix))
-	log.SetFlags(flags())
-
-	return nil
+func initStdLogger(l *log.Logger) {
+	log.SetOutput(l.Writer())
+	log.SetPrefix(l.Prefix())
+	log.SetFlags(l.Flags())
 }
 
 func prefix(p string, pr string) string {
@@ -109,7 +180,7 @@ func flags() int {
 	return log.Ldate | log.Ltime | log.LUTC | log.Lmicroseconds | log.Lshortfile
 }
 
-func InitLoggersByConfig(cfg LogConfigs, projectName string, fatalIfFail bool) Loggers {
+func InitLoggersByConfig(cfg LogConfigs, opts Options, projectName string, fatalIfFail bool) *Loggers {
 	hn, err := os.Hostname()
 	if err != nil {
 		log.Println("Can't get hostname")
@@ -125,7 +196,7 @@ func InitLoggersByConfig(cfg LogConfigs, projectName string, fatalIfFail bool) L
 		}
 	}
 
-	ls, err := InitLogger(projectName+" (host: "+hn+")", cfg)
+	ls, err := InitLogger(projectName, hn, cfg, opts)
 	if fatalIfFail && err != nil {
 		log.Fatalf("Error logger initialization: %s", err)
 	}
@@ -0,0 +1,94 @@
+package logsEngine
+
+import (
+	"bytes"
+	jsoniter "github.com/json-iterator/go"
+	"io"
+	"time"
+)
+
+// recordWriter drops records below current level and adds fields to records.
+// In JSON format it turns output of log.Logger with log.Lshortfile flag into a JSON object per line
+type recordWriter struct {
+	name    string
+	rank    int
+	output  io.Writer
+	format  string
+	level   *Level
+	service string
+	host    string
+	fields  [][2]string
+}
+
+func (w *recordWriter) Write(p []byte) (int, error) {
+	// records of loggers out of ordered levels (STD) are always written
+	if w.rank >= 0 && !w.level.Enabled(w.rank) {
+		return len(p), nil
+	}
+	var record []byte
+	if w.format == FormatJson {
+		record = w.json(p)
+	} else {
+		record = w.text(p)
+	}
+	if _, err := w.output.Write(record); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *recordWriter) text(p []byte) []byte {
+	if len(w.fields) == 0 {
+		return p
+	}
+	buffer := bytes.NewBuffer(make([]byte, 0, len(p)+64))
+	buffer.Write(bytes.TrimSuffix(p, []byte("\n")))
+	for _, field := range w.fields {
+		buffer.WriteString(" ")
+		buffer.WriteString(field[0])
+		buffer.WriteString("=")
+		buffer.WriteString(field[1])
+	}
+	buffer.WriteString("\n")
+	return buffer.Bytes()
+}
+
+func (w *recordWriter) json(p []byte) []byte {
+	message := string(bytes.TrimSuffix(p, []byte("\n")))
+	caller := ""
+	// log.Lshortfile writes "file.go:line: " before message
+	if idx := bytes.Index(p, []byte(": ")); idx > 0 && bytes.IndexByte(p[:idx], ' ') < 0 {
+		caller = string(p[:idx])
+		message = message[idx+2:]
+	}
+	stream := jsoniter.ConfigDefault.BorrowStream(nil)
+	defer jsoniter.ConfigDefault.ReturnStream(stream)
+	stream.WriteObjectStart()
+	writeJsonField(stream, "time", time.Now().UTC().Format(time.RFC3339Nano))
+	stream.WriteMore()
+	writeJsonField(stream, "level", w.name)
+	stream.WriteMore()
+	writeJsonField(stream, "service", w.service)
+	stream.WriteMore()
+	writeJsonField(stream, "host", w.host)
+	if caller != "" {
+		stream.WriteMore()
+		writeJsonField(stream, "caller", caller)
+	}
+	stream.WriteMore()
+	writeJsonField(stream, "msg", message)
+	for _, field := range w.fields {
+		stream.WriteMore()
+		writeJsonField(stream, field[0], field[1])
+	}
+	stream.WriteObjectEnd()
+	stream.WriteRaw("\n")
+	record := make([]byte, len(stream.Buffer()))
+	copy(record, stream.Buffer())
+	return record
+}
+
+func writeJsonField(stream *jsoniter.Stream, name string, value string) {
+	stream.WriteObjectField(name)
+	stream.WriteString(value)
+}
@@ -33,6 +33,13 @@ func NewWriterWithCounter(writer io.Writer, opts CounterOpts) *WriterWithCounter
 		Subsystem: subsystem,
 		Name:      opts.Name,
 	})
-	prometheus.MustRegister(w.counter)
+	if err := prometheus.Register(w.counter); err != nil {
+		// loggers are built again with stack, counter of the same name is shared
+		registered, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			panic(err)
+		}
+		w.counter = registered.ExistingCollector.(prometheus.Counter)
+	}
 	return w
 }
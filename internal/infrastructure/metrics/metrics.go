@@ -14,6 +14,22 @@ var (
 
 var gHealth *health.Health
 
+// Register registers collectors, which are package variables, collectors registered before are skipped
+func Register(collectors ...prometheus.Collector) {
+	for _, c := range collectors {
+		if err := prometheus.Register(c); err != nil {
+			if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+				panic(err)
+			}
+		}
+	}
+}
+
+// ProvideHealth returns state of process, which is exported to metrics
+func ProvideHealth() *health.Health {
+	return gHealth
+}
+
 func init() {
 	gHealth = health.NewHealth()
 	gHealth.RegisterCollector(config.ProjectMetricsNamespace)
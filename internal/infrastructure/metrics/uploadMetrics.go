@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"time"
+)
+
+var ChunkSizeBuckets = prometheus.ExponentialBuckets(1024*1024, 2, 12)
+
+var uploadMetrics = &UploadMetrics{
+	uploads: prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.ProjectMetricsNamespace,
+		Subsystem: "uploader",
+		Name:      "uploads_count",
+	}, []string{"status", "reason"}),
+	chunkBytes: prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: config.ProjectMetricsNamespace,
+		Subsystem: "uploader",
+		Name:      "chunk_bytes",
+		Buckets:   ChunkSizeBuckets,
+	}),
+	chunkDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: config.ProjectMetricsNamespace,
+		Subsystem: "uploader",
+		Name:      "chunk_duration",
+		Buckets:   StdHttpBuckets,
+	}),
+	composeDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: config.ProjectMetricsNamespace,
+		Subsystem: "composer",
+		Name:      "compose_duration",
+		Buckets:   StdHttpBuckets,
+	}),
+	composeQueue: prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: config.ProjectMetricsNamespace,
+		Subsystem: "composer",
+		Name:      "queue_depth",
+	}),
+	callbacks: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: config.ProjectMetricsNamespace,
+		Subsystem: "callbacks",
+		Name:      "callback_duration",
+		Buckets:   StdHttpBuckets,
+	}, []string{"type", "outcome"}),
+}
+
+type UploadMetrics struct {
+	uploads         *prometheus.CounterVec
+	chunkBytes      prometheus.Histogram
+	chunkDuration   prometheus.Histogram
+	composeDuration prometheus.Histogram
+	composeQueue    prometheus.Gauge
+	callbacks       *prometheus.HistogramVec
+}
+
+// ProvideUploadMetrics returns shared metrics, so stack can be built more than once in process
+func ProvideUploadMetrics() *UploadMetrics {
+	m := uploadMetrics
+	Register(m.uploads, m.chunkBytes, m.chunkDuration, m.composeDuration, m.composeQueue, m.callbacks)
+	return m
+}
+
+func (m *UploadMetrics) UploadStarted() {
+	m.uploads.WithLabelValues("started", "").Inc()
+}
+
+func (m *UploadMetrics) UploadCompleted() {
+	m.uploads.WithLabelValues("completed", "").Inc()
+}
+
+func (m *UploadMetrics) UploadFailed(reason string) {
+	m.uploads.WithLabelValues("failed", reason).Inc()
+}
+
+func (m *UploadMetrics) ChunkReceived(size int64, duration time.Duration) {
+	m.chunkBytes.Observe(float64(size))
+	m.chunkDuration.Observe(duration.Seconds())
+}
+
+func (m *UploadMetrics) ComposeDone(duration time.Duration) {
+	m.composeDuration.Observe(duration.Seconds())
+}
+
+func (m *UploadMetrics) ComposeQueueDepth(depth int) {
+	m.composeQueue.Set(float64(depth))
+}
+
+func (m *UploadMetrics) CallbackDone(callbackType string, outcome string, duration time.Duration) {
+	m.callbacks.WithLabelValues(callbackType, outcome).Observe(duration.Seconds())
+}
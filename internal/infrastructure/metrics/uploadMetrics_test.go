@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type suiteUploadMetrics struct {
+	suite.Suite
+}
+
+func TestUploadMetrics(t *testing.T) {
+	suite.Run(t, new(suiteUploadMetrics))
+}
+
+func (s *suiteUploadMetrics) TestProvideTwice() {
+	var first, second *UploadMetrics
+	s.NotPanics(func() {
+		first = ProvideUploadMetrics()
+		second = ProvideUploadMetrics()
+	})
+	s.Same(first, second)
+}
@@ -0,0 +1,39 @@
+package processors
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"io"
+)
+
+// Checksum calculates sha256 and md5 of file
+type Checksum struct {
+}
+
+func NewChecksum() *Checksum {
+	return new(Checksum)
+}
+
+func (c *Checksum) GetName() string {
+	return "checksum"
+}
+
+func (c *Checksum) Accepts(string) bool {
+	return true
+}
+
+func (c *Checksum) Process(source *io.SectionReader, _ string, _ port.DerivedOutput) (map[string]interface{}, error) {
+	sha, md := sha256.New(), md5.New()
+	size, err := io.Copy(io.MultiWriter(sha, md), source)
+	if err != nil {
+		return nil, errors.Wrap(err, "Checksum.Process")
+	}
+	return map[string]interface{}{
+		"sha256": hex.EncodeToString(sha.Sum(nil)),
+		"md5":    hex.EncodeToString(md.Sum(nil)),
+		"size":   size,
+	}, nil
+}
@@ -0,0 +1,87 @@
+package processors
+
+import (
+	"bufio"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"io"
+)
+
+const (
+	noExifSuffix = ".noexif.jpg"
+	markerSOI    = 0xD8
+	markerSOS    = 0xDA
+	markerAPP1   = 0xE1
+)
+
+// ExifStripper makes copy of jpeg without APP1 segments (EXIF and XMP)
+type ExifStripper struct {
+}
+
+func NewExifStripper() *ExifStripper {
+	return new(ExifStripper)
+}
+
+func (e *ExifStripper) GetName() string {
+	return "exif_strip"
+}
+
+func (e *ExifStripper) Accepts(contentType string) bool {
+	return contentType == "image/jpeg"
+}
+
+func (e *ExifStripper) Process(source *io.SectionReader, _ string, output port.DerivedOutput) (map[string]interface{}, error) {
+	in := bufio.NewReader(source)
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(in, head); err != nil || head[0] != 0xFF || head[1] != markerSOI {
+		return nil, errors.New("ExifStripper: not a jpeg")
+	}
+	derived, err := output.Create(noExifSuffix, "image/jpeg")
+	if err != nil {
+		return nil, errors.Wrap(err, "ExifStripper.Create")
+	}
+	out := bufio.NewWriter(derived)
+	_, _ = out.Write(head)
+	stripped := 0
+	for {
+		if _, err = io.ReadFull(in, head); err != nil {
+			return nil, errors.Wrap(err, "ExifStripper.ReadMarker")
+		}
+		if head[0] != 0xFF {
+			return nil, errors.New("ExifStripper: broken jpeg")
+		}
+		if head[1] == markerSOS {
+			// compressed data follows, it is copied as is
+			_, _ = out.Write(head)
+			if _, err = io.Copy(out, in); err != nil {
+				return nil, errors.Wrap(err, "ExifStripper.Copy")
+			}
+			break
+		}
+		length := make([]byte, 2)
+		if _, err = io.ReadFull(in, length); err != nil {
+			return nil, errors.Wrap(err, "ExifStripper.ReadLength")
+		}
+		segmentSize := int64(binary.BigEndian.Uint16(length))
+		if segmentSize < 2 {
+			return nil, errors.New("ExifStripper: broken segment")
+		}
+		if head[1] == markerAPP1 {
+			stripped++
+			if _, err = in.Discard(int(segmentSize - 2)); err != nil {
+				return nil, errors.Wrap(err, "ExifStripper.SkipSegment")
+			}
+			continue
+		}
+		_, _ = out.Write(head)
+		_, _ = out.Write(length)
+		if _, err = io.CopyN(out, in, segmentSize-2); err != nil {
+			return nil, errors.Wrap(err, "ExifStripper.CopySegment")
+		}
+	}
+	if err = out.Flush(); err != nil {
+		return nil, errors.Wrap(err, "ExifStripper.Flush")
+	}
+	return map[string]interface{}{"stripped_segments": stripped}, nil
+}
@@ -0,0 +1,142 @@
+package processors
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/stretchr/testify/suite"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+)
+
+type derivedBuffer struct {
+	suffix      string
+	contentType string
+	content     bytes.Buffer
+}
+
+// fakeOutput keeps derived objects in memory
+type fakeOutput struct {
+	objects []*derivedBuffer
+}
+
+func (f *fakeOutput) Create(suffix string, contentType string) (io.Writer, error) {
+	object := &derivedBuffer{suffix: suffix, contentType: contentType}
+	f.objects = append(f.objects, object)
+	return &object.content, nil
+}
+
+type suiteProcessors struct {
+	suite.Suite
+	output *fakeOutput
+}
+
+func TestProcessors(t *testing.T) {
+	suite.Run(t, new(suiteProcessors))
+}
+
+func (s *suiteProcessors) SetupTest() {
+	s.output = &fakeOutput{}
+}
+
+func (s *suiteProcessors) source(content []byte) *io.SectionReader {
+	return io.NewSectionReader(bytes.NewReader(content), 0, int64(len(content)))
+}
+
+func (s *suiteProcessors) makeImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, x%height, color.RGBA{R: 255, A: 255})
+	}
+	return img
+}
+
+func (s *suiteProcessors) TestChecksum() {
+	data, err := NewChecksum().Process(s.source([]byte("content")), "text/plain", s.output)
+	s.Require().Nil(err)
+	s.Equal("9a0364b9e99bb480dd25e1f0284c8555", data["md5"])
+	s.Equal("ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73", data["sha256"])
+	s.Equal(int64(7), data["size"])
+	s.Equal(0, len(s.output.objects))
+}
+
+func (s *suiteProcessors) TestExifStripper() {
+	buf := new(bytes.Buffer)
+	s.Require().Nil(jpeg.Encode(buf, s.makeImage(8, 8), nil))
+	original := buf.Bytes()
+	app1 := append([]byte{0xFF, markerAPP1, 0x00, 0x08}, []byte("Exif\x00\x00")...)
+	withExif := append(append(append([]byte{}, original[:2]...), app1...), original[2:]...)
+
+	data, err := NewExifStripper().Process(s.source(withExif), "image/jpeg", s.output)
+	s.Require().Nil(err)
+	s.Equal(1, data["stripped_segments"])
+	s.Require().Equal(1, len(s.output.objects))
+	s.Equal(noExifSuffix, s.output.objects[0].suffix)
+	s.Equal("image/jpeg", s.output.objects[0].contentType)
+	s.Equal(original, s.output.objects[0].content.Bytes())
+}
+
+func (s *suiteProcessors) TestExifStripperNotJpeg() {
+	_, err := NewExifStripper().Process(s.source([]byte("not a jpeg")), "image/jpeg", s.output)
+	s.NotNil(err)
+	s.Equal(0, len(s.output.objects))
+}
+
+func (s *suiteProcessors) TestThumbnail() {
+	buf := new(bytes.Buffer)
+	s.Require().Nil(png.Encode(buf, s.makeImage(100, 50)))
+	thumbnail := NewThumbnail(config.ThumbnailConfig{Width: 20, Height: 20, Quality: 80})
+
+	data, err := thumbnail.Process(s.source(buf.Bytes()), "image/png", s.output)
+	s.Require().Nil(err)
+	s.Equal(100, data["width"])
+	s.Equal(50, data["height"])
+	s.Equal(20, data["thumb_width"])
+	s.Equal(10, data["thumb_height"])
+	s.Require().Equal(1, len(s.output.objects))
+	s.Equal(thumbnailSuffix, s.output.objects[0].suffix)
+	cfg, format, err := image.DecodeConfig(&s.output.objects[0].content)
+	s.Require().Nil(err)
+	s.Equal("jpeg", format)
+	s.Equal(20, cfg.Width)
+	s.Equal(10, cfg.Height)
+}
+
+func (s *suiteProcessors) TestZipList() {
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		w, err := archive.Create(name)
+		s.Require().Nil(err)
+		_, _ = w.Write([]byte("content of " + name))
+	}
+	s.Require().Nil(archive.Close())
+
+	data, err := NewZipList(config.ZipListConfig{MaxEntries: 2}).Process(s.source(buf.Bytes()), "application/zip", s.output)
+	s.Require().Nil(err)
+	s.Equal(3, data["total"])
+	s.Equal(true, data["truncated"])
+	entries := data["entries"].([]zipEntry)
+	s.Require().Equal(2, len(entries))
+	s.Equal("a.txt", entries[0].Name)
+	s.Equal(uint64(len("content of a.txt")), entries[0].Size)
+}
+
+func (s *suiteProcessors) TestZipListBroken() {
+	_, err := NewZipList(config.ZipListConfig{MaxEntries: 2}).Process(s.source([]byte("not a zip")), "application/zip", s.output)
+	s.NotNil(err)
+}
+
+func (s *suiteProcessors) TestRegistry() {
+	registry := ProvideProcessorsRegistry(config.Configuration{})
+	for _, name := range []string{"checksum", "thumbnail", "exif_strip", "zip_list", "ZIP_LIST"} {
+		_, ok := registry.GetProcessor(name)
+		s.True(ok, name)
+	}
+	_, ok := registry.GetProcessor("unknown")
+	s.False(ok)
+}
@@ -0,0 +1,38 @@
+package processors
+
+import (
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"strings"
+)
+
+// Registry keeps built-in processors by name
+type Registry struct {
+	processors map[string]port.FileProcessor
+}
+
+func ProvideProcessorsRegistry(cfg config.Configuration) *Registry {
+	r := &Registry{processors: make(map[string]port.FileProcessor)}
+	r.Register(NewChecksum())
+	r.Register(NewThumbnail(cfg.Processors.Thumbnail))
+	r.Register(NewExifStripper())
+	r.Register(NewZipList(cfg.Processors.ZipList))
+	return r
+}
+
+func (r *Registry) Register(processor port.FileProcessor) {
+	r.processors[strings.ToLower(processor.GetName())] = processor
+}
+
+func (r *Registry) GetProcessor(name string) (port.FileProcessor, bool) {
+	processor, ok := r.processors[strings.ToLower(name)]
+	return processor, ok
+}
+
+func isImage(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
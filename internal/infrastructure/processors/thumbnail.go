@@ -0,0 +1,61 @@
+package processors
+
+import (
+	"bufio"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/internal/infrastructure/images"
+	"golang.org/x/image/draw"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+const thumbnailSuffix = ".thumb.jpg"
+
+// Thumbnail makes jpeg preview of image, which fits in configured box
+type Thumbnail struct {
+	cfg config.ThumbnailConfig
+}
+
+func NewThumbnail(cfg config.ThumbnailConfig) *Thumbnail {
+	return &Thumbnail{cfg: cfg}
+}
+
+func (t *Thumbnail) GetName() string {
+	return "thumbnail"
+}
+
+func (t *Thumbnail) Accepts(contentType string) bool {
+	return isImage(contentType)
+}
+
+func (t *Thumbnail) Process(source *io.SectionReader, _ string, output port.DerivedOutput) (map[string]interface{}, error) {
+	src, _, err := image.Decode(source)
+	if err != nil {
+		return nil, errors.Wrap(err, "Thumbnail.Decode")
+	}
+	width, height := images.FitSize(src.Bounds().Dx(), src.Bounds().Dy(), t.cfg.Width, t.cfg.Height)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+	derived, err := output.Create(thumbnailSuffix, "image/jpeg")
+	if err != nil {
+		return nil, errors.Wrap(err, "Thumbnail.Create")
+	}
+	out := bufio.NewWriter(derived)
+	if err = jpeg.Encode(out, dst, &jpeg.Options{Quality: t.cfg.Quality}); err != nil {
+		return nil, errors.Wrap(err, "Thumbnail.Encode")
+	}
+	if err = out.Flush(); err != nil {
+		return nil, errors.Wrap(err, "Thumbnail.Flush")
+	}
+	return map[string]interface{}{
+		"width":        src.Bounds().Dx(),
+		"height":       src.Bounds().Dy(),
+		"thumb_width":  width,
+		"thumb_height": height,
+	}, nil
+}
@@ -0,0 +1,55 @@
+package processors
+
+import (
+	"archive/zip"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"io"
+)
+
+// ZipList lists entries of zip archive. Only central directory of archive is read
+type ZipList struct {
+	cfg config.ZipListConfig
+}
+
+type zipEntry struct {
+	Name           string `json:"name"`
+	Size           uint64 `json:"size"`
+	CompressedSize uint64 `json:"compressed_size"`
+}
+
+func NewZipList(cfg config.ZipListConfig) *ZipList {
+	return &ZipList{cfg: cfg}
+}
+
+func (z *ZipList) GetName() string {
+	return "zip_list"
+}
+
+func (z *ZipList) Accepts(contentType string) bool {
+	return contentType == "application/zip"
+}
+
+func (z *ZipList) Process(source *io.SectionReader, _ string, _ port.DerivedOutput) (map[string]interface{}, error) {
+	reader, err := zip.NewReader(source, source.Size())
+	if err != nil {
+		return nil, errors.Wrap(err, "ZipList.NewReader")
+	}
+	entries := make([]zipEntry, 0, len(reader.File))
+	for i, file := range reader.File {
+		if i >= z.cfg.MaxEntries {
+			break
+		}
+		entries = append(entries, zipEntry{
+			Name:           file.Name,
+			Size:           file.UncompressedSize64,
+			CompressedSize: file.CompressedSize64,
+		})
+	}
+	return map[string]interface{}{
+		"total":     len(reader.File),
+		"entries":   entries,
+		"truncated": len(reader.File) > len(entries),
+	}, nil
+}
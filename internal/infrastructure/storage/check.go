@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"github.com/minio/minio-go/v7"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"strconv"
+	"time"
+)
+
+const checkObjectPrefix = ".filup-check-"
+
+// StorageCheck checks storage before start of server, it does not create buckets
+type StorageCheck struct {
+	storage *MinioS3
+}
+
+// BucketAccess - result of check of one bucket, Err is nil if bucket is usable
+type BucketAccess struct {
+	Bucket string
+	Err    error
+}
+
+func ProvideStorageCheck(cfg config.Configuration, cc port.ContextProvider) (*StorageCheck, error) {
+	m, err := newMinioS3(cfg, cc)
+	if err != nil {
+		return nil, err
+	}
+	return &StorageCheck{storage: m}, nil
+}
+
+// Check verifies credentials, existence of buckets and permissions to write, read and remove objects
+func (sc *StorageCheck) Check(ctx context.Context) []BucketAccess {
+	var result []BucketAccess
+	seen := make(map[string]bool)
+	for _, bucket := range sc.storage.buckets() {
+		if seen[bucket] {
+			continue
+		}
+		seen[bucket] = true
+		result = append(result, BucketAccess{Bucket: bucket, Err: sc.checkBucket(ctx, bucket)})
+	}
+	return result
+}
+
+func (sc *StorageCheck) checkBucket(ctx context.Context, bucket string) error {
+	m := sc.storage
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.GetTimeout())
+	defer cancel()
+	exists, err := m.client.BucketExists(ctx, bucket)
+	if err != nil {
+		return errors.Wrap(err, "StorageCheck.BucketExists")
+	}
+	if !exists {
+		return errors.New("bucket does not exist")
+	}
+	name := checkObjectPrefix + strconv.FormatInt(time.Now().UnixNano(), 10)
+	content := []byte(name)
+	if err = m.putFile("text/plain", bucket, name, content); err != nil {
+		return errors.Wrap(err, "StorageCheck.write")
+	}
+	read, err := m.getFile(bucket, name)
+	if err == nil && string(read) != string(content) {
+		err = errors.New("content of object differs")
+	}
+	removeErr := m.client.RemoveObject(ctx, bucket, name, minio.RemoveObjectOptions{})
+	if err != nil {
+		return errors.Wrap(err, "StorageCheck.read")
+	}
+	if removeErr != nil {
+		return errors.Wrap(removeErr, "StorageCheck.remove")
+	}
+	return nil
+}
@@ -5,61 +5,113 @@ import (
 	"context"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	minioTags "github.com/minio/minio-go/v7/pkg/tags"
 	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
 	"github.com/satmaelstorm/filup/internal/domain/port"
 	"github.com/satmaelstorm/filup/internal/infrastructure/config"
 	"io"
 	"strings"
 )
 
+const noSuchKeyCode = "NoSuchKey"
+
 type MinioS3 struct {
 	client    *minio.Client
 	cfg       config.S3Config
 	ctx       context.Context
 	metaCache port.MetaCacheController
+
+	namespacesBuckets []string
+	quarantineBucket  string
+	imagesBucket      string
+	sse               sseKeys
 }
 
 var storageClient *MinioS3
 
 func ProvideMinioS3(cfg config.Configuration, cc port.ContextProvider, cache port.MetaCacheController) (*MinioS3, error) {
 	if nil == storageClient {
-		storageClient = new(MinioS3)
-		storageClient.cfg = cfg.Storage.S3
-		storageClient.ctx = cc.Ctx()
-		c, err := minio.New(storageClient.cfg.Endpoint, &minio.Options{
-			Creds: credentials.NewStaticV4(
-				storageClient.cfg.Credentials.Key,
-				storageClient.cfg.Credentials.Secret,
-				storageClient.cfg.Credentials.Token,
-			),
-			Secure: storageClient.cfg.UseSSL,
-		})
+		client, err := NewMinioS3(cfg, cc, cache)
 		if err != nil {
 			return nil, err
 		}
-		storageClient.client = c
-		err = storageClient.ensureBuckets()
-		if err != nil {
-			return nil, err
-		}
-		storageClient.metaCache = cache
+		storageClient = client
 	}
 	return storageClient, nil
 }
 
-func (m *MinioS3) ensureBuckets() error {
-	if err := m.ensureBucket(m.cfg.Buckets.Final); err != nil {
-		return err
+// NewMinioS3 makes storage, which is not shared, and creates its buckets
+func NewMinioS3(cfg config.Configuration, cc port.ContextProvider, cache port.MetaCacheController) (*MinioS3, error) {
+	client, err := newMinioS3(cfg, cc)
+	if err != nil {
+		return nil, err
+	}
+	if err = client.ensureBuckets(); err != nil {
+		return nil, err
 	}
+	client.metaCache = cache
+	return client, nil
+}
 
-	if err := m.ensureBucket(m.cfg.Buckets.Parts); err != nil {
-		return err
+func newMinioS3(cfg config.Configuration, cc port.ContextProvider) (*MinioS3, error) {
+	m := new(MinioS3)
+	m.cfg = cfg.Storage.S3
+	m.sse = newSseKeys(cfg.Storage.S3.Encryption)
+	m.namespacesBuckets = cfg.Uploader.GetNamespacesBuckets()
+	m.quarantineBucket = cfg.Antivirus.GetQuarantineBucket()
+	m.imagesBucket = cfg.Images.GetBucket()
+	m.ctx = cc.Ctx()
+	c, err := minio.New(m.cfg.Endpoint, &minio.Options{
+		Creds: credentials.NewStaticV4(
+			m.cfg.Credentials.Key,
+			m.cfg.Credentials.Secret,
+			m.cfg.Credentials.Token,
+		),
+		Secure: m.cfg.UseSSL,
+	})
+	if err != nil {
+		return nil, err
 	}
+	m.client = c
+	return m, nil
+}
 
-	if err := m.ensureBucket(m.cfg.Buckets.Meta); err != nil {
-		return err
+func (m *MinioS3) ensureBuckets() error {
+	for _, bucket := range m.buckets() {
+		if err := m.ensureBucket(bucket); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
+// buckets returns all buckets, which are used by Filup
+func (m *MinioS3) buckets() []string {
+	result := []string{m.cfg.Buckets.Final, m.cfg.Buckets.Parts, m.cfg.Buckets.Meta}
+	result = append(result, m.namespacesBuckets...)
+	if m.quarantineBucket != "" {
+		result = append(result, m.quarantineBucket)
+	}
+	if m.imagesBucket != "" {
+		result = append(result, m.imagesBucket)
+	}
+	return result
+}
+
+// CheckBuckets is readiness check: S3 is reachable and all buckets exist
+func (m *MinioS3) CheckBuckets(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.GetTimeout())
+	defer cancel()
+	for _, bucket := range m.buckets() {
+		exists, err := m.client.BucketExists(ctx, bucket)
+		if err != nil {
+			return errors.Wrap(err, "MinioS3.CheckBuckets ("+bucket+")")
+		}
+		if !exists {
+			return errors.New("MinioS3.CheckBuckets: bucket " + bucket + " does not exist")
+		}
+	}
 	return nil
 }
 
@@ -87,16 +139,25 @@ func (m *MinioS3) ensureBucket(bucketName string) error {
 	return nil
 }
 
-func (m *MinioS3) putFileByReader(contentType, bucketName, fileName string, filesize int64, content io.Reader) error {
+func (m *MinioS3) putFileByReader(
+	contentType, bucketName, fileName string,
+	filesize int64,
+	content io.Reader,
+	encryption *dto.Encryption,
+) error {
+	sse, err := m.sse.forWrite(encryption)
+	if err != nil {
+		return errors.Wrap(err, "MinioS3.putFile.Encryption")
+	}
 	ctx, cancel := m.getContextTimeout()
 	defer cancel()
-	_, err := m.client.PutObject(
+	_, err = m.client.PutObject(
 		ctx,
 		bucketName,
 		fileName,
 		content,
 		filesize,
-		minio.PutObjectOptions{ContentType: contentType},
+		minio.PutObjectOptions{ContentType: contentType, ServerSideEncryption: sse},
 	)
 	if err != nil {
 		return errors.Wrap(err, "MinioS3.putFile.PutObject")
@@ -105,16 +166,20 @@ func (m *MinioS3) putFileByReader(contentType, bucketName, fileName string, file
 }
 
 func (m *MinioS3) putFile(contentType, bucketName, fileName string, content []byte) error {
+	sse, err := m.sse.forWrite(nil)
+	if err != nil {
+		return errors.Wrap(err, "MinioS3.putFile.Encryption")
+	}
 	ctx, cancel := m.getContextTimeout()
 	defer cancel()
 	buf := bytes.NewBuffer(content)
-	_, err := m.client.PutObject(
+	_, err = m.client.PutObject(
 		ctx,
 		bucketName,
 		fileName,
 		buf,
 		int64(buf.Len()),
-		minio.PutObjectOptions{ContentType: contentType},
+		minio.PutObjectOptions{ContentType: contentType, ServerSideEncryption: sse},
 	)
 	if err != nil {
 		return errors.Wrap(err, "MinioS3.putFile.PutObject")
@@ -131,6 +196,9 @@ func (m *MinioS3) getFile(bucketName, fileName string) ([]byte, error) {
 	}
 	stat, err := object.Stat()
 	if err != nil {
+		if minio.ToErrorResponse(err).Code == noSuchKeyCode {
+			return nil, nil
+		}
 		return nil, errors.Wrap(err, "MinioS3.getFile.ObjectStat")
 	}
 	buf := make([]byte, stat.Size)
@@ -140,8 +208,55 @@ func (m *MinioS3) getFile(bucketName, fileName string) ([]byte, error) {
 	return buf, nil
 }
 
-func (m *MinioS3) GetFileStream(fileName string) (io.ReadCloser, port.FileInfo, error) {
-	object, err := m.client.GetObject(m.ctx, m.cfg.Buckets.Final, fileName, minio.GetObjectOptions{})
+func (m *MinioS3) finalBucket(location dto.FileLocation) string {
+	if location.GetBucket() != "" {
+		return location.GetBucket()
+	}
+	return m.cfg.Buckets.Final
+}
+
+func (m *MinioS3) GetFileInfo(location dto.FileLocation) (port.FileInfo, error) {
+	ctx, cancel := m.getContextTimeout()
+	defer cancel()
+	bucket := m.finalBucket(location)
+	sse, err := m.sse.forRead(location.Encryption)
+	if err != nil {
+		return nil, errors.Wrap(err, "MinioS3.GetFileInfo.Encryption")
+	}
+	stat, err := m.client.StatObject(ctx, bucket, location.GetKey(), minio.StatObjectOptions{ServerSideEncryption: sse})
+	if err != nil {
+		return nil, errors.Wrap(err, "MinioS3.GetFileInfo.StatObject")
+	}
+	return FileInfo{
+		size:         stat.Size,
+		contentType:  stat.ContentType,
+		userMetadata: stat.UserMetadata,
+		etag:         stat.ETag,
+	}, nil
+}
+
+func (m *MinioS3) GetFileTags(location dto.FileLocation) (map[string]string, error) {
+	ctx, cancel := m.getContextTimeout()
+	defer cancel()
+	objectTags, err := m.client.GetObjectTagging(ctx, m.finalBucket(location), location.GetKey(), minio.GetObjectTaggingOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "MinioS3.GetFileTags")
+	}
+	return objectTags.ToMap(), nil
+}
+
+func (m *MinioS3) GetFileStream(location dto.FileLocation, byteRange *dto.ByteRange) (io.ReadCloser, port.FileInfo, error) {
+	sse, err := m.sse.forRead(location.Encryption)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "MinioS3.GetFileStream.Encryption")
+	}
+	opts := minio.GetObjectOptions{ServerSideEncryption: sse}
+	if byteRange != nil {
+		if err := opts.SetRange(byteRange.Start, byteRange.End); err != nil {
+			return nil, nil, errors.Wrap(err, "MinioS3.GetFileStream.SetRange")
+		}
+	}
+	object, err := m.client.GetObject(m.ctx, m.finalBucket(location), location.GetKey(), opts)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "MinioS3.getFile.GetObject")
 	}
@@ -149,7 +264,117 @@ func (m *MinioS3) GetFileStream(fileName string) (io.ReadCloser, port.FileInfo,
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "MinioS3.getFile.ObjectStat")
 	}
-	return object, FileInfo{size: stat.Size, contentType: stat.ContentType}, nil
+	return object, FileInfo{size: stat.Size, contentType: stat.ContentType, userMetadata: stat.UserMetadata, etag: stat.ETag}, nil
+}
+
+func (m *MinioS3) IsFileExists(location dto.FileLocation) (bool, error) {
+	ctx, cancel := m.getContextTimeout()
+	defer cancel()
+	sse, err := m.sse.forRead(location.Encryption)
+	if err != nil {
+		return false, errors.Wrap(err, "MinioS3.IsFileExists.Encryption")
+	}
+	_, err = m.client.StatObject(ctx, m.finalBucket(location), location.GetKey(), minio.StatObjectOptions{ServerSideEncryption: sse})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == noSuchKeyCode {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "MinioS3.IsFileExists")
+	}
+	return true, nil
+}
+
+func (m *MinioS3) RemoveFile(location dto.FileLocation) error {
+	ctx, cancel := m.getContextTimeout()
+	defer cancel()
+	err := m.client.RemoveObject(ctx, m.finalBucket(location), location.GetKey(), minio.RemoveObjectOptions{})
+	if err != nil {
+		return errors.Wrap(err, "MinioS3.RemoveFile")
+	}
+	return nil
+}
+
+func (m *MinioS3) PutFile(location dto.FileLocation, contentType string, size int64, content io.Reader) error {
+	err := m.putFileByReader(contentType, m.finalBucket(location), location.GetKey(), size, content, location.Encryption)
+	if err != nil {
+		return errors.Wrap(err, "MinioS3.PutFile")
+	}
+	return nil
+}
+
+func (m *MinioS3) RemoveFiles(bucket string, prefix string) error {
+	ctx, cancel := m.getContextTimeout()
+	defer cancel()
+	objects := m.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true})
+	var errs []string
+	for removeErr := range m.client.RemoveObjects(ctx, bucket, objects, minio.RemoveObjectsOptions{GovernanceBypass: true}) {
+		errs = append(errs, removeErr.Err.Error())
+	}
+	if len(errs) > 0 {
+		return errors.New("MinioS3.RemoveFiles: [" + strings.Join(errs, ",") + "]")
+	}
+	return nil
+}
+
+func (m *MinioS3) TagFile(location dto.FileLocation, tags map[string]string) error {
+	ctx, cancel := m.getContextTimeout()
+	defer cancel()
+	bucket := m.finalBucket(location)
+	objectTags, err := m.client.GetObjectTagging(ctx, bucket, location.GetKey(), minio.GetObjectTaggingOptions{})
+	if err != nil {
+		return errors.Wrap(err, "MinioS3.TagFile.GetObjectTagging")
+	}
+	merged := objectTags.ToMap()
+	for k, v := range tags {
+		merged[k] = v
+	}
+	newTags, err := minioTags.NewTags(merged, true)
+	if err != nil {
+		return errors.Wrap(err, "MinioS3.TagFile.NewTags")
+	}
+	err = m.client.PutObjectTagging(ctx, bucket, location.GetKey(), newTags, minio.PutObjectTaggingOptions{})
+	if err != nil {
+		return errors.Wrap(err, "MinioS3.TagFile.PutObjectTagging")
+	}
+	return nil
+}
+
+func (m *MinioS3) MoveFile(src dto.FileLocation, dest dto.FileLocation) error {
+	srcSse, err := m.sse.forCopySource(src.Encryption)
+	if err != nil {
+		return errors.Wrap(err, "MinioS3.MoveFile.Encryption")
+	}
+	destSse, err := m.sse.forWrite(dest.Encryption)
+	if err != nil {
+		return errors.Wrap(err, "MinioS3.MoveFile.Encryption")
+	}
+	ctx, cancel := m.getContextTimeout()
+	defer cancel()
+	_, err = m.client.CopyObject(
+		ctx,
+		minio.CopyDestOptions{Bucket: m.finalBucket(dest), Object: dest.GetKey(), Encryption: destSse},
+		minio.CopySrcOptions{Bucket: m.finalBucket(src), Object: src.GetKey(), Encryption: srcSse},
+	)
+	if err != nil {
+		return errors.Wrap(err, "MinioS3.MoveFile.CopyObject")
+	}
+	err = m.client.RemoveObject(ctx, m.finalBucket(src), src.GetKey(), minio.RemoveObjectOptions{})
+	if err != nil {
+		return errors.Wrap(err, "MinioS3.MoveFile.RemoveObject")
+	}
+	return nil
+}
+
+func (m *MinioS3) IsCustomerKeyAllowed() bool {
+	return m.sse.isCustomerKeyAllowed()
+}
+
+func (m *MinioS3) SealCustomerKey(key []byte) (string, error) {
+	sealed, err := m.sse.seal(key)
+	if err != nil {
+		return "", errors.Wrap(err, "MinioS3.SealCustomerKey")
+	}
+	return sealed, nil
 }
 
 func (m *MinioS3) PutMetaFile(fileName string, content []byte) error {
@@ -173,13 +398,40 @@ func (m *MinioS3) GetMetaFile(fileName string) ([]byte, error) {
 	return content, nil
 }
 
-func (m *MinioS3) PutFilePart(fullPartName string, filesize int64, content io.Reader) error {
+func (m *MinioS3) ListMetaFiles(prefix string, marker string, limit int) ([]string, error) {
+	ctx, cancel := m.getContextTimeout()
+	defer cancel()
+	options := minio.ListObjectsOptions{
+		Prefix:     prefix,
+		StartAfter: marker,
+		Recursive:  true,
+	}
+	if limit > 0 {
+		options.MaxKeys = limit
+	}
+	resultChan := m.client.ListObjects(ctx, m.cfg.Buckets.Meta, options)
+	var result []string //nolint:prealloc
+	for obj := range resultChan {
+		if obj.Err != nil {
+			return nil, errors.Wrap(obj.Err, "MinioS3.ListMetaFiles")
+		}
+		result = append(result, obj.Key)
+		if limit > 0 && len(result) >= limit {
+			// canceled context stops listing of next pages
+			break
+		}
+	}
+	return result, nil
+}
+
+func (m *MinioS3) PutFilePart(fullPartName string, filesize int64, content io.Reader, encryption *dto.Encryption) error {
 	err := m.putFileByReader(
 		"application/octet-stream",
 		m.cfg.Buckets.Parts,
 		fullPartName,
 		filesize,
 		content,
+		encryption,
 	)
 	if err != nil {
 		return errors.Wrap(err, "MinioS3.PutFilePart")
@@ -204,20 +456,39 @@ func (m *MinioS3) GetLoadedFilePartsNames(fileName string) ([]string, error) {
 	return result, nil
 }
 
-func (m *MinioS3) ComposeFileParts(destFileName string, fullPartsName []string, tags map[string]string) (port.PartsComposerResult, error) {
+func (m *MinioS3) ComposeFileParts(
+	dest dto.FileLocation,
+	fullPartsName []string,
+	tags map[string]string,
+	contentType string,
+) (port.PartsComposerResult, error) {
+	// parts are encrypted with the same key as composed file
+	srcSse, err := m.sse.forCopySource(dest.Encryption)
+	if err != nil {
+		return nil, errors.Wrap(err, "MinioS3.ComposeFileParts.Encryption")
+	}
+	destSse, err := m.sse.forWrite(dest.Encryption)
+	if err != nil {
+		return nil, errors.Wrap(err, "MinioS3.ComposeFileParts.Encryption")
+	}
 	objects := make([]minio.CopySrcOptions, len(fullPartsName))
 	for i, fn := range fullPartsName {
-		objects[i] = minio.CopySrcOptions{Bucket: m.cfg.Buckets.Parts, Object: fn}
+		objects[i] = minio.CopySrcOptions{Bucket: m.cfg.Buckets.Parts, Object: fn, Encryption: srcSse}
 	}
 	ctx, cancel := m.getContextTimeout()
 	defer cancel()
-	dest := minio.CopyDestOptions{
-		Bucket:      m.cfg.Buckets.Final,
-		Object:      destFileName,
+	destOpts := minio.CopyDestOptions{
+		Bucket:      m.finalBucket(dest),
+		Object:      dest.GetKey(),
 		ReplaceTags: true,
 		UserTags:    tags,
+		Encryption:  destSse,
+	}
+	if contentType != "" {
+		destOpts.ReplaceMetadata = true
+		destOpts.UserMetadata = map[string]string{"Content-Type": contentType}
 	}
-	ui, err := m.client.ComposeObject(ctx, dest, objects...)
+	ui, err := m.client.ComposeObject(ctx, destOpts, objects...)
 	if err != nil {
 		return nil, errors.Wrap(err, "MinioS3.ComposeFileParts")
 	}
@@ -276,8 +547,10 @@ func (c ComposeResult) GetSize() int64 {
 }
 
 type FileInfo struct {
-	contentType string
-	size        int64
+	contentType  string
+	size         int64
+	userMetadata map[string]string
+	etag         string
 }
 
 func (fi FileInfo) GetSize() int64 {
@@ -287,3 +560,11 @@ func (fi FileInfo) GetSize() int64 {
 func (fi FileInfo) GetContentType() string {
 	return fi.contentType
 }
+
+func (fi FileInfo) GetUserMetadata() map[string]string {
+	return fi.userMetadata
+}
+
+func (fi FileInfo) GetETag() string {
+	return fi.etag
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"io"
+)
+
+// sseKeys makes server-side encryption options of objects.
+// SSE-C key of upload is sealed by master key with AES-GCM or derived from master key by uuid of upload
+type sseKeys struct {
+	cfg config.StorageEncryption
+}
+
+func newSseKeys(cfg config.StorageEncryption) sseKeys {
+	return sseKeys{cfg: cfg}
+}
+
+// forWrite returns encryption of new object. Objects without reference to key (meta) are not encrypted by SSE-C
+func (k sseKeys) forWrite(encryption *dto.Encryption) (encrypt.ServerSide, error) {
+	switch k.cfg.Type {
+	case config.SseS3:
+		return encrypt.NewSSE(), nil
+	case config.SseKms:
+		var context interface{}
+		if len(k.cfg.KmsContext) > 0 {
+			context = k.cfg.KmsContext
+		}
+		return encrypt.NewSSEKMS(k.cfg.KmsKeyId, context)
+	case config.SseC:
+		return k.forRead(encryption)
+	}
+	return nil, nil
+}
+
+// forRead returns encryption, which is required to read object. Only SSE-C requires key for reading
+func (k sseKeys) forRead(encryption *dto.Encryption) (encrypt.ServerSide, error) {
+	if k.cfg.Type != config.SseC || !encryption.IsSseC() {
+		return nil, nil
+	}
+	key, err := k.customerKey(encryption)
+	if err != nil {
+		return nil, err
+	}
+	return encrypt.NewSSEC(key)
+}
+
+// forCopySource - source of copy requires SSE-C key in special headers
+func (k sseKeys) forCopySource(encryption *dto.Encryption) (encrypt.ServerSide, error) {
+	sse, err := k.forRead(encryption)
+	if err != nil || sse == nil {
+		return nil, err
+	}
+	return encrypt.SSECopy(sse), nil
+}
+
+func (k sseKeys) customerKey(encryption *dto.Encryption) ([]byte, error) {
+	if encryption.EncryptedKey != "" {
+		return k.open(encryption.EncryptedKey)
+	}
+	if encryption.Uuid == "" {
+		return nil, errors.New("sseKeys: no key of object")
+	}
+	mac := hmac.New(sha256.New, k.cfg.GetMasterKey())
+	mac.Write([]byte(encryption.Uuid))
+	return mac.Sum(nil), nil
+}
+
+func (k sseKeys) isCustomerKeyAllowed() bool {
+	return k.cfg.Type == config.SseC
+}
+
+func (k sseKeys) seal(key []byte) (string, error) {
+	gcm, err := k.gcm()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", errors.Wrap(err, "sseKeys.seal")
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, key, nil)), nil
+}
+
+func (k sseKeys) open(sealed string) ([]byte, error) {
+	gcm, err := k.gcm()
+	if err != nil {
+		return nil, err
+	}
+	content, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(content) < gcm.NonceSize() {
+		return nil, errors.New("sseKeys: incorrect sealed key")
+	}
+	key, err := gcm.Open(nil, content[:gcm.NonceSize()], content[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "sseKeys.open")
+	}
+	return key, nil
+}
+
+func (k sseKeys) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k.cfg.GetMasterKey())
+	if err != nil {
+		return nil, errors.Wrap(err, "sseKeys.gcm")
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"net/url"
+	"time"
+)
+
+const (
+	otlpTimeout     = 10 * time.Second
+	otlpDefaultPath = "/v1/traces"
+)
+
+// NewOtlpExporter returns OTLP/HTTP exporter with protobuf encoding, endpoint is full url of traces, e.g. http://localhost:4318/v1/traces
+func NewOtlpExporter(endpoint string, headers map[string]string) (*otlptrace.Exporter, error) {
+	options, err := otlpOptions(endpoint, headers)
+	if err != nil {
+		return nil, err
+	}
+	return otlptracehttp.New(context.Background(), options...)
+}
+
+func otlpOptions(endpoint string, headers map[string]string) ([]otlptracehttp.Option, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "incorrect otlp endpoint")
+	}
+	if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.New("otlp endpoint must be http or https url")
+	}
+	path := u.Path
+	if path == "" || path == "/" {
+		path = otlpDefaultPath
+	}
+	options := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithURLPath(path),
+		otlptracehttp.WithTimeout(otlpTimeout),
+	}
+	if len(headers) > 0 {
+		options = append(options, otlptracehttp.WithHeaders(headers))
+	}
+	if u.Scheme == "http" {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	return options, nil
+}
@@ -0,0 +1,133 @@
+package tracing
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	InstrumentationName = "github.com/satmaelstorm/filup"
+	traceParentHeader   = "traceparent"
+	shutdownTimeout     = 5 * time.Second
+)
+
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// ProvideTracer sets global tracer provider with configured exporter and W3C trace context propagator
+func ProvideTracer(cfg config.Configuration, ctxProvider port.ContextProvider, logger logsEngine.ILogger) (*Tracer, error) {
+	provider, err := NewTracerProvider(cfg, ctxProvider, logger)
+	if err != nil {
+		return nil, err
+	}
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	otel.SetTracerProvider(provider)
+	return NewTracer(provider), nil
+}
+
+// NewTracer returns tracer of provider with W3C trace context propagator, globals of otel are not changed
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: provider.Tracer(InstrumentationName), propagator: propagation.TraceContext{}}
+}
+
+// NewTracerProvider returns provider with configured exporter, it is shut down when context is done.
+// Provider does not record spans, if exporter is not configured
+func NewTracerProvider(cfg config.Configuration, ctxProvider port.ContextProvider, logger logsEngine.ILogger) (trace.TracerProvider, error) {
+	exporter, err := newExporter(cfg.Tracing)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return trace.NewNoopTracerProvider(), nil
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.Tracing.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	)
+	ctx := ctxProvider.Ctx()
+	go func() {
+		<-ctx.Done()
+		// ctx is cancelled already, buffered spans are exported with own timeout
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := provider.Shutdown(shutdownCtx); err != nil {
+			logger.Error().Println(errors.Wrap(err, "Tracer.Shutdown()"))
+		}
+	}()
+	return provider, nil
+}
+
+func newExporter(cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case config.TracingExporterOtlp:
+		return NewOtlpExporter(cfg.Endpoint, cfg.Headers)
+	case config.TracingExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case config.TracingExporterFile:
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, errors.Wrap(err, "can not open file of traces")
+		}
+		return stdouttrace.New(stdouttrace.WithWriter(file))
+	}
+	return nil, nil
+}
+
+func (t *Tracer) Extract(ctx context.Context, headers [][2]string) context.Context {
+	carrier := propagation.MapCarrier{}
+	for _, h := range headers {
+		carrier[strings.ToLower(h[0])] = h[1]
+	}
+	return t.propagator.Extract(ctx, carrier)
+}
+
+func (t *Tracer) Start(ctx context.Context, name string, links ...string) (context.Context, port.Span) {
+	var opts []trace.SpanStartOption
+	for _, link := range links {
+		linked := trace.SpanContextFromContext(t.propagator.Extract(context.Background(), propagation.MapCarrier{traceParentHeader: link}))
+		if linked.IsValid() {
+			opts = append(opts, trace.WithLinks(trace.Link{SpanContext: linked}))
+		}
+	}
+	ctx, s := t.tracer.Start(ctx, name, opts...)
+	return ctx, span{span: s}
+}
+
+func (t *Tracer) TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	t.propagator.Inject(ctx, carrier)
+	return carrier.Get(traceParentHeader)
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetAttribute(key string, value string) {
+	s.span.SetAttributes(attribute.String(key, value))
+}
+
+func (s span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
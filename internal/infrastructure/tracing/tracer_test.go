@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+type suiteTracer struct {
+	suite.Suite
+	spans  *tracetest.SpanRecorder
+	tracer *Tracer
+}
+
+func TestTracer(t *testing.T) {
+	suite.Run(t, new(suiteTracer))
+}
+
+func (s *suiteTracer) SetupTest() {
+	s.spans = tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(s.spans))
+	s.tracer = NewTracer(provider)
+}
+
+func (s *suiteTracer) TestExtractAndPropagate() {
+	ctx := s.tracer.Extract(context.Background(), [][2]string{{"Traceparent", testTraceParent}})
+	ctx, span := s.tracer.Start(ctx, "upload.start")
+	span.SetAttribute("upload.uuid", "a")
+	parent := s.tracer.TraceParent(ctx)
+	span.End(nil)
+
+	s.Require().Equal(1, len(s.spans.Ended()))
+	ended := s.spans.Ended()[0]
+	s.Equal("upload.start", ended.Name())
+	s.Equal("4bf92f3577b34da6a3ce929d0e0e4736", ended.SpanContext().TraceID().String())
+	s.Equal("00f067aa0ba902b7", ended.Parent().SpanID().String())
+	s.Equal("00-4bf92f3577b34da6a3ce929d0e0e4736-"+ended.SpanContext().SpanID().String()+"-01", parent)
+}
+
+func (s *suiteTracer) TestLinkAndError() {
+	_, span := s.tracer.Start(context.Background(), "upload.compose", testTraceParent, "broken")
+	span.End(errors.New("compose failed"))
+
+	s.Require().Equal(1, len(s.spans.Ended()))
+	ended := s.spans.Ended()[0]
+	s.Require().Equal(1, len(ended.Links()))
+	s.Equal("4bf92f3577b34da6a3ce929d0e0e4736", ended.Links()[0].SpanContext.TraceID().String())
+	s.Equal("compose failed", ended.Status().Description)
+	s.NotEqual(trace.TraceID{}, ended.SpanContext().TraceID())
+}
+
+func (s *suiteTracer) TestOtlpOptions() {
+	_, err := otlpOptions("localhost:4318", nil)
+	s.NotNil(err)
+	_, err = otlpOptions("ftp://localhost:4318/v1/traces", nil)
+	s.NotNil(err)
+	// endpoint, path, timeout and headers
+	options, err := otlpOptions("https://collector:4318", map[string]string{"Authorization": "token"})
+	s.Nil(err)
+	s.Equal(4, len(options))
+	// endpoint, path, timeout and insecure
+	options, err = otlpOptions("http://collector:4318/v1/traces", nil)
+	s.Nil(err)
+	s.Equal(4, len(options))
+}
+
+func (s *suiteTracer) TestOtlpExporter() {
+	requests := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = ioutil.ReadAll(r.Body)
+		requests <- r
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer server.Close()
+
+	exporter, err := NewOtlpExporter(server.URL+"/collector/traces", map[string]string{"X-Token": "secret"})
+	s.Require().Nil(err)
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	_, span := provider.Tracer(InstrumentationName).Start(context.Background(), "upload.part")
+	span.End()
+	s.Require().Nil(provider.Shutdown(context.Background()))
+
+	select {
+	case r := <-requests:
+		s.Equal(http.MethodPost, r.Method)
+		s.Equal("/collector/traces", r.URL.Path)
+		s.Equal("secret", r.Header.Get("X-Token"))
+		s.Equal("application/x-protobuf", r.Header.Get("Content-Type"))
+	case <-time.After(5 * time.Second):
+		s.Fail("spans are not exported")
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"github.com/valyala/fasthttp"
+	"net/http"
+)
+
+func (h *Handlers) GetLogLevel(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.SetContentType("application/json")
+	response, err := h.CoreLogLevel.Get(h.processHeaders(&ctx.Request.Header))
+	if err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetBody(response)
+}
+
+func (h *Handlers) SetLogLevel(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.SetContentType("application/json")
+	response, err := h.CoreLogLevel.Set(h.processHeaders(&ctx.Request.Header), ctx.Request.Body())
+	if err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetBody(response)
+}
+
+func (h *Handlers) HealthDetails(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.SetContentType("application/json")
+	response, err := h.CoreHealth.Details(h.requestContext(ctx), h.processHeaders(&ctx.Request.Header))
+	if err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetBody(response)
+}
+
+func (h *Handlers) ListUploads(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.SetContentType("application/json")
+	response, err := h.CoreAdmin.ListUploads(h.processHeaders(&ctx.Request.Header), h.processArgs(ctx.QueryArgs()))
+	if err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetBody(response)
+}
+
+func (h *Handlers) GetUpload(ctx *fasthttp.RequestCtx) {
+	uuid, ok := ctx.UserValue(DownloadUuidParameter).(string)
+	if !ok {
+		ctx.Response.SetStatusCode(http.StatusBadRequest)
+		ctx.Response.SetBodyString("Invalid file name")
+		return
+	}
+	ctx.Response.Header.SetContentType("application/json")
+	response, err := h.CoreAdmin.GetUpload(h.processHeaders(&ctx.Request.Header), uuid)
+	if err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetBody(response)
+}
+
+func (h *Handlers) ComposeUpload(ctx *fasthttp.RequestCtx) {
+	uuid, ok := ctx.UserValue(DownloadUuidParameter).(string)
+	if !ok {
+		ctx.Response.SetStatusCode(http.StatusBadRequest)
+		ctx.Response.SetBodyString("Invalid file name")
+		return
+	}
+	if err := h.CoreAdmin.Compose(h.requestContext(ctx), h.processHeaders(&ctx.Request.Header), uuid); err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetStatusCode(http.StatusAccepted)
+}
+
+func (h *Handlers) ExpireUpload(ctx *fasthttp.RequestCtx) {
+	uuid, ok := ctx.UserValue(DownloadUuidParameter).(string)
+	if !ok {
+		ctx.Response.SetStatusCode(http.StatusBadRequest)
+		ctx.Response.SetBodyString("Invalid file name")
+		return
+	}
+	if err := h.CoreAdmin.Expire(h.requestContext(ctx), h.processHeaders(&ctx.Request.Header), uuid); err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetStatusCode(http.StatusNoContent)
+}
+
+func (h *Handlers) ResendCallbackAfter(ctx *fasthttp.RequestCtx) {
+	uuid, ok := ctx.UserValue(DownloadUuidParameter).(string)
+	if !ok {
+		ctx.Response.SetStatusCode(http.StatusBadRequest)
+		ctx.Response.SetBodyString("Invalid file name")
+		return
+	}
+	if err := h.CoreAdmin.ResendCallbackAfter(h.requestContext(ctx), h.processHeaders(&ctx.Request.Header), uuid); err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetStatusCode(http.StatusNoContent)
+}
+
+func (h *Handlers) ListFailedCallbacks(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.SetContentType("application/json")
+	response, err := h.CoreAdmin.ListFailedCallbacks(h.processHeaders(&ctx.Request.Header))
+	if err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetBody(response)
+}
+
+func (h *Handlers) ReplayCallback(ctx *fasthttp.RequestCtx) {
+	id, ok := ctx.UserValue(CallbackIdParameter).(string)
+	if !ok {
+		ctx.Response.SetStatusCode(http.StatusBadRequest)
+		ctx.Response.SetBodyString("Invalid callback id")
+		return
+	}
+	if err := h.CoreAdmin.ReplayCallback(h.requestContext(ctx), h.processHeaders(&ctx.Request.Header), id); err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetStatusCode(http.StatusNoContent)
+}
+
+func (h *Handlers) FlushCache(ctx *fasthttp.RequestCtx) {
+	if err := h.CoreAdmin.FlushCache(h.requestContext(ctx), h.processHeaders(&ctx.Request.Header)); err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetStatusCode(http.StatusNoContent)
+}
@@ -1,41 +1,95 @@
 package handlers
 
 import (
+	"context"
 	"errors"
+	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7"
 	"github.com/satmaelstorm/filup/internal/domain/exceptions"
 	"github.com/satmaelstorm/filup/internal/domain/port"
-	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/satmaelstorm/filup/pkg/client"
 	"github.com/valyala/fasthttp"
 	"net/http"
 )
 
-const DownloadUuidParameter = "uuid"
+const (
+	DownloadUuidParameter = "uuid"
+	CallbackIdParameter   = "id"
+	RequestIdHeader       = "X-Request-Id"
+	requestIdValue        = "requestId"
+	requestLoggerValue    = "requestLogger"
+	// multipartOverhead - boundaries, headers and fields of multipart form around chunk
+	multipartOverhead = 1 << 20
+)
+
+// MaxPartBodySize - limit of request body with chunk of configured length
+func MaxPartBodySize(cfg port.UploaderConfig) int64 {
+	return cfg.GetChunkLength() + multipartOverhead
+}
+
+// partUploadStatus - 202 for accepted chunk and 204, when the last chunk of upload is received
+func partUploadStatus(done bool) int {
+	if done {
+		return http.StatusNoContent
+	}
+	return http.StatusAccepted
+}
 
 type Handlers struct {
-	logger           logsEngine.ILogger
+	logger           port.Logger
 	CoreStartUpload  port.HandlerJson
 	CorePartUpload   port.HandlerMultipart
+	CoreSession      port.HandlerUploadSession
 	CoreFileStreamer port.HandlerStreamer
+	CoreCatalog      port.HandlerCatalog
+	CoreFileRemover  port.HandlerDelete
+	CoreUrlSigner    port.HandlerSigner
+	CoreImages       port.HandlerImage
+	CoreArchiver     port.HandlerArchive
+	CoreLogLevel     port.HandlerLogLevel
+	CoreAdmin        port.HandlerAdminUploads
+	CoreHealth       port.HandlerAdminHealth
 }
 
 func ProvideHandlers(
-	logger logsEngine.ILogger,
+	logger port.Logger,
 	StartUpload port.HandlerJson,
 	PartUpload port.HandlerMultipart,
+	CoreSession port.HandlerUploadSession,
 	CoreFileStreamer port.HandlerStreamer,
+	CoreCatalog port.HandlerCatalog,
+	CoreFileRemover port.HandlerDelete,
+	CoreUrlSigner port.HandlerSigner,
+	CoreImages port.HandlerImage,
+	CoreArchiver port.HandlerArchive,
+	CoreLogLevel port.HandlerLogLevel,
+	CoreAdmin port.HandlerAdminUploads,
+	CoreHealth port.HandlerAdminHealth,
 ) *Handlers {
 	return &Handlers{
 		logger:           logger,
 		CoreStartUpload:  StartUpload,
 		CorePartUpload:   PartUpload,
+		CoreSession:      CoreSession,
 		CoreFileStreamer: CoreFileStreamer,
+		CoreCatalog:      CoreCatalog,
+		CoreFileRemover:  CoreFileRemover,
+		CoreUrlSigner:    CoreUrlSigner,
+		CoreImages:       CoreImages,
+		CoreArchiver:     CoreArchiver,
+		CoreLogLevel:     CoreLogLevel,
+		CoreAdmin:        CoreAdmin,
+		CoreHealth:       CoreHealth,
 	}
 }
 
+func (h *Handlers) UploadApi(ctx *fasthttp.RequestCtx) {
+	ctx.Response.SetBodyString("upload api")
+}
+
 func (h *Handlers) StartUpload(ctx *fasthttp.RequestCtx) {
 	ctx.Response.Header.SetContentType("application/json")
-	response, err := h.CoreStartUpload.Handle(h.processHeaders(&ctx.Request.Header), ctx.Request.Body())
+	response, err := h.CoreStartUpload.Handle(h.requestContext(ctx), h.processHeaders(&ctx.Request.Header), ctx.Request.Body())
 	if err != nil {
 		h.processError(ctx, err)
 		return
@@ -64,13 +118,44 @@ func (h *Handlers) PartUpload(ctx *fasthttp.RequestCtx) {
 		h.processError(ctx, exceptions.NewApiError(http.StatusBadRequest, err))
 		return
 	}
-	b, err := h.CorePartUpload.Handle(fileSlice[0].Filename, fileSlice[0].Size, file)
+	secret := string(ctx.Request.Header.Peek(client.UploadSecretHeader))
+	if secret == "" && len(mf.Value[client.UploadSecretField]) > 0 {
+		secret = mf.Value[client.UploadSecretField][0]
+	}
+	done, err := h.CorePartUpload.Handle(h.requestContext(ctx), h.processHeaders(&ctx.Request.Header), secret, fileSlice[0].Filename, fileSlice[0].Size, file)
+	if err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetStatusCode(partUploadStatus(done))
+}
+
+func (h *Handlers) UploadStatus(ctx *fasthttp.RequestCtx) {
+	uuid, ok := ctx.UserValue(DownloadUuidParameter).(string)
+	if !ok {
+		ctx.Response.SetStatusCode(http.StatusBadRequest)
+		ctx.Response.SetBodyString("Invalid file name")
+		return
+	}
+	ctx.Response.Header.SetContentType("application/json")
+	response, err := h.CoreSession.Status(h.requestContext(ctx), string(ctx.Request.Header.Peek(client.UploadSecretHeader)), uuid)
 	if err != nil {
 		h.processError(ctx, err)
 		return
 	}
-	if !b {
-		ctx.SetStatusCode(http.StatusContinue)
+	ctx.SetBody(response)
+}
+
+func (h *Handlers) AbortUpload(ctx *fasthttp.RequestCtx) {
+	uuid, ok := ctx.UserValue(DownloadUuidParameter).(string)
+	if !ok {
+		ctx.Response.SetStatusCode(http.StatusBadRequest)
+		ctx.Response.SetBodyString("Invalid file name")
+		return
+	}
+	if err := h.CoreSession.Abort(h.requestContext(ctx), string(ctx.Request.Header.Peek(client.UploadSecretHeader)), uuid); err != nil {
+		h.processError(ctx, err)
+		return
 	}
 	ctx.SetStatusCode(http.StatusNoContent)
 }
@@ -83,30 +168,167 @@ func (h *Handlers) DownloadFile(ctx *fasthttp.RequestCtx) {
 		ctx.Response.SetBodyString("Invalid file name")
 		return
 	}
-	streamer, info, err := h.CoreFileStreamer.GetStreamer(h.processHeaders(&ctx.Request.Header), fileName)
+	headers := h.processHeaders(&ctx.Request.Header)
+	options, err := h.CoreUrlSigner.Verify(fileName, h.processArgs(ctx.QueryArgs()), headers, ctx.RemoteIP().String())
+	if err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	streamer, result, err := h.CoreFileStreamer.GetStreamer(h.requestContext(ctx), headers, fileName, options)
+	if err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.Response.SetStatusCode(result.GetStatusCode())
+	ctx.Response.Header.SetContentType(result.GetContentType())
+	for _, header := range result.GetHeaders() {
+		ctx.Response.Header.Set(header[0], header[1])
+	}
+	ctx.Response.SetBodyStreamWriter(streamer)
+}
+
+func (h *Handlers) DownloadImage(ctx *fasthttp.RequestCtx) {
+	fileName, ok := ctx.UserValue(DownloadUuidParameter).(string)
+	if !ok {
+		ctx.Response.SetStatusCode(http.StatusBadRequest)
+		ctx.Response.SetBodyString("Invalid file name")
+		return
+	}
+	headers := h.processHeaders(&ctx.Request.Header)
+	args := h.processArgs(ctx.QueryArgs())
+	options, err := h.CoreUrlSigner.Verify(fileName, args, headers, ctx.RemoteIP().String())
+	if err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	streamer, result, err := h.CoreImages.GetStreamer(h.requestContext(ctx), headers, fileName, args, options)
 	if err != nil {
 		h.processError(ctx, err)
 		return
 	}
-	ctx.Response.Header.SetContentType(info.GetContentType())
+	ctx.Response.SetStatusCode(result.GetStatusCode())
+	ctx.Response.Header.SetContentType(result.GetContentType())
+	for _, header := range result.GetHeaders() {
+		ctx.Response.Header.Set(header[0], header[1])
+	}
 	ctx.Response.SetBodyStreamWriter(streamer)
 }
 
+func (h *Handlers) DownloadArchive(ctx *fasthttp.RequestCtx) {
+	streamer, result, err := h.CoreArchiver.GetStreamer(h.requestContext(ctx), h.processHeaders(&ctx.Request.Header), ctx.Request.Body())
+	if err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.Response.SetStatusCode(result.GetStatusCode())
+	ctx.Response.Header.SetContentType(result.GetContentType())
+	for _, header := range result.GetHeaders() {
+		ctx.Response.Header.Set(header[0], header[1])
+	}
+	ctx.Response.SetBodyStreamWriter(streamer)
+}
+
+func (h *Handlers) ListFiles(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.SetContentType("application/json")
+	response, err := h.CoreCatalog.List(h.processHeaders(&ctx.Request.Header), h.processArgs(ctx.QueryArgs()))
+	if err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetBody(response)
+}
+
+func (h *Handlers) GetFile(ctx *fasthttp.RequestCtx) {
+	uuid, ok := ctx.UserValue(DownloadUuidParameter).(string)
+	if !ok {
+		ctx.Response.SetStatusCode(http.StatusBadRequest)
+		ctx.Response.SetBodyString("Invalid file name")
+		return
+	}
+	ctx.Response.Header.SetContentType("application/json")
+	response, err := h.CoreCatalog.Get(h.processHeaders(&ctx.Request.Header), uuid)
+	if err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetBody(response)
+}
+
+func (h *Handlers) DeleteFile(ctx *fasthttp.RequestCtx) {
+	uuid, ok := ctx.UserValue(DownloadUuidParameter).(string)
+	if !ok {
+		ctx.Response.SetStatusCode(http.StatusBadRequest)
+		ctx.Response.SetBodyString("Invalid file name")
+		return
+	}
+	if err := h.CoreFileRemover.Delete(h.requestContext(ctx), h.processHeaders(&ctx.Request.Header), uuid); err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetStatusCode(http.StatusNoContent)
+}
+
+func (h *Handlers) SignUrl(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.SetContentType("application/json")
+	response, err := h.CoreUrlSigner.Sign(h.processHeaders(&ctx.Request.Header), ctx.Request.Body())
+	if err != nil {
+		h.processError(ctx, err)
+		return
+	}
+	ctx.SetBody(response)
+}
+
+// RequestId returns id of request from X-Request-Id header or generates it. Id is returned in response
+func RequestId(ctx *fasthttp.RequestCtx) string {
+	if requestId, ok := ctx.UserValue(requestIdValue).(string); ok {
+		return requestId
+	}
+	requestId := string(ctx.Request.Header.Peek(RequestIdHeader))
+	if requestId == "" {
+		requestId = uuid.New().String()
+	}
+	ctx.SetUserValue(requestIdValue, requestId)
+	ctx.Response.Header.Set(RequestIdHeader, requestId)
+	return requestId
+}
+
+// requestLogger returns logger with id and remote ip of request
+func (h *Handlers) requestLogger(ctx *fasthttp.RequestCtx) port.Logger {
+	if logger, ok := ctx.UserValue(requestLoggerValue).(port.Logger); ok {
+		return logger
+	}
+	logger := h.logger.With(
+		[2]string{port.LogFieldRequestId, RequestId(ctx)},
+		[2]string{port.LogFieldRemoteIp, ctx.RemoteIP().String()},
+	)
+	ctx.SetUserValue(requestLoggerValue, logger)
+	return logger
+}
+
+// requestContext carries logger of request to domain. It is not bound to fasthttp.RequestCtx,
+// because streamers are called after handler returns
+func (h *Handlers) requestContext(ctx *fasthttp.RequestCtx) context.Context {
+	return port.ContextWithLogger(context.Background(), h.requestLogger(ctx), RequestId(ctx))
+}
+
 func (h *Handlers) processError(ctx *fasthttp.RequestCtx, err error) {
-	h.logger.Error().Println(err)
+	h.requestLogger(ctx).Error().Println(err)
+	code, msg := h.errorResponse(err)
+	ctx.SetStatusCode(code)
+	ctx.SetBodyString(msg)
+}
+
+// errorResponse returns code and body of response on error, internal errors are not disclosed
+func (h *Handlers) errorResponse(err error) (int, string) {
 	apiErr, ok := err.(port.HttpError)
-	if ok {
-		code, msg := h.getBaseErrorCodeAndMsg(apiErr.GetErr(), apiErr.GetCode(), apiErr.Error())
-		ctx.SetStatusCode(code)
-		if code >= http.StatusInternalServerError {
-			ctx.Response.SetBodyString("Internal server error")
-		} else {
-			ctx.SetBodyString(msg)
-		}
-	} else {
-		ctx.SetStatusCode(http.StatusInternalServerError)
-		ctx.Response.SetBodyString("Internal server error")
+	if !ok {
+		return http.StatusInternalServerError, "Internal server error"
 	}
+	code, msg := h.getBaseErrorCodeAndMsg(apiErr.GetErr(), apiErr.GetCode(), apiErr.Error())
+	if code >= http.StatusInternalServerError {
+		return code, "Internal server error"
+	}
+	return code, msg
 }
 
 func (h *Handlers) processHeaders(header *fasthttp.RequestHeader) [][2]string {
@@ -123,6 +345,14 @@ func (h *Handlers) processHeaders(header *fasthttp.RequestHeader) [][2]string {
 	return result
 }
 
+func (h *Handlers) processArgs(args *fasthttp.Args) [][2]string {
+	result := make([][2]string, 0, args.Len())
+	args.VisitAll(func(key, value []byte) {
+		result = append(result, [2]string{string(key), string(value)})
+	})
+	return result
+}
+
 func (h *Handlers) getBaseError(err error) error {
 	r := err
 	for nr := errors.Unwrap(r); nr != nil; nr = errors.Unwrap(r) {
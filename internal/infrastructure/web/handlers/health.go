@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/satmaelstorm/filup/internal/infrastructure/health"
+	"github.com/valyala/fasthttp"
+	"net/http"
+)
+
+// probeResult - public answer of probes, results of checks are available only by admin api
+type probeResult struct {
+	Ready bool `json:"ready"`
+}
+
+type HealthHandlers struct {
+	probes *health.Probes
+}
+
+func ProvideHealthHandlers(probes *health.Probes) *HealthHandlers {
+	return &HealthHandlers{probes: probes}
+}
+
+func (h *HealthHandlers) Live(ctx *fasthttp.RequestCtx) {
+	ctx.Response.Header.SetContentType("application/json")
+	ctx.SetBodyString(`{"live":true}`)
+}
+
+// Ready answers 503, when instance is stopping or any of checks fails
+func (h *HealthHandlers) Ready(ctx *fasthttp.RequestCtx) {
+	ready, content, err := h.status()
+	if err != nil {
+		ctx.SetStatusCode(http.StatusInternalServerError)
+		return
+	}
+	ctx.Response.Header.SetContentType("application/json")
+	if !ready {
+		ctx.SetStatusCode(http.StatusServiceUnavailable)
+	}
+	ctx.SetBody(content)
+}
+
+func (h *HealthHandlers) status() (bool, []byte, error) {
+	ready, _ := h.probes.Ready(context.Background())
+	content, err := jsoniter.Marshal(probeResult{Ready: ready})
+	return ready, content, err
+}
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/pkg/client"
+	"github.com/valyala/fasthttp"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// maxBodySize limits json bodies like fasthttp server does
+	maxBodySize = fasthttp.DefaultMaxRequestBodySize
+	// multipartMemory - part of multipart form, which is kept in memory, the rest is stored in temporary files
+	multipartMemory = 32 << 20
+)
+
+type pathParamsKey struct{}
+
+type httpRequestIdKey struct{}
+
+// HttpHandlers - net/http handlers over the same ports of domain as Handlers
+type HttpHandlers struct {
+	ports        *Handlers
+	maxPartBytes int64
+}
+
+func ProvideHttpHandlers(hs *Handlers, cfg port.UploaderConfig) *HttpHandlers {
+	return &HttpHandlers{ports: hs, maxPartBytes: MaxPartBodySize(cfg)}
+}
+
+// WithPathParams returns request with parameters of matched route
+func WithPathParams(r *http.Request, params map[string]string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), pathParamsKey{}, params))
+}
+
+// PathParam returns parameter of matched route
+func PathParam(r *http.Request, name string) (string, bool) {
+	params, _ := r.Context().Value(pathParamsKey{}).(map[string]string)
+	value, ok := params[name]
+	return value, ok
+}
+
+// HttpRequestId is RequestId for net/http: id is stored in context of returned request
+func HttpRequestId(w http.ResponseWriter, r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(httpRequestIdKey{}).(string); ok {
+		return r
+	}
+	requestId := r.Header.Get(RequestIdHeader)
+	if requestId == "" {
+		requestId = uuid.New().String()
+	}
+	w.Header().Set(RequestIdHeader, requestId)
+	return r.WithContext(context.WithValue(r.Context(), httpRequestIdKey{}, requestId))
+}
+
+func (h *HttpHandlers) UploadApi(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("upload api"))
+}
+
+func (h *HttpHandlers) StartUpload(w http.ResponseWriter, r *http.Request) {
+	body, err := h.readBody(w, r)
+	if err != nil {
+		h.processError(w, r, err)
+		return
+	}
+	response, err := h.ports.CoreStartUpload.Handle(h.requestContext(r), h.processHeaders(r), body)
+	h.respondJson(w, r, response, err)
+}
+
+// PartUpload limits body like fasthttp server, which is configured by length of chunk
+func (h *HttpHandlers) PartUpload(w http.ResponseWriter, r *http.Request) {
+	if r.ContentLength > h.maxPartBytes {
+		h.processError(w, r, exceptions.NewApiError(http.StatusRequestEntityTooLarge, errors.New("Part is too large")))
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxPartBytes)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		h.processError(w, r, exceptions.NewApiError(http.StatusBadRequest, err))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+	fileSlice := r.MultipartForm.File["part"]
+	if len(fileSlice) < 1 || fileSlice[0] == nil {
+		h.processError(w, r, exceptions.NewApiError(http.StatusBadRequest, errors.New("No part field")))
+		return
+	}
+	file, err := fileSlice[0].Open()
+	if err != nil {
+		h.processError(w, r, exceptions.NewApiError(http.StatusBadRequest, err))
+		return
+	}
+	secret := r.Header.Get(client.UploadSecretHeader)
+	if secret == "" && len(r.MultipartForm.Value[client.UploadSecretField]) > 0 {
+		secret = r.MultipartForm.Value[client.UploadSecretField][0]
+	}
+	done, err := h.ports.CorePartUpload.Handle(h.requestContext(r), h.processHeaders(r), secret, fileSlice[0].Filename, fileSlice[0].Size, file)
+	h.respondStatus(w, r, partUploadStatus(done), err)
+}
+
+func (h *HttpHandlers) UploadStatus(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := PathParam(r, DownloadUuidParameter)
+	if !ok {
+		http.Error(w, "Invalid file name", http.StatusBadRequest)
+		return
+	}
+	response, err := h.ports.CoreSession.Status(h.requestContext(r), r.Header.Get(client.UploadSecretHeader), uuid)
+	h.respondJson(w, r, response, err)
+}
+
+func (h *HttpHandlers) AbortUpload(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := PathParam(r, DownloadUuidParameter)
+	if !ok {
+		http.Error(w, "Invalid file name", http.StatusBadRequest)
+		return
+	}
+	err := h.ports.CoreSession.Abort(h.requestContext(r), r.Header.Get(client.UploadSecretHeader), uuid)
+	h.respondStatus(w, r, http.StatusNoContent, err)
+}
+
+func (h *HttpHandlers) DownloadFile(w http.ResponseWriter, r *http.Request) {
+	fileName, ok := PathParam(r, DownloadUuidParameter)
+	if !ok {
+		http.Error(w, "Invalid file name", http.StatusBadRequest)
+		return
+	}
+	headers := h.processHeaders(r)
+	options, err := h.ports.CoreUrlSigner.Verify(fileName, h.processArgs(r), headers, remoteIp(r))
+	if err != nil {
+		h.processError(w, r, err)
+		return
+	}
+	streamer, result, err := h.ports.CoreFileStreamer.GetStreamer(h.requestContext(r), headers, fileName, options)
+	h.respondStream(w, r, streamer, result, err)
+}
+
+func (h *HttpHandlers) DownloadImage(w http.ResponseWriter, r *http.Request) {
+	fileName, ok := PathParam(r, DownloadUuidParameter)
+	if !ok {
+		http.Error(w, "Invalid file name", http.StatusBadRequest)
+		return
+	}
+	headers := h.processHeaders(r)
+	args := h.processArgs(r)
+	options, err := h.ports.CoreUrlSigner.Verify(fileName, args, headers, remoteIp(r))
+	if err != nil {
+		h.processError(w, r, err)
+		return
+	}
+	streamer, result, err := h.ports.CoreImages.GetStreamer(h.requestContext(r), headers, fileName, args, options)
+	h.respondStream(w, r, streamer, result, err)
+}
+
+func (h *HttpHandlers) DownloadArchive(w http.ResponseWriter, r *http.Request) {
+	body, err := h.readBody(w, r)
+	if err != nil {
+		h.processError(w, r, err)
+		return
+	}
+	streamer, result, err := h.ports.CoreArchiver.GetStreamer(h.requestContext(r), h.processHeaders(r), body)
+	h.respondStream(w, r, streamer, result, err)
+}
+
+func (h *HttpHandlers) ListFiles(w http.ResponseWriter, r *http.Request) {
+	response, err := h.ports.CoreCatalog.List(h.processHeaders(r), h.processArgs(r))
+	h.respondJson(w, r, response, err)
+}
+
+func (h *HttpHandlers) GetFile(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := PathParam(r, DownloadUuidParameter)
+	if !ok {
+		http.Error(w, "Invalid file name", http.StatusBadRequest)
+		return
+	}
+	response, err := h.ports.CoreCatalog.Get(h.processHeaders(r), uuid)
+	h.respondJson(w, r, response, err)
+}
+
+func (h *HttpHandlers) DeleteFile(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := PathParam(r, DownloadUuidParameter)
+	if !ok {
+		http.Error(w, "Invalid file name", http.StatusBadRequest)
+		return
+	}
+	err := h.ports.CoreFileRemover.Delete(h.requestContext(r), h.processHeaders(r), uuid)
+	h.respondStatus(w, r, http.StatusNoContent, err)
+}
+
+func (h *HttpHandlers) SignUrl(w http.ResponseWriter, r *http.Request) {
+	body, err := h.readBody(w, r)
+	if err != nil {
+		h.processError(w, r, err)
+		return
+	}
+	response, err := h.ports.CoreUrlSigner.Sign(h.processHeaders(r), body)
+	h.respondJson(w, r, response, err)
+}
+
+// requestLogger returns logger with id and remote ip of request
+func (h *HttpHandlers) requestLogger(r *http.Request) port.Logger {
+	return h.ports.logger.With(
+		[2]string{port.LogFieldRequestId, h.requestId(r)},
+		[2]string{port.LogFieldRemoteIp, remoteIp(r)},
+	)
+}
+
+func (h *HttpHandlers) requestId(r *http.Request) string {
+	requestId, _ := r.Context().Value(httpRequestIdKey{}).(string)
+	return requestId
+}
+
+// requestContext carries values of request context and logger of request to domain. Only cancellation of request
+// is dropped, so work on upload is not interrupted by disconnected client
+func (h *HttpHandlers) requestContext(r *http.Request) context.Context {
+	return port.ContextWithLogger(detachedContext{Context: r.Context()}, h.requestLogger(r), h.requestId(r))
+}
+
+// detachedContext keeps values of parent, but is never canceled and has no deadline
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (h *HttpHandlers) readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		return nil, exceptions.NewApiError(http.StatusBadRequest, err)
+	}
+	return body, nil
+}
+
+func (h *HttpHandlers) respondJson(w http.ResponseWriter, r *http.Request, response []byte, err error) {
+	if err != nil {
+		h.processError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(response)
+}
+
+func (h *HttpHandlers) respondStatus(w http.ResponseWriter, r *http.Request, code int, err error) {
+	if err != nil {
+		h.processError(w, r, err)
+		return
+	}
+	w.WriteHeader(code)
+}
+
+func (h *HttpHandlers) respondStream(
+	w http.ResponseWriter,
+	r *http.Request,
+	streamer func(writer *bufio.Writer),
+	result dto.DownloadResult,
+	err error,
+) {
+	if err != nil {
+		h.processError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", result.GetContentType())
+	for _, header := range result.GetHeaders() {
+		w.Header().Set(header[0], header[1])
+	}
+	w.WriteHeader(result.GetStatusCode())
+	writer := bufio.NewWriter(w)
+	streamer(writer)
+	_ = writer.Flush()
+}
+
+func (h *HttpHandlers) processError(w http.ResponseWriter, r *http.Request, err error) {
+	h.requestLogger(r).Error().Println(err)
+	code, msg := h.ports.errorResponse(err)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(code)
+	_, _ = w.Write([]byte(msg))
+}
+
+// processHeaders returns headers like fasthttp, which has Host among them
+func (h *HttpHandlers) processHeaders(r *http.Request) [][2]string {
+	result := make([][2]string, 0, len(r.Header)+1)
+	result = append(result, [2]string{"Host", r.Host})
+	for name, values := range r.Header {
+		for _, value := range values {
+			result = append(result, [2]string{name, value})
+		}
+	}
+	return result
+}
+
+// processArgs keeps order of query arguments like fasthttp
+func (h *HttpHandlers) processArgs(r *http.Request) [][2]string {
+	result := make([][2]string, 0)
+	for _, pair := range strings.Split(r.URL.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value := pair, ""
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			key, value = pair[:i], pair[i+1:]
+		}
+		result = append(result, [2]string{unescape(key), unescape(value)})
+	}
+	return result
+}
+
+func unescape(s string) string {
+	if u, err := url.QueryUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
+
+func remoteIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+func (h *HttpHandlers) GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	response, err := h.ports.CoreLogLevel.Get(h.processHeaders(r))
+	h.respondJson(w, r, response, err)
+}
+
+func (h *HttpHandlers) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	body, err := h.readBody(w, r)
+	if err != nil {
+		h.processError(w, r, err)
+		return
+	}
+	response, err := h.ports.CoreLogLevel.Set(h.processHeaders(r), body)
+	h.respondJson(w, r, response, err)
+}
+
+func (h *HttpHandlers) HealthDetails(w http.ResponseWriter, r *http.Request) {
+	response, err := h.ports.CoreHealth.Details(h.requestContext(r), h.processHeaders(r))
+	h.respondJson(w, r, response, err)
+}
+
+func (h *HttpHandlers) ListUploads(w http.ResponseWriter, r *http.Request) {
+	response, err := h.ports.CoreAdmin.ListUploads(h.processHeaders(r), h.processArgs(r))
+	h.respondJson(w, r, response, err)
+}
+
+func (h *HttpHandlers) GetUpload(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := PathParam(r, DownloadUuidParameter)
+	if !ok {
+		http.Error(w, "Invalid file name", http.StatusBadRequest)
+		return
+	}
+	response, err := h.ports.CoreAdmin.GetUpload(h.processHeaders(r), uuid)
+	h.respondJson(w, r, response, err)
+}
+
+func (h *HttpHandlers) ComposeUpload(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := PathParam(r, DownloadUuidParameter)
+	if !ok {
+		http.Error(w, "Invalid file name", http.StatusBadRequest)
+		return
+	}
+	err := h.ports.CoreAdmin.Compose(h.requestContext(r), h.processHeaders(r), uuid)
+	h.respondStatus(w, r, http.StatusAccepted, err)
+}
+
+func (h *HttpHandlers) ExpireUpload(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := PathParam(r, DownloadUuidParameter)
+	if !ok {
+		http.Error(w, "Invalid file name", http.StatusBadRequest)
+		return
+	}
+	err := h.ports.CoreAdmin.Expire(h.requestContext(r), h.processHeaders(r), uuid)
+	h.respondStatus(w, r, http.StatusNoContent, err)
+}
+
+func (h *HttpHandlers) ResendCallbackAfter(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := PathParam(r, DownloadUuidParameter)
+	if !ok {
+		http.Error(w, "Invalid file name", http.StatusBadRequest)
+		return
+	}
+	err := h.ports.CoreAdmin.ResendCallbackAfter(h.requestContext(r), h.processHeaders(r), uuid)
+	h.respondStatus(w, r, http.StatusNoContent, err)
+}
+
+func (h *HttpHandlers) ListFailedCallbacks(w http.ResponseWriter, r *http.Request) {
+	response, err := h.ports.CoreAdmin.ListFailedCallbacks(h.processHeaders(r))
+	h.respondJson(w, r, response, err)
+}
+
+func (h *HttpHandlers) ReplayCallback(w http.ResponseWriter, r *http.Request) {
+	id, ok := PathParam(r, CallbackIdParameter)
+	if !ok {
+		http.Error(w, "Invalid callback id", http.StatusBadRequest)
+		return
+	}
+	err := h.ports.CoreAdmin.ReplayCallback(h.requestContext(r), h.processHeaders(r), id)
+	h.respondStatus(w, r, http.StatusNoContent, err)
+}
+
+func (h *HttpHandlers) FlushCache(w http.ResponseWriter, r *http.Request) {
+	err := h.ports.CoreAdmin.FlushCache(h.requestContext(r), h.processHeaders(r))
+	h.respondStatus(w, r, http.StatusNoContent, err)
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// HttpHealthHandlers - net/http version of HealthHandlers
+type HttpHealthHandlers struct {
+	health *HealthHandlers
+}
+
+func ProvideHttpHealthHandlers(health *HealthHandlers) *HttpHealthHandlers {
+	return &HttpHealthHandlers{health: health}
+}
+
+func (h *HttpHealthHandlers) Live(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"live":true}`))
+}
+
+func (h *HttpHealthHandlers) Ready(w http.ResponseWriter, _ *http.Request) {
+	ready, content, err := h.health.status()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = w.Write(content)
+}
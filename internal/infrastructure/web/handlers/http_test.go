@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/satmaelstorm/filup/internal/domain/exceptions"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/satmaelstorm/filup/pkg/client"
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testPartName = "870915da-76bb-11ec-8686-e4e7494803df_part_0"
+
+type fakeUploaderConfig struct {
+	port.UploaderConfig
+	chunkLength int64
+}
+
+func (f fakeUploaderConfig) GetChunkLength() int64 {
+	return f.chunkLength
+}
+
+type fakeMultipart struct {
+	done     bool
+	err      error
+	calls    int
+	secret   string
+	filename string
+	content  []byte
+}
+
+func (f *fakeMultipart) Handle(
+	_ context.Context,
+	_ [][2]string,
+	secret string,
+	filename string,
+	_ int64,
+	file io.ReadCloser,
+) (bool, error) {
+	defer file.Close()
+	f.calls++
+	f.secret, f.filename = secret, filename
+	f.content, _ = ioutil.ReadAll(file)
+	return f.done, f.err
+}
+
+type fakeJson struct {
+	body []byte
+}
+
+func (f *fakeJson) Handle(_ context.Context, _ [][2]string, body []byte) ([]byte, error) {
+	f.body = body
+	return []byte(`{"uuid":"1"}`), nil
+}
+
+// response of handler, which is the same for both transports
+type response struct {
+	code        int
+	body        string
+	contentType string
+}
+
+type suiteHttpHandlers struct {
+	suite.Suite
+	part  *fakeMultipart
+	start *fakeJson
+	fast  *Handlers
+	http  *HttpHandlers
+}
+
+func TestHttpHandlers(t *testing.T) {
+	suite.Run(t, new(suiteHttpHandlers))
+}
+
+func (s *suiteHttpHandlers) SetupTest() {
+	loggers := logsEngine.InitLoggersEmpty("test")
+	s.part = &fakeMultipart{}
+	s.start = &fakeJson{}
+	s.fast = ProvideHandlers(&loggers, s.start, s.part, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	s.http = ProvideHttpHandlers(s.fast, fakeUploaderConfig{chunkLength: 1024})
+}
+
+// multipartBody returns form with chunk in field "part" and other fields
+func (s *suiteHttpHandlers) multipartBody(field string, content []byte, values map[string]string) ([]byte, string) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range values {
+		s.Require().Nil(mw.WriteField(name, value))
+	}
+	part, err := mw.CreateFormFile(field, testPartName)
+	s.Require().Nil(err)
+	_, err = part.Write(content)
+	s.Require().Nil(err)
+	s.Require().Nil(mw.Close())
+	return body.Bytes(), mw.FormDataContentType()
+}
+
+func (s *suiteHttpHandlers) serveHttp(handler http.HandlerFunc, r *http.Request) response {
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return response{code: w.Code, body: w.Body.String(), contentType: w.Header().Get("Content-Type")}
+}
+
+func (s *suiteHttpHandlers) serveFast(handler fasthttp.RequestHandler, method string, body []byte, contentType string, headers map[string]string) response {
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	req.SetRequestURI("/")
+	req.Header.SetContentType(contentType)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	req.SetBody(body)
+	var ctx fasthttp.RequestCtx
+	ctx.Init(&req, nil, nil)
+	handler(&ctx)
+	return response{
+		code:        ctx.Response.StatusCode(),
+		body:        string(ctx.Response.Body()),
+		contentType: string(ctx.Response.Header.ContentType()),
+	}
+}
+
+// partUpload sends the same multipart request by both transports
+func (s *suiteHttpHandlers) partUpload(field string, values map[string]string, headers map[string]string) (response, response) {
+	body, contentType := s.multipartBody(field, []byte("chunk"), values)
+	r := httptest.NewRequest(http.MethodPost, "/upload/part", bytes.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+	return s.serveHttp(s.http.PartUpload, r), s.serveFast(s.fast.PartUpload, http.MethodPost, body, contentType, headers)
+}
+
+func (s *suiteHttpHandlers) TestPartUpload() {
+	httpResponse, fastResponse := s.partUpload("part", nil, map[string]string{client.UploadSecretHeader: "secret"})
+	s.Equal(http.StatusAccepted, httpResponse.code)
+	s.Equal(httpResponse.code, fastResponse.code)
+	s.Equal(2, s.part.calls)
+	s.Equal("secret", s.part.secret)
+	s.Equal(testPartName, s.part.filename)
+	s.Equal([]byte("chunk"), s.part.content)
+
+	s.part.done = true
+	httpResponse, fastResponse = s.partUpload("part", map[string]string{client.UploadSecretField: "field"}, nil)
+	s.Equal(http.StatusNoContent, httpResponse.code)
+	s.Equal(httpResponse.code, fastResponse.code)
+	s.Equal("field", s.part.secret)
+}
+
+func (s *suiteHttpHandlers) TestPartUploadErrors() {
+	httpResponse, fastResponse := s.partUpload("file", nil, nil)
+	s.Equal(http.StatusBadRequest, httpResponse.code)
+	s.Equal(httpResponse, fastResponse)
+	s.Equal(0, s.part.calls)
+
+	s.part.err = exceptions.NewApiError(http.StatusForbidden, errors.New("invalid upload secret"))
+	httpResponse, fastResponse = s.partUpload("part", nil, nil)
+	s.Equal(http.StatusForbidden, httpResponse.code)
+	s.Equal(httpResponse, fastResponse)
+}
+
+func (s *suiteHttpHandlers) TestPartUploadLimit() {
+	body, contentType := s.multipartBody("part", bytes.Repeat([]byte("0"), int(s.http.maxPartBytes)), nil)
+
+	r := httptest.NewRequest(http.MethodPost, "/upload/part", bytes.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	s.Equal(http.StatusRequestEntityTooLarge, s.serveHttp(s.http.PartUpload, r).code)
+
+	// body without length is limited while it is read
+	r = httptest.NewRequest(http.MethodPost, "/upload/part", ioutil.NopCloser(bytes.NewReader(body)))
+	r.ContentLength = -1
+	r.Header.Set("Content-Type", contentType)
+	s.Equal(http.StatusBadRequest, s.serveHttp(s.http.PartUpload, r).code)
+	s.Equal(0, s.part.calls)
+}
+
+func (s *suiteHttpHandlers) TestStartUpload() {
+	body := []byte(`{"file_name":"a.txt"}`)
+	r := httptest.NewRequest(http.MethodPost, "/upload/start", bytes.NewReader(body))
+	httpResponse := s.serveHttp(s.http.StartUpload, r)
+	s.Equal(body, s.start.body)
+	fastResponse := s.serveFast(s.fast.StartUpload, http.MethodPost, body, "application/json", nil)
+	s.Equal(http.StatusOK, httpResponse.code)
+	s.Equal(`{"uuid":"1"}`, httpResponse.body)
+	s.Equal("application/json", httpResponse.contentType)
+	s.Equal(httpResponse, fastResponse)
+}
+
+func (s *suiteHttpHandlers) TestProcessArgs() {
+	r := httptest.NewRequest(http.MethodGet, "/files?b=2&a=%20x&flag", nil)
+	s.Equal([][2]string{{"b", "2"}, {"a", " x"}, {"flag", ""}}, s.http.processArgs(r))
+}
+
+type testContextKey struct{}
+
+func (s *suiteHttpHandlers) TestRequestContext() {
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), testContextKey{}, "value"))
+	r := httptest.NewRequest(http.MethodGet, "/upload", nil).WithContext(parent)
+	ctx := s.http.requestContext(r)
+	cancel()
+
+	s.Nil(ctx.Err())
+	s.Nil(ctx.Done())
+	s.Equal("value", ctx.Value(testContextKey{}))
+}
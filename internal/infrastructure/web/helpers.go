@@ -5,6 +5,11 @@ import (
 	"context"
 	"github.com/pkg/errors"
 	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"io"
 	"net/http"
 	"net/url"
@@ -12,16 +17,33 @@ import (
 	"time"
 )
 
+// ErrHttpError - response has not 2xx code, body and code of response are returned with it
 var ErrHttpError = errors.New("http error")
 
 type RequestFunc func(url.URL, time.Duration, string, *bytes.Reader, ...[2]string) ([]byte, int, error)
 
 type RequestHelpers struct {
 	requestFunc RequestFunc
+	tracer      trace.Tracer
+	propagator  propagation.TextMapPropagator
 }
 
+// ProvideRequestHelpers returns helpers, which trace requests by global tracer provider and propagator of otel
 func ProvideRequestHelpers() *RequestHelpers {
-	return &RequestHelpers{requestFunc: doRequest}
+	return &RequestHelpers{
+		requestFunc: doRequest,
+		tracer:      otel.Tracer(config.ProjectHttpClientName),
+		propagator:  otel.GetTextMapPropagator(),
+	}
+}
+
+// NewRequestHelpers returns helpers, which trace requests by provider with W3C trace context propagator
+func NewRequestHelpers(provider trace.TracerProvider) *RequestHelpers {
+	return &RequestHelpers{
+		requestFunc: doRequest,
+		tracer:      provider.Tracer(config.ProjectHttpClientName),
+		propagator:  propagation.TraceContext{},
+	}
 }
 
 func (rh *RequestHelpers) SetRequestFunc(f RequestFunc) {
@@ -39,7 +61,11 @@ func (rh *RequestHelpers) Get(
 	serviceUrl url.URL,
 	timeOut time.Duration,
 	headers ...[2]string,
-) ([]byte, int, error) {
+) (result []byte, code int, err error) {
+	ctx, headers, endSpan := rh.startClientSpan(ctx, http.MethodGet, serviceUrl, headers)
+	defer func() {
+		endSpan(code, err)
+	}()
 	chResult := make(chan response)
 	go func() {
 		httpResult, httpCode, err := rh.requestFunc(
@@ -58,8 +84,8 @@ func (rh *RequestHelpers) Get(
 	select {
 	case <-ctx.Done():
 		return nil, 0, ctx.Err()
-	case result := <-chResult:
-		return result.result, result.code, result.err
+	case resp := <-chResult:
+		return resp.result, resp.code, resp.err
 	}
 }
 
@@ -69,7 +95,11 @@ func (rh *RequestHelpers) Post(
 	timeOut time.Duration,
 	body []byte,
 	headers ...[2]string,
-) ([]byte, int, error) {
+) (result []byte, code int, err error) {
+	ctx, headers, endSpan := rh.startClientSpan(ctx, http.MethodPost, serviceUrl, headers)
+	defer func() {
+		endSpan(code, err)
+	}()
 	chResult := make(chan response)
 	go func() {
 		httpResult, httpCode, err := rh.requestFunc(
@@ -88,8 +118,44 @@ func (rh *RequestHelpers) Post(
 	select {
 	case <-ctx.Done():
 		return nil, 0, ctx.Err()
-	case result := <-chResult:
-		return result.result, result.code, result.err
+	case resp := <-chResult:
+		return resp.result, resp.code, resp.err
+	}
+}
+
+// startClientSpan starts span of outgoing request and adds W3C traceparent of span to headers.
+// Headers are set in order, so traceparent of incoming request, which is passed to callback, is replaced
+func (rh *RequestHelpers) startClientSpan(
+	ctx context.Context,
+	method string,
+	serviceUrl url.URL,
+	headers [][2]string,
+) (context.Context, [][2]string, func(code int, err error)) {
+	ctx, span := rh.tracer.Start(ctx, "HTTP "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", method),
+			attribute.String("http.url", serviceUrl.Scheme+"://"+serviceUrl.Host+serviceUrl.Path),
+		),
+	)
+	carrier := propagation.MapCarrier{}
+	rh.propagator.Inject(ctx, carrier)
+	result := make([][2]string, 0, len(headers)+len(carrier))
+	result = append(result, headers...)
+	for name, value := range carrier {
+		result = append(result, [2]string{name, value})
+	}
+	return ctx, result, func(code int, err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetAttributes(attribute.Int("http.status_code", code))
+			if code >= http.StatusBadRequest {
+				span.SetStatus(codes.Error, "code "+strconv.Itoa(code))
+			}
+		}
+		span.End()
 	}
 }
 
@@ -100,7 +166,12 @@ func doRequest(
 	body *bytes.Reader,
 	headers ...[2]string,
 ) ([]byte, int, error) {
-	req, err := http.NewRequest(method, url.String(), body)
+	// typed nil reader is dereferenced by http.NewRequest
+	var reader io.Reader
+	if body != nil {
+		reader = body
+	}
+	req, err := http.NewRequest(method, url.String(), reader)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -120,11 +191,14 @@ func doRequest(
 		_ = resp.Body.Close()
 	}()
 
-	if resp.StatusCode < http.StatusOK || resp.StatusCode >= 299 {
-		return nil,
+	result, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode > 299 {
+		return result,
 			resp.StatusCode,
 			errors.Wrap(ErrHttpError, "status code is "+strconv.Itoa(resp.StatusCode))
 	}
-	result, err := io.ReadAll(resp.Body)
-	return result, resp.StatusCode, err
+	return result, resp.StatusCode, nil
 }
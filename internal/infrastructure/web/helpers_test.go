@@ -0,0 +1,74 @@
+package web
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type suiteRequestHelpers struct {
+	suite.Suite
+	server  *httptest.Server
+	method  string
+	body    []byte
+	header  string
+	code    int
+	helpers *RequestHelpers
+}
+
+func TestRequestHelpers(t *testing.T) {
+	suite.Run(t, new(suiteRequestHelpers))
+}
+
+func (s *suiteRequestHelpers) SetupTest() {
+	s.code = http.StatusOK
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.method, s.header = r.Method, r.Header.Get("X-Test")
+		s.body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(s.code)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	s.helpers = ProvideRequestHelpers()
+}
+
+func (s *suiteRequestHelpers) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *suiteRequestHelpers) serverUrl() url.URL {
+	u, err := url.Parse(s.server.URL + "/callback")
+	s.Require().Nil(err)
+	return *u
+}
+
+func (s *suiteRequestHelpers) TestGet() {
+	result, code, err := s.helpers.Get(context.Background(), s.serverUrl(), time.Duration(5), [2]string{"X-Test", "get"})
+	s.Require().Nil(err)
+	s.Equal(http.StatusOK, code)
+	s.Equal(`{"ok":true}`, string(result))
+	s.Equal(http.MethodGet, s.method)
+	s.Equal("get", s.header)
+	s.Empty(s.body)
+}
+
+func (s *suiteRequestHelpers) TestPost() {
+	_, code, err := s.helpers.Post(context.Background(), s.serverUrl(), time.Duration(5), []byte(`{"uuid":"1"}`))
+	s.Require().Nil(err)
+	s.Equal(http.StatusOK, code)
+	s.Equal(http.MethodPost, s.method)
+	s.Equal(`{"uuid":"1"}`, string(s.body))
+}
+
+func (s *suiteRequestHelpers) TestHttpError() {
+	s.code = http.StatusForbidden
+	result, code, err := s.helpers.Get(context.Background(), s.serverUrl(), time.Duration(5))
+	s.True(errors.Is(err, ErrHttpError))
+	s.Equal(http.StatusForbidden, code)
+	s.Equal(`{"ok":true}`, string(result))
+}
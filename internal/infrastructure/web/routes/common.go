@@ -2,13 +2,45 @@ package routes
 
 import "github.com/satmaelstorm/filup/internal/infrastructure/web/handlers"
 
+// Ops - operational endpoints: pprof, metrics and health probes. They are served without authorization,
+// so server enables them and embedded filup only by option
+type Ops struct {
+	Enabled bool
+}
+
 const (
-	Metrics = "/metrics"
+	PprofParameter = "ep"
+	Pprof          = "/debug/pprof/{" + PprofParameter + ":*}"
+	Metrics        = "/metrics"
+
+	Health      = "/health"
+	HealthLive  = Health + "/live"
+	HealthReady = Health + "/ready"
 
 	Upload                = "/upload"
 	StartUpload           = Upload + "/start"
 	UploadPart            = Upload + "/part"
+	UploadSession         = Upload + "/{" + DownloadUuidParameter + ":^[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}$}"
 	Download              = "/download"
 	DownloadUuidParameter = handlers.DownloadUuidParameter
 	DownloadFile          = Download + "/{" + DownloadUuidParameter + ":^[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}$}"
+	DownloadImage         = DownloadFile + "/image"
+	DownloadArchive       = Download + "/archive"
+	Files                 = "/files"
+	FileInfo              = Files + "/{" + DownloadUuidParameter + ":^[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}$}"
+	CallbackIdParameter   = handlers.CallbackIdParameter
+
+	// paths in Admin group
+	Admin                     = "/admin"
+	AdminSign                 = "/sign"
+	AdminLogLevel             = "/log-level"
+	AdminHealth               = Health
+	AdminUploads              = "/uploads"
+	AdminUpload               = AdminUploads + "/{" + DownloadUuidParameter + ":^[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}$}"
+	AdminUploadCompose        = AdminUpload + "/compose"
+	AdminUploadExpire         = AdminUpload + "/expire"
+	AdminFileCallbackAfter    = "/files/{" + DownloadUuidParameter + ":^[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}$}/callback-after"
+	AdminFailedCallbacks      = "/callbacks/failed"
+	AdminFailedCallbackReplay = AdminFailedCallbacks + "/{" + CallbackIdParameter + "}/replay"
+	AdminCacheFlush           = "/cache/flush"
 )
@@ -0,0 +1,221 @@
+package routes
+
+import (
+	"github.com/satmaelstorm/filup/internal/infrastructure/web/handlers"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// methodWild - routes of ANY match every method, like router.MethodWild of fasthttp router
+const methodWild = "*"
+
+// httpRouter routes net/http requests by paths of route table, so paths, parameters, 404, 405 and redirects
+// of trailing slash are the same as of fasthttp router. Segment of path is static, parameter {name},
+// parameter with regular expression {name:^...$} or tail of path {name:*}
+type httpRouter struct {
+	routes       map[string][]httpRoute
+	PanicHandler func(w http.ResponseWriter, r *http.Request, i interface{})
+}
+
+type httpRoute struct {
+	segments []pathSegment
+	handler  http.HandlerFunc
+}
+
+type segmentKind int
+
+// kinds are ordered by priority of matching
+const (
+	segmentStatic segmentKind = iota
+	segmentParam
+	segmentTail
+)
+
+type pathSegment struct {
+	kind  segmentKind
+	value string
+	re    *regexp.Regexp
+}
+
+func newHttpRouter() *httpRouter {
+	return &httpRouter{routes: make(map[string][]httpRoute)}
+}
+
+// Handle adds route, it panics on pattern, which is not supported, like fasthttp router
+func (hr *httpRouter) Handle(method, path string, handler http.HandlerFunc) {
+	hr.routes[method] = append(hr.routes[method], httpRoute{segments: parsePattern(path), handler: handler})
+}
+
+func (hr *httpRouter) ANY(path string, handler http.HandlerFunc) {
+	hr.Handle(methodWild, path, handler)
+}
+
+func (hr *httpRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if hr.PanicHandler != nil {
+		defer func() {
+			if i := recover(); i != nil {
+				hr.PanicHandler(w, r, i)
+			}
+		}()
+	}
+
+	path := r.URL.Path
+	for _, method := range []string{r.Method, methodWild} {
+		if route, params := hr.match(method, path); route != nil {
+			route.handler(w, handlers.WithPathParams(r, params))
+			return
+		}
+		if r.Method != http.MethodConnect && path != "/" && hr.redirectSlash(w, r, method, path) {
+			return
+		}
+	}
+
+	if allow := hr.allowed(r.Method, path); allow != "" {
+		w.Header().Set("Allow", allow)
+		if r.Method == http.MethodOptions {
+			return
+		}
+		hr.respondText(w, http.StatusMethodNotAllowed)
+		return
+	}
+	hr.respondText(w, http.StatusNotFound)
+}
+
+// match returns route of method with the most specific segments and its parameters
+func (hr *httpRouter) match(method, path string) (*httpRoute, map[string]string) {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	var (
+		best       *httpRoute
+		bestParams map[string]string
+	)
+	for i := range hr.routes[method] {
+		route := &hr.routes[method][i]
+		params, ok := route.match(parts)
+		if ok && (best == nil || route.moreSpecific(best)) {
+			best, bestParams = route, params
+		}
+	}
+	return best, bestParams
+}
+
+// redirectSlash redirects to path with or without trailing slash, if route of method matches it
+func (hr *httpRouter) redirectSlash(w http.ResponseWriter, r *http.Request, method, path string) bool {
+	fixed := path + "/"
+	if strings.HasSuffix(path, "/") {
+		fixed = path[:len(path)-1]
+	}
+	if route, _ := hr.match(method, fixed); route == nil {
+		return false
+	}
+	code := http.StatusMovedPermanently
+	if r.Method != http.MethodGet {
+		code = http.StatusPermanentRedirect
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	location := scheme + "://" + r.Host + fixed
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+	w.Header().Set("Location", location)
+	w.WriteHeader(code)
+	return true
+}
+
+// allowed returns sorted methods with routes of path and OPTIONS or empty string, if there are no such routes
+func (hr *httpRouter) allowed(reqMethod, path string) string {
+	allowed := make([]string, 0, len(hr.routes)+1)
+	for method := range hr.routes {
+		if method == reqMethod || method == http.MethodOptions {
+			continue
+		}
+		if route, _ := hr.match(method, path); route != nil {
+			allowed = append(allowed, method)
+		}
+	}
+	if len(allowed) == 0 {
+		return ""
+	}
+	allowed = append(allowed, http.MethodOptions)
+	sort.Strings(allowed)
+	return strings.Join(allowed, ", ")
+}
+
+// respondText writes status text without new line like fasthttp router
+func (hr *httpRouter) respondText(w http.ResponseWriter, code int) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(code)
+	_, _ = w.Write([]byte(http.StatusText(code)))
+}
+
+func (route *httpRoute) match(parts []string) (map[string]string, bool) {
+	params := make(map[string]string)
+	for i, segment := range route.segments {
+		if i >= len(parts) {
+			return nil, false
+		}
+		if segment.kind == segmentTail {
+			params[segment.value] = strings.Join(parts[i:], "/")
+			return params, true
+		}
+		switch segment.kind {
+		case segmentStatic:
+			if parts[i] != segment.value {
+				return nil, false
+			}
+		case segmentParam:
+			if parts[i] == "" || (segment.re != nil && !segment.re.MatchString(parts[i])) {
+				return nil, false
+			}
+			params[segment.value] = parts[i]
+		}
+	}
+	return params, len(parts) == len(route.segments)
+}
+
+// moreSpecific compares routes by segments: static segment wins parameter, parameter wins tail of path
+func (route *httpRoute) moreSpecific(other *httpRoute) bool {
+	for i := 0; i < len(route.segments) && i < len(other.segments); i++ {
+		if route.segments[i].kind != other.segments[i].kind {
+			return route.segments[i].kind < other.segments[i].kind
+		}
+	}
+	return len(route.segments) > len(other.segments)
+}
+
+func parsePattern(path string) []pathSegment {
+	if !strings.HasPrefix(path, "/") {
+		panic("path must begin with '/' in path '" + path + "'")
+	}
+	parts := strings.Split(path[1:], "/")
+	segments := make([]pathSegment, 0, len(parts))
+	for i, part := range parts {
+		if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+			if strings.ContainsAny(part, "{}") {
+				panic("parameter must be whole segment in path '" + path + "'")
+			}
+			segments = append(segments, pathSegment{kind: segmentStatic, value: part})
+			continue
+		}
+		name, expr := part[1:len(part)-1], ""
+		if colon := strings.IndexByte(name, ':'); colon >= 0 {
+			name, expr = name[:colon], name[colon+1:]
+		}
+		switch {
+		case expr == "*":
+			if i != len(parts)-1 {
+				panic("tail parameter must be last segment in path '" + path + "'")
+			}
+			segments = append(segments, pathSegment{kind: segmentTail, value: name})
+		case expr != "":
+			segments = append(segments, pathSegment{kind: segmentParam, value: name, re: regexp.MustCompile(expr)})
+		default:
+			segments = append(segments, pathSegment{kind: segmentParam, value: name})
+		}
+	}
+	return segments
+}
@@ -0,0 +1,96 @@
+package routes
+
+import (
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/satmaelstorm/filup/internal/infrastructure/metrics"
+	"github.com/satmaelstorm/filup/internal/infrastructure/web/handlers"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+)
+
+// ProvideHttpRoutes - net/http version of ProvideRoutes with the same route table and metrics
+func ProvideHttpRoutes(hs *handlers.HttpHandlers, health *handlers.HttpHealthHandlers, logger logsEngine.ILogger, ops Ops) http.Handler {
+	metrics.Register(requestCount, requestDuration)
+	r := newHttpRouter()
+
+	r.PanicHandler = func(w http.ResponseWriter, req *http.Request, i interface{}) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("Internal server error"))
+		requestCount.WithLabelValues(strconv.Itoa(http.StatusInternalServerError)).Inc()
+		logger.Critical().Printf("%s : %s\n", i, string(debug.Stack()))
+	}
+	if ops.Enabled {
+		for _, route := range opsRoutes {
+			handle := route.http
+			r.Handle(route.method, route.path, func(w http.ResponseWriter, req *http.Request) {
+				handle(health, w, req)
+			})
+		}
+	}
+
+	innerHandler := getHttpDomainRouter(hs)
+
+	r.ANY("/{path:*}", func(w http.ResponseWriter, req *http.Request) {
+		timeStart := time.Now()
+
+		path, _ := handlers.PathParam(req, "path")
+		req = handlers.HttpRequestId(w, req)
+		sw := &statusWriter{ResponseWriter: w}
+		innerHandler.ServeHTTP(sw, req)
+
+		timeElapsed := time.Since(timeStart)
+		c := sw.StatusCode()
+		code := strconv.Itoa(c)
+		requestCount.WithLabelValues(code).Inc()
+		if c != http.StatusNotFound &&
+			c != http.StatusMethodNotAllowed &&
+			c != http.StatusForbidden &&
+			c != http.StatusUnauthorized &&
+			c != http.StatusNotAcceptable {
+			requestDuration.WithLabelValues(path, code).Observe(timeElapsed.Seconds())
+		}
+	})
+
+	return r
+}
+
+func getHttpDomainRouter(hs *handlers.HttpHandlers) *httpRouter {
+	r := newHttpRouter()
+	for _, route := range domainRoutes {
+		handle := route.http
+		r.Handle(route.method, route.path, func(w http.ResponseWriter, req *http.Request) {
+			handle(hs, w, req)
+		})
+	}
+	return r
+}
+
+// statusWriter remembers status code of response for metrics
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	if sw.status == 0 {
+		sw.status = code
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(b)
+}
+
+func (sw *statusWriter) StatusCode() int {
+	if sw.status == 0 {
+		return http.StatusOK
+	}
+	return sw.status
+}
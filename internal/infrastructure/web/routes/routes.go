@@ -3,14 +3,11 @@ package routes
 import (
 	"github.com/fasthttp/router"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/satmaelstorm/filup/internal/infrastructure/config"
 	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
 	"github.com/satmaelstorm/filup/internal/infrastructure/metrics"
 	"github.com/satmaelstorm/filup/internal/infrastructure/web/handlers"
 	"github.com/valyala/fasthttp"
-	"github.com/valyala/fasthttp/fasthttpadaptor"
-	"github.com/valyala/fasthttp/pprofhandler"
 	"runtime/debug"
 	"strconv"
 	"time"
@@ -30,8 +27,8 @@ var (
 	}, []string{"code"})
 )
 
-func ProvideRoutes(hs *handlers.Handlers, logger logsEngine.ILogger) *router.Router {
-	prometheus.MustRegister(requestCount, requestDuration)
+func ProvideRoutes(hs *handlers.Handlers, health *handlers.HealthHandlers, logger logsEngine.ILogger, ops Ops) *router.Router {
+	metrics.Register(requestCount, requestDuration)
 	r := router.New()
 
 	r.PanicHandler = func(ctx *fasthttp.RequestCtx, i interface{}) {
@@ -40,15 +37,24 @@ func ProvideRoutes(hs *handlers.Handlers, logger logsEngine.ILogger) *router.Rou
 		requestCount.WithLabelValues(strconv.Itoa(fasthttp.StatusInternalServerError)).Inc()
 		logger.Critical().Printf("%s : %s\n", i, string(debug.Stack()))
 	}
-	r.GET("/debug/pprof/{ep:*}", pprofhandler.PprofHandler)
-	r.GET(Metrics, fasthttpadaptor.NewFastHTTPHandler(promhttp.Handler()))
+	if ops.Enabled {
+		for _, route := range opsRoutes {
+			handle := route.fast
+			r.Handle(route.method, route.path, func(ctx *fasthttp.RequestCtx) {
+				handle(health, ctx)
+			})
+		}
+	}
 
 	innerHandler := getDomainRouter(hs).Handler
 
 	r.ANY("/{path:*}", func(ctx *fasthttp.RequestCtx) {
 		timeStart := time.Now()
 
+		requestId := handlers.RequestId(ctx)
 		innerHandler(ctx)
+		// not found response of router resets headers
+		ctx.Response.Header.Set(handlers.RequestIdHeader, requestId)
 
 		timeElapsed := time.Since(timeStart)
 		c := ctx.Response.StatusCode()
@@ -68,13 +74,11 @@ func ProvideRoutes(hs *handlers.Handlers, logger logsEngine.ILogger) *router.Rou
 
 func getDomainRouter(hs *handlers.Handlers) *router.Router {
 	r := router.New()
-	r.GET("/upload", func(ctx *fasthttp.RequestCtx) {
-		ctx.Response.SetBodyString("upload api")
-	})
-
-	r.POST(StartUpload, hs.StartUpload)
-	r.POST(UploadPart, hs.PartUpload)
-	r.GET(DownloadFile, hs.DownloadFile)
-
+	for _, route := range domainRoutes {
+		handle := route.fast
+		r.Handle(route.method, route.path, func(ctx *fasthttp.RequestCtx) {
+			handle(hs, ctx)
+		})
+	}
 	return r
 }
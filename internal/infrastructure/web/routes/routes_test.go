@@ -0,0 +1,201 @@
+package routes
+
+import (
+	"context"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/satmaelstorm/filup/internal/infrastructure/web/handlers"
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testUuid = "870915da-76bb-11ec-8686-e4e7494803df"
+
+type fakeUploaderConfig struct {
+	port.UploaderConfig
+}
+
+func (fakeUploaderConfig) GetChunkLength() int64 {
+	return 1024
+}
+
+type fakeStartUpload struct{}
+
+func (fakeStartUpload) Handle(context.Context, [][2]string, []byte) ([]byte, error) {
+	panic("start is broken")
+}
+
+type fakeSession struct {
+	aborted string
+}
+
+func (f *fakeSession) Status(_ context.Context, _ string, uuid string) ([]byte, error) {
+	return []byte(`{"uuid":"` + uuid + `"}`), nil
+}
+
+func (f *fakeSession) Abort(_ context.Context, _ string, uuid string) error {
+	f.aborted = uuid
+	return nil
+}
+
+// response of router, which must be the same for both transports
+type response struct {
+	code      int
+	body      string
+	allow     string
+	location  string
+	requestId bool
+}
+
+type suiteRoutes struct {
+	suite.Suite
+	session *fakeSession
+}
+
+func TestRoutes(t *testing.T) {
+	suite.Run(t, new(suiteRoutes))
+}
+
+func (s *suiteRoutes) SetupTest() {
+	s.session = &fakeSession{}
+}
+
+// serve sends request by both transports and checks, that responses are equal
+func (s *suiteRoutes) serve(ops Ops, method string, target string) response {
+	loggers := logsEngine.InitLoggersEmpty("test")
+	hs := handlers.ProvideHandlers(&loggers, fakeStartUpload{}, nil, s.session, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	health := handlers.ProvideHealthHandlers(nil)
+
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	req.SetRequestURI("http://example.com" + target)
+	var ctx fasthttp.RequestCtx
+	ctx.Init(&req, nil, nil)
+	ProvideRoutes(hs, health, &loggers, ops).Handler(&ctx)
+	fast := response{
+		code:      ctx.Response.StatusCode(),
+		body:      string(ctx.Response.Body()),
+		allow:     string(ctx.Response.Header.Peek("Allow")),
+		location:  string(ctx.Response.Header.Peek("Location")),
+		requestId: len(ctx.Response.Header.Peek(handlers.RequestIdHeader)) > 0,
+	}
+
+	w := httptest.NewRecorder()
+	httpRoutes := ProvideHttpRoutes(
+		handlers.ProvideHttpHandlers(hs, fakeUploaderConfig{}),
+		handlers.ProvideHttpHealthHandlers(health),
+		&loggers,
+		ops,
+	)
+	httpRoutes.ServeHTTP(w, httptest.NewRequest(method, "http://example.com"+target, nil))
+	s.Equal(fast, response{
+		code:      w.Code,
+		body:      w.Body.String(),
+		allow:     w.Header().Get("Allow"),
+		location:  w.Header().Get("Location"),
+		requestId: w.Header().Get(handlers.RequestIdHeader) != "",
+	}, method+" "+target)
+	return fast
+}
+
+func (s *suiteRoutes) TestDomainRoutes() {
+	result := s.serve(Ops{}, http.MethodGet, Upload)
+	s.Equal(http.StatusOK, result.code)
+	s.Equal("upload api", result.body)
+	s.True(result.requestId)
+
+	result = s.serve(Ops{}, http.MethodGet, Upload+"/"+testUuid)
+	s.Equal(http.StatusOK, result.code)
+	s.Equal(`{"uuid":"`+testUuid+`"}`, result.body)
+
+	result = s.serve(Ops{}, http.MethodDelete, Upload+"/"+testUuid)
+	s.Equal(http.StatusNoContent, result.code)
+	s.Equal(testUuid, s.session.aborted)
+}
+
+func (s *suiteRoutes) TestRouterResponses() {
+	result := s.serve(Ops{}, http.MethodPut, StartUpload)
+	s.Equal(http.StatusMethodNotAllowed, result.code)
+	s.Equal("OPTIONS, POST", result.allow)
+
+	s.Equal(http.StatusNotFound, s.serve(Ops{}, http.MethodGet, Upload+"/not-uuid").code)
+	s.Equal(http.StatusNotFound, s.serve(Ops{}, http.MethodGet, "/unknown").code)
+
+	result = s.serve(Ops{}, http.MethodGet, Files+"/?limit=1")
+	s.Equal(http.StatusMovedPermanently, result.code)
+	s.Equal("http://example.com/files?limit=1", result.location)
+
+	result = s.serve(Ops{}, http.MethodOptions, StartUpload)
+	s.Equal(http.StatusOK, result.code)
+	s.Equal("OPTIONS, POST", result.allow)
+
+	result = s.serve(Ops{}, http.MethodPost, StartUpload+"/")
+	s.Equal(http.StatusPermanentRedirect, result.code)
+	s.Equal("http://example.com"+StartUpload, result.location)
+
+	s.Equal(http.StatusInternalServerError, s.serve(Ops{}, http.MethodPost, StartUpload).code)
+}
+
+func (s *suiteRoutes) TestHttpRouterParams() {
+	r := newHttpRouter()
+	var params map[string]string
+	remember := func(names ...string) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			params = make(map[string]string)
+			for _, name := range names {
+				params[name], _ = handlers.PathParam(req, name)
+			}
+		}
+	}
+	r.Handle(http.MethodGet, DownloadFile, remember(DownloadUuidParameter))
+	r.Handle(http.MethodGet, DownloadArchive, remember())
+	r.Handle(http.MethodPost, Admin+AdminFailedCallbackReplay, remember(CallbackIdParameter))
+	r.Handle(http.MethodGet, Pprof, remember(PprofParameter))
+
+	cases := []struct {
+		method string
+		target string
+		params map[string]string
+	}{
+		{http.MethodGet, Download + "/" + testUuid, map[string]string{DownloadUuidParameter: testUuid}},
+		{http.MethodGet, DownloadArchive, map[string]string{}},
+		{http.MethodPost, Admin + AdminFailedCallbacks + "/a1/replay", map[string]string{CallbackIdParameter: "a1"}},
+		{http.MethodGet, "/debug/pprof/", map[string]string{PprofParameter: ""}},
+		{http.MethodGet, "/debug/pprof/heap", map[string]string{PprofParameter: "heap"}},
+	}
+	for _, c := range cases {
+		params = nil
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.method, c.target, nil))
+		s.Equal(http.StatusOK, w.Code, c.target)
+		s.Equal(c.params, params, c.target)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, Admin+AdminFailedCallbacks+"//replay", nil))
+	s.Equal(http.StatusNotFound, w.Code)
+}
+
+func (s *suiteRoutes) TestOps() {
+	s.Equal(http.StatusNotFound, s.serve(Ops{}, http.MethodGet, HealthLive).code)
+	s.Equal(http.StatusNotFound, s.serve(Ops{}, http.MethodGet, Metrics).code)
+
+	result := s.serve(Ops{Enabled: true}, http.MethodGet, HealthLive)
+	s.Equal(http.StatusOK, result.code)
+	s.Equal(`{"live":true}`, result.body)
+	s.False(result.requestId)
+}
+
+func (s *suiteRoutes) TestTable() {
+	seen := make(map[string]bool)
+	for _, route := range domainRoutes {
+		key := route.method + " " + route.path
+		s.False(seen[key], key)
+		seen[key] = true
+		s.NotNil(route.fast, key)
+		s.NotNil(route.http, key)
+	}
+}
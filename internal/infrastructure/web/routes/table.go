@@ -0,0 +1,96 @@
+package routes
+
+import (
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/satmaelstorm/filup/internal/infrastructure/web/handlers"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+	"github.com/valyala/fasthttp/pprofhandler"
+	"net/http"
+	"net/http/pprof"
+)
+
+// route of domain, which is served by both transports: fasthttp by Handlers and net/http by HttpHandlers
+type route struct {
+	method string
+	path   string
+	fast   func(hs *handlers.Handlers, ctx *fasthttp.RequestCtx)
+	http   func(hs *handlers.HttpHandlers, w http.ResponseWriter, r *http.Request)
+}
+
+// opsRoute - operational route, which is served only by option Ops
+type opsRoute struct {
+	method string
+	path   string
+	fast   func(health *handlers.HealthHandlers, ctx *fasthttp.RequestCtx)
+	http   func(health *handlers.HttpHealthHandlers, w http.ResponseWriter, r *http.Request)
+}
+
+var domainRoutes = []route{
+	{http.MethodGet, Upload, (*handlers.Handlers).UploadApi, (*handlers.HttpHandlers).UploadApi},
+	{http.MethodPost, StartUpload, (*handlers.Handlers).StartUpload, (*handlers.HttpHandlers).StartUpload},
+	{http.MethodPost, UploadPart, (*handlers.Handlers).PartUpload, (*handlers.HttpHandlers).PartUpload},
+	{http.MethodGet, UploadSession, (*handlers.Handlers).UploadStatus, (*handlers.HttpHandlers).UploadStatus},
+	{http.MethodDelete, UploadSession, (*handlers.Handlers).AbortUpload, (*handlers.HttpHandlers).AbortUpload},
+	{http.MethodGet, DownloadFile, (*handlers.Handlers).DownloadFile, (*handlers.HttpHandlers).DownloadFile},
+	{http.MethodGet, DownloadImage, (*handlers.Handlers).DownloadImage, (*handlers.HttpHandlers).DownloadImage},
+	{http.MethodPost, DownloadArchive, (*handlers.Handlers).DownloadArchive, (*handlers.HttpHandlers).DownloadArchive},
+	{http.MethodGet, Files, (*handlers.Handlers).ListFiles, (*handlers.HttpHandlers).ListFiles},
+	{http.MethodGet, FileInfo, (*handlers.Handlers).GetFile, (*handlers.HttpHandlers).GetFile},
+	{http.MethodDelete, FileInfo, (*handlers.Handlers).DeleteFile, (*handlers.HttpHandlers).DeleteFile},
+
+	// every admin request is authorized by admin token
+	{http.MethodPost, Admin + AdminSign, (*handlers.Handlers).SignUrl, (*handlers.HttpHandlers).SignUrl},
+	{http.MethodGet, Admin + AdminLogLevel, (*handlers.Handlers).GetLogLevel, (*handlers.HttpHandlers).GetLogLevel},
+	{http.MethodPut, Admin + AdminLogLevel, (*handlers.Handlers).SetLogLevel, (*handlers.HttpHandlers).SetLogLevel},
+	{http.MethodGet, Admin + AdminHealth, (*handlers.Handlers).HealthDetails, (*handlers.HttpHandlers).HealthDetails},
+	{http.MethodGet, Admin + AdminUploads, (*handlers.Handlers).ListUploads, (*handlers.HttpHandlers).ListUploads},
+	{http.MethodGet, Admin + AdminUpload, (*handlers.Handlers).GetUpload, (*handlers.HttpHandlers).GetUpload},
+	{http.MethodPost, Admin + AdminUploadCompose, (*handlers.Handlers).ComposeUpload, (*handlers.HttpHandlers).ComposeUpload},
+	{http.MethodPost, Admin + AdminUploadExpire, (*handlers.Handlers).ExpireUpload, (*handlers.HttpHandlers).ExpireUpload},
+	{http.MethodPost, Admin + AdminFileCallbackAfter, (*handlers.Handlers).ResendCallbackAfter, (*handlers.HttpHandlers).ResendCallbackAfter},
+	{http.MethodGet, Admin + AdminFailedCallbacks, (*handlers.Handlers).ListFailedCallbacks, (*handlers.HttpHandlers).ListFailedCallbacks},
+	{http.MethodPost, Admin + AdminFailedCallbackReplay, (*handlers.Handlers).ReplayCallback, (*handlers.HttpHandlers).ReplayCallback},
+	{http.MethodPost, Admin + AdminCacheFlush, (*handlers.Handlers).FlushCache, (*handlers.HttpHandlers).FlushCache},
+}
+
+var opsRoutes = []opsRoute{
+	{http.MethodGet, Pprof, fastPprof, httpPprof},
+	{http.MethodGet, Metrics, fastMetrics, httpMetrics},
+	{http.MethodGet, Health, (*handlers.HealthHandlers).Ready, (*handlers.HttpHealthHandlers).Ready},
+	{http.MethodGet, HealthLive, (*handlers.HealthHandlers).Live, (*handlers.HttpHealthHandlers).Live},
+	{http.MethodGet, HealthReady, (*handlers.HealthHandlers).Ready, (*handlers.HttpHealthHandlers).Ready},
+}
+
+var (
+	promHandler     = promhttp.Handler()
+	fastPromHandler = fasthttpadaptor.NewFastHTTPHandler(promHandler)
+)
+
+func fastMetrics(_ *handlers.HealthHandlers, ctx *fasthttp.RequestCtx) {
+	fastPromHandler(ctx)
+}
+
+func httpMetrics(_ *handlers.HttpHealthHandlers, w http.ResponseWriter, r *http.Request) {
+	promHandler.ServeHTTP(w, r)
+}
+
+func fastPprof(_ *handlers.HealthHandlers, ctx *fasthttp.RequestCtx) {
+	pprofhandler.PprofHandler(ctx)
+}
+
+func httpPprof(_ *handlers.HttpHealthHandlers, w http.ResponseWriter, r *http.Request) {
+	ep, _ := handlers.PathParam(r, PprofParameter)
+	switch ep {
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default:
+		pprof.Index(w, r)
+	}
+}
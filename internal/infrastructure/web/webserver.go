@@ -5,7 +5,9 @@ import (
 	"github.com/fasthttp/router"
 	"github.com/satmaelstorm/filup/internal/infrastructure/appctx"
 	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/internal/infrastructure/health"
 	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/satmaelstorm/filup/internal/infrastructure/web/handlers"
 	"github.com/valyala/fasthttp"
 	"net/http"
 	"os"
@@ -21,9 +23,11 @@ type Server struct {
 	IsStarted atomic.Value
 	Router    *router.Router
 	Config    config.HTTP
+	MaxBody   int
 	Ctx       context.Context
 	Logs      logsEngine.ILogger
 	Cc        *appctx.CoreContext
+	Health    *health.Health
 }
 
 func ProvideWebServer(
@@ -31,15 +35,18 @@ func ProvideWebServer(
 	routes *router.Router,
 	cfg config.Configuration,
 	logs logsEngine.ILogger,
+	processHealth *health.Health,
 ) *Server {
 	webServer := new(Server)
 	webServer.Router = routes
 	webServer.IsStarted.Store(false)
 	webServer.Stop = make(chan bool)
 	webServer.Config = cfg.Http
+	webServer.MaxBody = int(handlers.MaxPartBodySize(config.ProvideUploaderConfig(cfg)))
 	webServer.Ctx = ctx.Ctx()
 	webServer.Logs = logs
 	webServer.Cc = ctx
+	webServer.Health = processHealth
 	return webServer
 }
 
@@ -58,6 +65,7 @@ func (w *Server) Serve() {
 		Handler:            w.Router.Handler,
 		ReadTimeout:        w.Config.GetTimeout(),
 		WriteTimeout:       w.Config.GetTimeout(),
+		MaxRequestBodySize: w.MaxBody,
 		Logger:             w.Logs.Debug(),
 		DisableKeepalive:   true,
 		TCPKeepalive:       false,
@@ -66,6 +74,7 @@ func (w *Server) Serve() {
 	}
 	go func() {
 		w.IsStarted.Store(true)
+		w.Health.SetReady(true)
 		err := w.Server.ListenAndServe(":" + w.Config.Port)
 		if err != nil {
 			if err != http.ErrServerClosed {
@@ -111,6 +120,9 @@ func (w *Server) Run() {
 	go func(ch <-chan os.Signal, st chan<- bool) {
 		<-ch
 		w.Logs.Trace().Println("STOP received")
+		// balancer stops sending requests, while running requests are completed
+		w.Health.SetReady(false)
+		time.Sleep(w.Config.GetShutdownDelay())
 		w.ServerStop()
 		w.Logs.Trace().Println("WebServer STOP send")
 		w.Cc.Cancel()
@@ -8,6 +8,6 @@ import (
 func main() {
 	err := cmd.Execute()
 	if err != nil {
-		log.Println(err)
+		log.Fatalln(err)
 	}
 }
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// headers of callbacks, which are signed with uploader.callbackSecret of server
+const (
+	CallbackTimestampHeader  = "X-Filup-Timestamp"
+	CallbackSignatureHeader  = "X-Filup-Signature"
+	DefaultCallbackTolerance = 5 * time.Minute
+
+	callbackSignatureVersion = "v1="
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature of callback")
+	ErrExpiredSignature = errors.New("timestamp of callback is out of tolerance")
+)
+
+// SignCallback returns signature of callback: hex encoded HMAC-SHA256 of timestamp, dot and body with version prefix
+func SignCallback(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return callbackSignatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyCallback checks signature of callback body. Timestamp must differ from current time less than tolerance,
+// zero tolerance disables check of time
+func VerifyCallback(secret []byte, timestamp string, signature string, body []byte, tolerance time.Duration) error {
+	if !hmac.Equal([]byte(signature), []byte(SignCallback(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+	if tolerance <= 0 {
+		return nil
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	diff := time.Since(time.Unix(unix, 0))
+	if diff > tolerance || diff < -tolerance {
+		return ErrExpiredSignature
+	}
+	return nil
+}
+
+// VerifyCallbackRequest checks signature of callback request and returns its body. Body of request can be read again
+func VerifyCallbackRequest(r *http.Request, secret []byte, tolerance time.Duration) ([]byte, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	err = VerifyCallback(secret, r.Header.Get(CallbackTimestampHeader), r.Header.Get(CallbackSignatureHeader), body, tolerance)
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
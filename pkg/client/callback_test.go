@@ -0,0 +1,63 @@
+package client
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type suiteCallback struct {
+	suite.Suite
+	secret []byte
+	body   []byte
+}
+
+func TestCallback(t *testing.T) {
+	suite.Run(t, new(suiteCallback))
+}
+
+func (s *suiteCallback) SetupTest() {
+	s.secret = []byte("secret")
+	s.body = []byte(`{"uuid":"1"}`)
+}
+
+func (s *suiteCallback) TestSignature() {
+	// signature must not change, backends verify callbacks of servers with older versions
+	s.Equal("v1=42dd8d5dd63130ba3719c594e40b78956344a664a8e1ff17178aa989cd10da42", SignCallback(s.secret, "1700000000", s.body))
+}
+
+func (s *suiteCallback) TestVerify() {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := SignCallback(s.secret, now, s.body)
+	s.Nil(VerifyCallback(s.secret, now, signature, s.body, DefaultCallbackTolerance))
+	s.Equal(ErrInvalidSignature, VerifyCallback([]byte("other"), now, signature, s.body, DefaultCallbackTolerance))
+	s.Equal(ErrInvalidSignature, VerifyCallback(s.secret, now, signature, []byte(`{"uuid":"2"}`), DefaultCallbackTolerance))
+
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	signature = SignCallback(s.secret, old, s.body)
+	s.Equal(ErrExpiredSignature, VerifyCallback(s.secret, old, signature, s.body, DefaultCallbackTolerance))
+	s.Nil(VerifyCallback(s.secret, old, signature, s.body, 0))
+}
+
+func (s *suiteCallback) TestVerifyRequest() {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	r := httptest.NewRequest(http.MethodPost, "/after", bytes.NewReader(s.body))
+	r.Header.Set(CallbackTimestampHeader, now)
+	r.Header.Set(CallbackSignatureHeader, SignCallback(s.secret, now, s.body))
+	body, err := VerifyCallbackRequest(r, s.secret, DefaultCallbackTolerance)
+	s.Require().Nil(err)
+	s.Equal(s.body, body)
+	again, err := ioutil.ReadAll(r.Body)
+	s.Require().Nil(err)
+	s.Equal(s.body, again)
+
+	r = httptest.NewRequest(http.MethodPost, "/after", bytes.NewReader(s.body))
+	r.Header.Set(CallbackTimestampHeader, now)
+	_, err = VerifyCallbackRequest(r, s.secret, DefaultCallbackTolerance)
+	s.Equal(ErrInvalidSignature, err)
+}
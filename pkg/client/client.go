@@ -0,0 +1,155 @@
+// Package client uploads and downloads files through Filup: it starts upload, sends chunks in parallel with retries
+// and resumes interrupted uploads by status of upload
+package client
+
+import (
+	"context"
+	jsoniter "github.com/json-iterator/go"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultInfoFieldName = "_uploader_info"
+	DefaultConcurrency   = 4
+	DefaultRetries       = 3
+	DefaultRetryDelay    = time.Second
+
+	userAgent    = "filup-client"
+	maxErrorBody = 4096
+)
+
+// Client of Filup server, it is safe for concurrent use
+type Client struct {
+	baseUrl       string
+	httpClient    *http.Client
+	headers       http.Header
+	infoFieldName string
+	concurrency   int
+	retries       int
+	retryDelay    time.Duration
+}
+
+type Option func(c *Client)
+
+// WithHttpClient sets http client, http.DefaultClient is used by default
+func WithHttpClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithHeader adds header to every request, for example token of authentication
+func WithHeader(name, value string) Option {
+	return func(c *Client) {
+		c.headers.Add(name, value)
+	}
+}
+
+// WithInfoFieldName sets field of start request with upload info, it must be equal to uploader.infoFieldName of server
+func WithInfoFieldName(name string) Option {
+	return func(c *Client) {
+		c.infoFieldName = name
+	}
+}
+
+// WithConcurrency sets count of chunks, which are sent in parallel
+func WithConcurrency(concurrency int) Option {
+	return func(c *Client) {
+		if concurrency > 0 {
+			c.concurrency = concurrency
+		}
+	}
+}
+
+// WithRetries sets count of retries of chunk and delay before first retry, delay grows with every attempt
+func WithRetries(retries int, delay time.Duration) Option {
+	return func(c *Client) {
+		if retries >= 0 {
+			c.retries = retries
+		}
+		c.retryDelay = delay
+	}
+}
+
+// New makes client of server with baseUrl, for example http://localhost:8080
+func New(baseUrl string, options ...Option) *Client {
+	c := &Client{
+		baseUrl:       strings.TrimRight(baseUrl, "/"),
+		httpClient:    http.DefaultClient,
+		headers:       make(http.Header),
+		infoFieldName: DefaultInfoFieldName,
+		concurrency:   DefaultConcurrency,
+		retries:       DefaultRetries,
+		retryDelay:    DefaultRetryDelay,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Error - response of server with not 2xx code
+type Error struct {
+	Code int
+	Body []byte
+}
+
+func (e *Error) Error() string {
+	message := "filup responded with code " + strconv.Itoa(e.Code)
+	if len(e.Body) > 0 {
+		message += ": " + strings.TrimSpace(string(e.Body))
+	}
+	return message
+}
+
+// IsTemporary - request can be repeated
+func (e *Error) IsTemporary() bool {
+	return e.Code >= http.StatusInternalServerError || e.Code == http.StatusTooManyRequests
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseUrl+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range c.headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	req.Header.Set("User-Agent", userAgent)
+	return req, nil
+}
+
+// do sends request and returns response with 2xx code, other responses are returned as *Error
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, &Error{Code: resp.StatusCode, Body: body}
+	}
+	return resp, nil
+}
+
+// doJson sends request and decodes json response to result
+func (c *Client) doJson(req *http.Request, result interface{}) error {
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	return jsoniter.NewDecoder(resp.Body).Decode(result)
+}
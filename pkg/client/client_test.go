@@ -0,0 +1,231 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testUuid   = "870915da-76bb-11ec-8686-e4e7494803df"
+	testSecret = "upload-secret"
+)
+
+// fakeServer - upload api of Filup, which keeps received chunks in memory
+type fakeServer struct {
+	mu       sync.Mutex
+	upload   Upload
+	chunks   map[string][]byte
+	start    map[string]interface{}
+	failures map[string]int
+	failCode int
+	aborted  bool
+}
+
+func newFakeServer(size int64, chunkSize int64) *fakeServer {
+	upload := Upload{Uuid: testUuid, Size: size, Chunks: make(map[string]Chunk)}
+	for num, offset := 0, int64(0); offset < size; num, offset = num+1, offset+chunkSize {
+		chunk := Chunk{Name: ChunkFileName(testUuid, num), Offset: offset, Size: chunkSize}
+		if offset+chunkSize > size {
+			chunk.Size = size - offset
+		}
+		upload.Chunks[chunk.Name] = chunk
+	}
+	return &fakeServer{upload: upload, chunks: make(map[string][]byte), failures: make(map[string]int)}
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/upload/start":
+		_ = jsoniter.NewDecoder(r.Body).Decode(&f.start)
+		upload := f.upload
+		upload.UploadSecret = testSecret
+		f.writeJson(w, upload)
+	case r.URL.Path == "/upload/part":
+		if r.Header.Get(UploadSecretHeader) != testSecret {
+			http.Error(w, "invalid secret", http.StatusForbidden)
+			return
+		}
+		file, header, err := r.FormFile("part")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if f.failures[header.Filename] > 0 {
+			f.failures[header.Filename]--
+			http.Error(w, "failure", f.failCode)
+			return
+		}
+		content, _ := ioutil.ReadAll(file)
+		f.chunks[header.Filename] = content
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/upload/"+testUuid && r.Method == http.MethodGet:
+		status := f.upload
+		for name := range f.chunks {
+			status.UploadedChunks = append(status.UploadedChunks, name)
+		}
+		f.writeJson(w, status)
+	case r.URL.Path == "/upload/"+testUuid && r.Method == http.MethodDelete:
+		f.aborted = true
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/download/"+testUuid:
+		_, _ = w.Write(f.join())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeServer) writeJson(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = jsoniter.NewEncoder(w).Encode(value)
+}
+
+// content returns chunks, which are received by server, in order of file
+func (f *fakeServer) content() []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.join()
+}
+
+// received returns chunk, handlers of server can still run after failed upload
+func (f *fakeServer) received(name string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	content, ok := f.chunks[name]
+	return content, ok
+}
+
+func (f *fakeServer) join() []byte {
+	var content []byte
+	for _, chunk := range f.upload.SortedChunks() {
+		content = append(content, f.chunks[chunk.Name]...)
+	}
+	return content
+}
+
+type suiteClient struct {
+	suite.Suite
+	server *fakeServer
+	http   *httptest.Server
+	client *Client
+	file   []byte
+}
+
+func TestClient(t *testing.T) {
+	suite.Run(t, new(suiteClient))
+}
+
+func (s *suiteClient) SetupTest() {
+	s.file = bytes.Repeat([]byte("0123456789"), 5)
+	s.server = newFakeServer(int64(len(s.file)), 15)
+	s.http = httptest.NewServer(s.server)
+	s.client = New(s.http.URL+"/", WithRetries(2, time.Millisecond), WithConcurrency(2))
+}
+
+func (s *suiteClient) TearDownTest() {
+	s.http.Close()
+}
+
+func (s *suiteClient) TestUpload() {
+	var mu sync.Mutex
+	var last int64
+	upload, err := s.client.Upload(context.Background(), UploadRequest{
+		Size:     int64(len(s.file)),
+		FileName: "digits.txt",
+		Fields:   map[string]interface{}{"project": "filup"},
+	}, bytes.NewReader(s.file), func(sent int64, total int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		if sent > last {
+			last = sent
+		}
+		s.Equal(int64(len(s.file)), total)
+	})
+	s.Require().Nil(err)
+	s.Equal(testSecret, upload.UploadSecret)
+	s.Equal(int64(len(s.file)), last)
+	s.Equal(s.file, s.server.content())
+
+	info, ok := s.server.start[DefaultInfoFieldName].(map[string]interface{})
+	s.Require().True(ok)
+	s.Equal("digits.txt", info["file_name"])
+	s.Equal(float64(len(s.file)), info["file_size"])
+	s.Equal("filup", s.server.start["project"])
+
+	var downloaded bytes.Buffer
+	n, err := s.client.Download(context.Background(), testUuid, &downloaded)
+	s.Require().Nil(err)
+	s.Equal(int64(len(s.file)), n)
+	s.Equal(s.file, downloaded.Bytes())
+}
+
+func (s *suiteClient) TestResume() {
+	first := ChunkFileName(testUuid, 0)
+	s.server.chunks[first] = []byte("uploaded before")
+	upload, err := s.client.Resume(context.Background(), testUuid, testSecret, bytes.NewReader(s.file), nil)
+	s.Require().Nil(err)
+	s.Equal([]string{first}, upload.UploadedChunks)
+	// uploaded chunk is not sent again
+	content, _ := s.server.received(first)
+	s.Equal([]byte("uploaded before"), content)
+	for name := range s.server.upload.Chunks {
+		_, ok := s.server.received(name)
+		s.True(ok, name)
+	}
+}
+
+func (s *suiteClient) TestRetries() {
+	s.server.failCode = http.StatusServiceUnavailable
+	s.server.failures[ChunkFileName(testUuid, 1)] = 2
+	_, err := s.client.Upload(context.Background(), UploadRequest{Size: int64(len(s.file))}, bytes.NewReader(s.file), nil)
+	s.Require().Nil(err)
+	s.Equal(s.file, s.server.content())
+
+	s.server.chunks = make(map[string][]byte)
+	s.server.failures[ChunkFileName(testUuid, 1)] = 3
+	_, err = s.client.Upload(context.Background(), UploadRequest{Size: int64(len(s.file))}, bytes.NewReader(s.file), nil)
+	var apiErr *Error
+	s.Require().True(errors.As(err, &apiErr))
+	s.Equal(http.StatusServiceUnavailable, apiErr.Code)
+	s.True(apiErr.IsTemporary())
+}
+
+func (s *suiteClient) TestFinalError() {
+	s.server.failCode = http.StatusRequestEntityTooLarge
+	s.server.failures[ChunkFileName(testUuid, 0)] = 1
+	_, err := s.client.Upload(context.Background(), UploadRequest{Size: int64(len(s.file))}, bytes.NewReader(s.file), nil)
+	var apiErr *Error
+	s.Require().True(errors.As(err, &apiErr))
+	s.Equal(http.StatusRequestEntityTooLarge, apiErr.Code)
+	s.False(apiErr.IsTemporary())
+	s.True(strings.HasSuffix(apiErr.Error(), ": failure"))
+	// final error is not retried, so chunk is not received
+	_, ok := s.server.received(ChunkFileName(testUuid, 0))
+	s.False(ok)
+
+	s.EqualError(s.client.SendChunks(context.Background(), Upload{}, bytes.NewReader(s.file), nil), "upload secret is required")
+}
+
+func (s *suiteClient) TestAbort() {
+	s.Require().Nil(s.client.Abort(context.Background(), testUuid, testSecret))
+	s.True(s.server.aborted)
+	var apiErr *Error
+	s.Require().True(errors.As(s.client.Abort(context.Background(), "unknown", testSecret), &apiErr))
+	s.Equal(http.StatusNotFound, apiErr.Code)
+}
+
+func (s *suiteClient) TestNames() {
+	s.Equal(testUuid+"_part_2", ChunkFileName(testUuid, 2))
+	s.Equal(testUuid+"_meta", MetaFileName(testUuid))
+}
@@ -0,0 +1,24 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Download writes file to w and returns count of written bytes
+func (c *Client) Download(ctx context.Context, uuid string, w io.Writer) (int64, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/download/"+url.PathEscape(uuid), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	return io.Copy(w, resp.Body)
+}
@@ -0,0 +1,26 @@
+package client
+
+import "strconv"
+
+// pieces of names of upload objects in storage, server names objects by the same functions
+const (
+	PartFilenamePiece = "_part_"
+	MetaFilenamePiece = "_meta"
+)
+
+// names, by which secret of upload is sent to server
+const (
+	UploadSecretHeader = "X-Upload-Secret"
+	// UploadSecretField - field of multipart form with chunk, used when header can not be set
+	UploadSecretField = "upload_secret"
+)
+
+// ChunkFileName returns name of chunk num of upload, names of chunks are also in plan of upload
+func ChunkFileName(uuid string, num int) string {
+	return uuid + PartFilenamePiece + strconv.Itoa(num)
+}
+
+// MetaFileName returns name of meta information of upload in meta bucket
+func MetaFileName(uuid string) string {
+	return uuid + MetaFilenamePiece
+}
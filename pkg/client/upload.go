@@ -0,0 +1,320 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	jsoniter "github.com/json-iterator/go"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// UploadRequest - parameters of start of upload. Upload with Uuid is resumed, if it was started before with the same parameters
+type UploadRequest struct {
+	Uuid        string
+	Size        int64
+	FileName    string
+	ContentType string
+	Tenant      string
+	Route       string
+	UserTags    map[string]string
+	Overwrite   bool
+	// UploadSecret - secret of started upload, start with it returns plan of upload without new authorization
+	UploadSecret string
+	// Fields are added to start request and are passed on callbackBefore
+	Fields map[string]interface{}
+}
+
+type Chunk struct {
+	Name   string `json:"name"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+}
+
+// Upload - plan of upload, which is returned by start and status of upload
+type Upload struct {
+	Uuid        string            `json:"uuid"`
+	Size        int64             `json:"size"`
+	UserTags    map[string]string `json:"user_tags"`
+	Chunks      map[string]Chunk  `json:"chunks"`
+	Bucket      string            `json:"bucket,omitempty"`
+	Key         string            `json:"key,omitempty"`
+	FileName    string            `json:"file_name,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Tenant      string            `json:"tenant,omitempty"`
+	Route       string            `json:"route,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	CreatedAt   int64             `json:"created_at,omitempty"`
+	// DetectedContentType - MIME type, which is detected by server by first bytes of file
+	DetectedContentType string   `json:"detected_content_type,omitempty"`
+	Processors          []string `json:"processors,omitempty"`
+	UploadSecret        string   `json:"upload_secret,omitempty"`
+	UploadedChunks      []string `json:"uploaded_chunks,omitempty"`
+}
+
+// Object - object in storage, which is made from uploaded file
+type Object struct {
+	Bucket string `json:"bucket,omitempty"`
+	Key    string `json:"key"`
+}
+
+type ScanVerdict struct {
+	Status    string `json:"status"`
+	Signature string `json:"signature,omitempty"`
+	Action    string `json:"action,omitempty"`
+}
+
+type ProcessorResult struct {
+	Name    string                 `json:"name"`
+	Status  string                 `json:"status"`
+	Error   string                 `json:"error,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	Objects []Object               `json:"objects,omitempty"`
+}
+
+// CompletedUpload - body of callbackAfter
+type CompletedUpload struct {
+	Upload
+	Scan       *ScanVerdict      `json:"scan,omitempty"`
+	Processing []ProcessorResult `json:"processing,omitempty"`
+}
+
+// SortedChunks returns chunks ordered by offset
+func (u Upload) SortedChunks() []Chunk {
+	chunks := make([]Chunk, 0, len(u.Chunks))
+	for _, chunk := range u.Chunks {
+		chunks = append(chunks, chunk)
+	}
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].Offset < chunks[j].Offset
+	})
+	return chunks
+}
+
+// Progress is called after every sent chunk with count of sent and total bytes, calls can be concurrent
+type Progress func(sent int64, total int64)
+
+// Start starts upload and returns plan of chunks with upload secret
+func (c *Client) Start(ctx context.Context, request UploadRequest) (Upload, error) {
+	info := map[string]interface{}{"file_size": request.Size}
+	optional := map[string]string{
+		"uuid":         request.Uuid,
+		"file_name":    request.FileName,
+		"content_type": request.ContentType,
+		"tenant":       request.Tenant,
+		"route":        request.Route,
+	}
+	for name, value := range optional {
+		if value != "" {
+			info[name] = value
+		}
+	}
+	if len(request.UserTags) > 0 {
+		info["user_tags"] = request.UserTags
+	}
+	if request.Overwrite {
+		info["overwrite"] = true
+	}
+	body := make(map[string]interface{}, len(request.Fields)+1)
+	for name, value := range request.Fields {
+		body[name] = value
+	}
+	body[c.infoFieldName] = info
+	content, err := jsoniter.Marshal(body)
+	if err != nil {
+		return Upload{}, err
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, "/upload/start", bytes.NewReader(content))
+	if err != nil {
+		return Upload{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if request.UploadSecret != "" {
+		req.Header.Set(UploadSecretHeader, request.UploadSecret)
+	}
+	var upload Upload
+	err = c.doJson(req, &upload)
+	return upload, err
+}
+
+// Status returns plan of upload with names of uploaded chunks
+func (c *Client) Status(ctx context.Context, uuid string, secret string) (Upload, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/upload/"+url.PathEscape(uuid), nil)
+	if err != nil {
+		return Upload{}, err
+	}
+	req.Header.Set(UploadSecretHeader, secret)
+	var upload Upload
+	if err = c.doJson(req, &upload); err != nil {
+		return Upload{}, err
+	}
+	upload.UploadSecret = secret
+	return upload, nil
+}
+
+// Abort removes upload with uploaded chunks
+func (c *Client) Abort(ctx context.Context, uuid string, secret string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, "/upload/"+url.PathEscape(uuid), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(UploadSecretHeader, secret)
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// UploadPart sends one chunk, content must have exactly chunk.Size bytes
+func (c *Client) UploadPart(ctx context.Context, secret string, chunk Chunk, content io.Reader) error {
+	head := new(bytes.Buffer)
+	writer := multipart.NewWriter(head)
+	if _, err := writer.CreateFormFile("part", chunk.Name); err != nil {
+		return err
+	}
+	headLength := head.Len()
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	tail := append([]byte(nil), head.Bytes()[headLength:]...)
+	head.Truncate(headLength)
+	body := io.MultiReader(head, io.LimitReader(content, chunk.Size), bytes.NewReader(tail))
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/upload/part", body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(headLength) + chunk.Size + int64(len(tail))
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set(UploadSecretHeader, secret)
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// Upload starts upload and sends file. Upload with Uuid is resumed: chunks, which are already uploaded, are not sent
+func (c *Client) Upload(ctx context.Context, request UploadRequest, file io.ReaderAt, progress Progress) (Upload, error) {
+	upload, err := c.Start(ctx, request)
+	if err != nil {
+		return Upload{}, err
+	}
+	if request.Uuid != "" {
+		status, err := c.Status(ctx, upload.Uuid, upload.UploadSecret)
+		if err != nil {
+			return upload, err
+		}
+		upload.UploadedChunks = status.UploadedChunks
+	}
+	return upload, c.SendChunks(ctx, upload, file, progress)
+}
+
+// Resume sends chunks, which are not uploaded yet, with secret of started upload
+func (c *Client) Resume(ctx context.Context, uuid string, secret string, file io.ReaderAt, progress Progress) (Upload, error) {
+	upload, err := c.Status(ctx, uuid, secret)
+	if err != nil {
+		return Upload{}, err
+	}
+	return upload, c.SendChunks(ctx, upload, file, progress)
+}
+
+// SendChunks sends chunks of upload, which are not in UploadedChunks, in parallel. Temporary errors are retried
+func (c *Client) SendChunks(ctx context.Context, upload Upload, file io.ReaderAt, progress Progress) error {
+	if upload.UploadSecret == "" {
+		return errors.New("upload secret is required")
+	}
+	uploaded := make(map[string]bool, len(upload.UploadedChunks))
+	for _, name := range upload.UploadedChunks {
+		uploaded[name] = true
+	}
+	var sent int64
+	var pending []Chunk
+	for _, chunk := range upload.SortedChunks() {
+		if uploaded[chunk.Name] {
+			sent += chunk.Size
+			continue
+		}
+		pending = append(pending, chunk)
+	}
+	if progress != nil {
+		progress(sent, upload.Size)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	chunks := make(chan Chunk)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i := 0; i < c.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				if err := c.sendChunk(ctx, upload.UploadSecret, chunk, file); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				total := atomic.AddInt64(&sent, chunk.Size)
+				if progress != nil {
+					progress(total, upload.Size)
+				}
+			}
+		}()
+	}
+	for _, chunk := range pending {
+		select {
+		case chunks <- chunk:
+		case <-ctx.Done():
+		}
+	}
+	close(chunks)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+func (c *Client) sendChunk(ctx context.Context, secret string, chunk Chunk, file io.ReaderAt) error {
+	var err error
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.retryDelay * time.Duration(attempt)):
+			}
+		}
+		err = c.UploadPart(ctx, secret, chunk, io.NewSectionReader(file, chunk.Offset, chunk.Size))
+		if err == nil || !isTemporary(ctx, err) {
+			return err
+		}
+	}
+	return err
+}
+
+// isTemporary - network errors and 5xx responses are retried, other responses of server are final
+func isTemporary(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.IsTemporary()
+	}
+	return true
+}
@@ -0,0 +1,291 @@
+// Package server embeds Filup into Go application: the full stack of Filup is built from options
+// and mounted as net/http or fasthttp handler on the server of application
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/satmaelstorm/filup/internal/domain/dto"
+	"github.com/satmaelstorm/filup/internal/domain/port"
+	"github.com/satmaelstorm/filup/internal/infrastructure/appctx"
+	"github.com/satmaelstorm/filup/internal/infrastructure/cache"
+	"github.com/satmaelstorm/filup/internal/infrastructure/catalog"
+	"github.com/satmaelstorm/filup/internal/infrastructure/config"
+	"github.com/satmaelstorm/filup/internal/infrastructure/di"
+	"github.com/satmaelstorm/filup/internal/infrastructure/health"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs"
+	"github.com/satmaelstorm/filup/internal/infrastructure/storage"
+	"github.com/satmaelstorm/filup/internal/infrastructure/tracing"
+	"github.com/satmaelstorm/filup/internal/infrastructure/web"
+	"github.com/satmaelstorm/filup/internal/infrastructure/web/routes"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ports of Filup, which can be replaced by embedding application
+type (
+	Config             = config.Configuration
+	Storage            = port.Storage
+	Poster             = port.Poster
+	Getter             = port.Getter
+	Logger             = port.Logger
+	LogLevelController = port.LogLevelController
+	MetaCache          = port.MetaCacheController
+	FileInfo           = port.FileInfo
+	ComposeResult      = port.PartsComposerResult
+	FileLocation       = dto.FileLocation
+	Encryption         = dto.Encryption
+	ByteRange          = dto.ByteRange
+	TracerProvider     = trace.TracerProvider
+)
+
+var ErrLevelNotManaged = errors.New("level of logger is managed by application")
+
+type options struct {
+	ctx     context.Context
+	cfg     *Config
+	storage Storage
+	poster  Poster
+	logger  Logger
+	cache   MetaCache
+	tracer  TracerProvider
+	prefix  string
+	ops     bool
+}
+
+// Option of embedded server
+type Option func(o *options)
+
+// WithConfig sets configuration, otherwise configuration is loaded like by serve command
+func WithConfig(cfg Config) Option {
+	return func(o *options) {
+		o.cfg = &cfg
+	}
+}
+
+// WithStorage replaces Minio S3 storage
+func WithStorage(s Storage) Option {
+	return func(o *options) {
+		o.storage = s
+	}
+}
+
+// WithPoster replaces http client of callbacks. If poster implements Getter, it also sends GET callbackDownload
+func WithPoster(p Poster) Option {
+	return func(o *options) {
+		o.poster = p
+	}
+}
+
+// WithLogger replaces loggers of Filup, level of logger can be changed by admin API
+// only when logger implements LogLevelController
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithCache replaces cache of uploads meta
+func WithCache(c MetaCache) Option {
+	return func(o *options) {
+		o.cache = c
+	}
+}
+
+// WithTracerProvider sets provider of spans of Filup, otherwise provider is built from configuration.
+// Spans are propagated by W3C trace context, globals of otel are not changed in both cases
+func WithTracerProvider(p TracerProvider) Option {
+	return func(o *options) {
+		o.tracer = p
+	}
+}
+
+// WithContext sets parent context, background jobs of Filup are stopped when it is done
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithPathPrefix sets prefix of path, under which handlers are mounted, e.g. "/files"
+func WithPathPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = strings.TrimSuffix(prefix, "/")
+	}
+}
+
+// WithOpsEndpoints adds pprof, metrics and health probes of Filup to handlers. They are not authorized,
+// so they must not be reachable from public network. Only domain routes are served without this option
+func WithOpsEndpoints() Option {
+	return func(o *options) {
+		o.ops = true
+	}
+}
+
+// LoadConfig loads configuration file like serve command, empty name loads default configuration
+func LoadConfig(name string) (Config, error) {
+	return config.LoadConfigByViper(name)
+}
+
+// Server - embedded Filup, it implements http.Handler
+type Server struct {
+	fastHandler fasthttp.RequestHandler
+	httpHandler http.Handler
+	cc          *appctx.CoreContext
+	health      *health.Health
+	prefix      string
+}
+
+// New builds embedded Filup, ports which are not set by options are built from configuration
+func New(opts ...Option) (*Server, error) {
+	o := options{ctx: context.Background()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var cfg Config
+	if o.cfg != nil {
+		cfg = *o.cfg
+	} else {
+		var err error
+		if cfg, err = LoadConfig(""); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.Prepare(); err != nil {
+		return nil, err
+	}
+
+	cc := appctx.NewContext(o.ctx)
+	s, err := build(cc, cfg, o)
+	if err != nil {
+		cc.Cancel()
+		return nil, err
+	}
+	s.health.SetReady(true)
+	return s, nil
+}
+
+func build(cc *appctx.CoreContext, cfg Config, o options) (*Server, error) {
+	var levels LogLevelController
+	if o.logger == nil {
+		loggers := logs.ProvideLoggers(cfg)
+		o.logger, levels = loggers, loggers
+	} else if l, ok := o.logger.(LogLevelController); ok {
+		levels = l
+	} else {
+		levels = fixedLevel{}
+	}
+
+	if o.cache == nil {
+		c, err := cache.ProvideMetaCache(cfg, o.logger)
+		if err != nil {
+			return nil, err
+		}
+		o.cache = c
+	}
+
+	if o.storage == nil {
+		s, err := storage.NewMinioS3(cfg, cc, o.cache)
+		if err != nil {
+			return nil, err
+		}
+		o.storage = s
+	}
+
+	filesCatalog, err := catalog.NewBoltCatalog(cfg, cc, o.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.tracer == nil {
+		p, err := tracing.NewTracerProvider(cfg, cc, o.logger)
+		if err != nil {
+			return nil, err
+		}
+		o.tracer = p
+	}
+
+	helpers := web.NewRequestHelpers(o.tracer)
+	if o.poster == nil {
+		o.poster = helpers
+	}
+	getter, ok := o.poster.(Getter)
+	if !ok {
+		getter = helpers
+	}
+
+	instanceHealth := health.NewHealth()
+	r, err := di.InitEmbedded(di.NewEmbeddedPorts(cc, cfg, o.storage, o.poster, getter, o.logger, levels, o.cache,
+		filesCatalog, tracing.NewTracer(o.tracer), instanceHealth, routes.Ops{Enabled: o.ops}))
+	if err != nil {
+		return nil, err
+	}
+	return &Server{fastHandler: r.FastHttp.Handler, httpHandler: r.Http, cc: cc, health: instanceHealth, prefix: o.prefix}, nil
+}
+
+// FastHTTPHandler returns handler for fasthttp server of application
+func (s *Server) FastHTTPHandler() fasthttp.RequestHandler {
+	if s.prefix == "" {
+		return s.fastHandler
+	}
+	return func(ctx *fasthttp.RequestCtx) {
+		path, ok := s.stripPrefix(string(ctx.Path()))
+		if !ok {
+			ctx.NotFound()
+			return
+		}
+		ctx.URI().SetPath(path)
+		s.fastHandler(ctx)
+	}
+}
+
+// ServeHTTP serves request of net/http server by net/http handlers of Filup
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.prefix == "" {
+		s.httpHandler.ServeHTTP(w, r)
+		return
+	}
+	path, ok := s.stripPrefix(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	stripped := new(http.Request)
+	*stripped = *r
+	stripped.URL = new(url.URL)
+	*stripped.URL = *r.URL
+	stripped.URL.Path, stripped.URL.RawPath = path, ""
+	s.httpHandler.ServeHTTP(w, stripped)
+}
+
+func (s *Server) stripPrefix(path string) (string, bool) {
+	if !strings.HasPrefix(path, s.prefix) {
+		return "", false
+	}
+	rest := path[len(s.prefix):]
+	if rest == "" {
+		return "/", true
+	}
+	return rest, rest[0] == '/'
+}
+
+// Close stops background jobs of Filup
+func (s *Server) Close() {
+	s.health.SetReady(false)
+	s.cc.Cancel()
+}
+
+// fixedLevel - level controller of logger, which is provided by application
+type fixedLevel struct{}
+
+func (fixedLevel) Level() string {
+	return ""
+}
+
+func (fixedLevel) SetLevel(string) error {
+	return ErrLevelNotManaged
+}
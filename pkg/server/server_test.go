@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"github.com/satmaelstorm/filup/internal/infrastructure/logs/logsEngine"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeStorage - instances are built without S3, storage is not called while they are built
+type fakeStorage struct {
+	Storage
+}
+
+type suiteServer struct {
+	suite.Suite
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func TestServer(t *testing.T) {
+	suite.Run(t, new(suiteServer))
+}
+
+func (s *suiteServer) SetupTest() {
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+}
+
+func (s *suiteServer) TearDownTest() {
+	s.cancel()
+}
+
+func (s *suiteServer) newServer(catalogPath string, opts ...Option) *Server {
+	cfg, err := LoadConfig("")
+	s.Require().Nil(err)
+	cfg.Catalog.Path = catalogPath
+	loggers := logsEngine.InitLoggersEmpty("test")
+	opts = append(opts, WithConfig(cfg), WithStorage(fakeStorage{}), WithLogger(&loggers), WithContext(s.ctx))
+	srv, err := New(opts...)
+	s.Require().Nil(err)
+	s.T().Cleanup(srv.Close)
+	return srv
+}
+
+func (s *suiteServer) TestInstancesHaveOwnCatalogs() {
+	dir := s.T().TempDir()
+	first := filepath.Join(dir, "first.db")
+	second := filepath.Join(dir, "second.db")
+	s.newServer(first)
+	s.newServer(second)
+
+	for _, path := range []string{first, second} {
+		_, err := os.Stat(path)
+		s.Nil(err, path)
+	}
+}
+
+func (s *suiteServer) TestOtelGlobalsAreNotChanged() {
+	provider := otel.GetTracerProvider()
+	s.newServer("")
+	s.newServer("", WithTracerProvider(sdktrace.NewTracerProvider()))
+	s.Equal(provider, otel.GetTracerProvider())
+	s.Empty(otel.GetTextMapPropagator().Fields())
+}